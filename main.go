@@ -17,10 +17,17 @@ import (
 	"github.com/SevenTV/REST/src/aws"
 	"github.com/SevenTV/REST/src/configure"
 	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/health"
+	"github.com/SevenTV/REST/src/maintenance"
+	"github.com/SevenTV/REST/src/migrate"
+	"github.com/SevenTV/REST/src/monitoring"
+	"github.com/SevenTV/REST/src/popularity"
 	"github.com/SevenTV/REST/src/rmq"
 	"github.com/SevenTV/REST/src/server"
+	"github.com/SevenTV/REST/src/server/v3/routes/emotes"
 	"github.com/bugsnag/panicwrap"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
 )
 
 var (
@@ -40,6 +47,10 @@ func init() {
 func main() {
 	config := configure.New()
 
+	if pflag.Arg(0) == "migrate" {
+		os.Exit(migrate.Command(config, pflag.Args()[1:]))
+	}
+
 	exitStatus, err := panicwrap.BasicWrap(func(s string) {
 		logrus.Error(s)
 	})
@@ -113,24 +124,65 @@ func main() {
 		gCtx.Inst().Auth = authInst
 		gCtx.Inst().Rmq = rmqInst
 		gCtx.Inst().AwsS3 = awsS3Inst
+		gCtx.Inst().Prometheus = monitoring.New(monitoring.SetupOptions{
+			NodeName: gCtx.Config().NodeName,
+		})
 		gCtx.Inst().Query = query.New(mongoInst, redisInst)
 	}
 
+	go maintenance.Watch(gCtx)
+	go configure.Watch(gCtx, config.ConfigFile, func() {
+		_, _ = global.ReloadConfig(gCtx)
+	})
+
 	httpServer := server.New()
-	serverDone, err := httpServer.Start(gCtx)
+	_, err = httpServer.Start(gCtx)
 	if err != nil {
 		logrus.WithError(err).Fatal("failed to start http server")
 	}
 
+	adminServer := server.New()
+	if gCtx.Config().Admin.Enabled {
+		if _, err := adminServer.StartAdmin(gCtx); err != nil {
+			logrus.WithError(err).Fatal("failed to start admin server")
+		}
+	}
+
+	if gCtx.Config().Monitoring.Enabled {
+		if _, err := monitoring.Start(gCtx); err != nil {
+			logrus.WithError(err).Fatal("failed to start monitoring server")
+		}
+	}
+
+	go emotes.WatchDeletions(gCtx)
+	go popularity.Watch(gCtx)
+
+	epl := emotes.NewEmoteProcessingListener(gCtx)
+	eplDone := make(chan struct{})
+	go func() {
+		defer close(eplDone)
+		epl.Listen()
+	}()
+
+	health.SetReady(true)
 	logrus.Info("running")
 
 	done := make(chan struct{})
 	go func() {
 		<-sig
-		cancel()
+
+		// Every step of the shutdown shares a single deadline, after which the process is forced to exit
+		timeout := time.Second * 60
+		if t := gCtx.Config().ShutdownTimeout; t > 0 {
+			timeout = time.Duration(t) * time.Second
+		}
+		deadline := time.Now().Add(timeout)
+		ctx, lcancel := context.WithDeadline(context.Background(), deadline)
+		defer lcancel()
+
 		go func() {
 			select {
-			case <-time.After(time.Minute):
+			case <-time.After(time.Until(deadline)):
 			case <-sig:
 			}
 			logrus.Fatal("force shutdown")
@@ -138,7 +190,39 @@ func main() {
 
 		logrus.Info("shutting down")
 
-		<-serverDone
+		// Stop receiving traffic once load balancers have seen the node is no longer ready, and let the requests in flight finish
+		health.Drain(ctx, time.Duration(gCtx.Config().ShutdownDelay)*time.Second)
+		if err := httpServer.Shutdown(ctx); err != nil {
+			logrus.WithError(err).Error("failed to shut down http server")
+		}
+		logrus.Info("http server stopped")
+		if gCtx.Config().Admin.Enabled {
+			_ = adminServer.Shutdown(ctx)
+		}
+
+		// Let the listener finish the messages it is handling, so that they are acknowledged
+		epl.Stop()
+		select {
+		case <-eplDone:
+		case <-ctx.Done():
+		}
+
+		cancel()
+
+		// Close connections
+		if gCtx.Inst().Rmq != nil {
+			gCtx.Inst().Rmq.Shutdown()
+		}
+		if gCtx.Inst().Mongo != nil {
+			if err := gCtx.Inst().Mongo.RawClient().Disconnect(ctx); err != nil {
+				logrus.WithError(err).Error("failed to disconnect from mongo")
+			}
+		}
+		if gCtx.Inst().Redis != nil {
+			if err := gCtx.Inst().Redis.RawClient().Close(); err != nil {
+				logrus.WithError(err).Error("failed to disconnect from redis")
+			}
+		}
 
 		close(done)
 	}()
@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/SevenTV/Common/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const CollectionName mongo.CollectionName = "audit_logs"
+
+// Log: a record of a mutating request
+type Log struct {
+	ID primitive.ObjectID `json:"id" bson:"_id"`
+	// the user who made the request, if authenticated
+	ActorID primitive.ObjectID `json:"actor_id" bson:"actor_id,omitempty"`
+	// the request method
+	Method string `json:"method" bson:"method"`
+	// the route that handled the request, as registered
+	Route string `json:"route" bson:"route"`
+	// the requested path
+	Path string `json:"path" bson:"path"`
+	// the final status code of the response
+	Status int `json:"status" bson:"status"`
+	// the error code, if the request failed
+	ErrorCode int `json:"error_code,omitempty" bson:"error_code,omitempty"`
+	// the objects affected by the request
+	Targets []Target `json:"targets" bson:"targets"`
+	// the changes made to the targets
+	Changes []Change `json:"changes" bson:"changes"`
+	// the time at which the request completed
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
+
+	mx sync.Mutex
+}
+
+type Target struct {
+	Kind TargetKind         `json:"kind" bson:"kind"`
+	ID   primitive.ObjectID `json:"id" bson:"id"`
+}
+
+type TargetKind string
+
+const (
+	TargetKindEmote TargetKind = "EMOTE"
+	TargetKindUser  TargetKind = "USER"
+)
+
+// Change: the value of a target's key before and after the request.
+// Old is empty when something was added, and New is empty when something was removed
+type Change struct {
+	TargetID primitive.ObjectID `json:"target_id" bson:"target_id"`
+	Key      string             `json:"key" bson:"key"`
+	Old      interface{}        `json:"old,omitempty" bson:"old,omitempty"`
+	New      interface{}        `json:"new,omitempty" bson:"new,omitempty"`
+}
+
+// AddTarget: mark an object as affected by the request
+func (l *Log) AddTarget(kind TargetKind, id primitive.ObjectID) *Log {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	for _, t := range l.Targets {
+		if t.ID == id {
+			return l
+		}
+	}
+	l.Targets = append(l.Targets, Target{Kind: kind, ID: id})
+	return l
+}
+
+// AddChange: record a change made to a target
+func (l *Log) AddChange(targetID primitive.ObjectID, key string, old interface{}, new interface{}) *Log {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.Changes = append(l.Changes, Change{
+		TargetID: targetID,
+		Key:      key,
+		Old:      old,
+		New:      new,
+	})
+	return l
+}
+
+// HasChanges: whether or not any change was recorded
+func (l *Log) HasChanges() bool {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	return len(l.Changes) > 0
+}
@@ -79,3 +79,67 @@ func (a *AwsS3Instance) DownloadFile(ctx context.Context, bucket, key string, fi
 	logrus.Debugf("%d bytes downloaded from %s %s", n, bucket, key)
 	return nil
 }
+
+// ListObjects: get the keys of the objects in a bucket which begin with a prefix
+func (a *AwsS3Instance) ListObjects(ctx context.Context, bucket, prefix string) ([]string, error) {
+	keys := []string{}
+	if err := a.s3.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, last bool) bool {
+		for _, obj := range page.Contents {
+			keys = append(keys, aws.StringValue(obj.Key))
+		}
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list files, %v", err)
+	}
+
+	return keys, nil
+}
+
+// DeleteObjects: remove objects from a bucket by their keys. Keys which do not exist are ignored
+func (a *AwsS3Instance) DeleteObjects(ctx context.Context, bucket string, keys []string) error {
+	// A request may delete up to 1000 objects
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > 1000 {
+			n = 1000
+		}
+
+		objects := make([]*s3.ObjectIdentifier, n)
+		for i, k := range keys[:n] {
+			objects[i] = &s3.ObjectIdentifier{Key: aws.String(k)}
+		}
+		out, err := a.s3.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &s3.Delete{
+				Objects: objects,
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete files, %v", err)
+		}
+		if len(out.Errors) > 0 {
+			e := out.Errors[0]
+			return fmt.Errorf("failed to delete %d files, %s: %s", len(out.Errors), aws.StringValue(e.Key), aws.StringValue(e.Message))
+		}
+
+		logrus.Debugf("%d files deleted from %s", n, bucket)
+		keys = keys[n:]
+	}
+
+	return nil
+}
+
+// HeadBucket: check that a bucket exists and can be accessed
+func (a *AwsS3Instance) HeadBucket(ctx context.Context, bucket string) error {
+	if _, err := a.s3.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(bucket),
+	}); err != nil {
+		return fmt.Errorf("failed to access bucket, %v", err)
+	}
+
+	return nil
+}
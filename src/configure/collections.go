@@ -2,6 +2,8 @@ package configure
 
 import (
 	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/REST/src/audit"
+	"github.com/SevenTV/REST/src/deletions"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -12,4 +14,64 @@ var Indexes = []mongo.IndexRef{
 			Keys: bson.M{"username": 1},
 		},
 	},
+	{
+		Collection: mongo.CollectionNameEmotes,
+		Index: mongo.IndexModel{
+			Keys: bson.M{"owner_id": 1},
+		},
+	},
+	{
+		Collection: mongo.CollectionNameEmotes,
+		Index: mongo.IndexModel{
+			Keys: bson.M{"tags": 1},
+		},
+	},
+	{
+		Collection: mongo.CollectionNameEmotes,
+		Index: mongo.IndexModel{
+			Keys: bson.M{"name": 1},
+		},
+	},
+	{
+		Collection: mongo.CollectionNameEmotes,
+		Index: mongo.IndexModel{
+			Keys: bson.D{{Key: "popularity", Value: -1}, {Key: "_id", Value: -1}},
+		},
+	},
+	{
+		Collection: mongo.CollectionNameEmoteSets,
+		Index: mongo.IndexModel{
+			Keys: bson.M{"emotes.id": 1},
+		},
+	},
+	{
+		Collection: deletions.CollectionName,
+		Index: mongo.IndexModel{
+			Keys: bson.M{"emote_id": 1},
+		},
+	},
+	{
+		Collection: deletions.CollectionName,
+		Index: mongo.IndexModel{
+			Keys: bson.M{"purge_at": 1},
+		},
+	},
+	{
+		Collection: audit.CollectionName,
+		Index: mongo.IndexModel{
+			Keys: bson.M{"actor_id": 1},
+		},
+	},
+	{
+		Collection: audit.CollectionName,
+		Index: mongo.IndexModel{
+			Keys: bson.M{"targets.id": 1},
+		},
+	},
+	{
+		Collection: audit.CollectionName,
+		Index: mongo.IndexModel{
+			Keys: bson.M{"timestamp": -1},
+		},
+	},
 }
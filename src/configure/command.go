@@ -0,0 +1,87 @@
+package configure
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+const commandUsage = `usage: rest config <command>
+
+commands:
+  validate          check the config, reporting all of its problems
+  print [--redact]  print the config as it is read by the api, with secrets hidden if --redact is set`
+
+// command: run the "config" subcommand, returning the exit code of the process
+func command(args []string, redact bool) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, commandUsage)
+		return 2
+	}
+
+	c, err := read()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+
+	switch args[0] {
+	case "validate":
+		if err := c.Validate(); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+		fmt.Println("config ok")
+	case "print":
+		if redact {
+			c = c.Redacted()
+		}
+		// JSON is valid YAML, so the output can be used as a config file
+		b, _ := json.MarshalIndent(c, "", "    ")
+		fmt.Println(string(b))
+	default:
+		fmt.Fprintln(os.Stderr, commandUsage)
+		return 2
+	}
+
+	return 0
+}
+
+const redacted = "REDACTED"
+
+// Redacted: a copy of the config with its secrets hidden, for it to be shared
+func (c *Config) Redacted() *Config {
+	r := *c
+
+	for _, s := range []*string{
+		&r.Redis.Password,
+		&r.Credentials.PrivateKey,
+		&r.Credentials.JWTSecret,
+		&r.Platforms.Twitch.ClientSecret,
+		&r.Aws.AccessToken,
+		&r.Aws.SecretKey,
+	} {
+		if *s != "" {
+			*s = redacted
+		}
+	}
+
+	// Connection strings may hold credentials
+	for _, s := range []*string{
+		&r.Mongo.URI,
+		&r.Rmq.ServerURL,
+	} {
+		u, err := url.Parse(*s)
+		if err != nil {
+			*s = redacted
+			continue
+		}
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			*s = u.String()
+		}
+	}
+
+	return &r
+}
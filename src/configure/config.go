@@ -3,6 +3,7 @@ package configure
 import (
 	"bytes"
 	"encoding/json"
+	"os"
 	"reflect"
 	"strings"
 
@@ -18,6 +19,45 @@ func checkErr(err error) {
 }
 
 func New() *Config {
+	pflag.String("config", "config.yaml", "Config file location")
+	pflag.Bool("noheader", false, "Disable the startup header")
+	redact := pflag.Bool("redact", false, "Hide secrets in the output of \"config print\"")
+	pflag.Parse()
+
+	if pflag.Arg(0) == "config" {
+		os.Exit(command(pflag.Args()[1:], *redact))
+	}
+
+	c, err := read()
+	checkErr(err)
+	checkErr(c.Validate())
+
+	initLogging(c.Level)
+
+	return c
+}
+
+// Reload: read the config again, for it to replace the current one. The new config is validated, and the log level applied
+//
+// Settings which are only used while starting up (i.e the addresses of services) keep their current values.
+// Those which were changed are returned, as they require a restart to take effect
+func Reload(current *Config) (*Config, []string, error) {
+	c, err := read()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = c.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	restart := keepStartupSettings(current, c)
+	initLogging(c.Level)
+
+	return c, restart, nil
+}
+
+// read: build the config from the defaults, the config file, the environment and the command line flags
+func read() (*Config, error) {
 	config := viper.New()
 
 	// Default config
@@ -27,20 +67,28 @@ func New() *Config {
 	defaultConfig := bytes.NewReader(b)
 	tmp := viper.New()
 	tmp.SetConfigType("json")
-	checkErr(tmp.ReadConfig(defaultConfig))
-	checkErr(config.MergeConfigMap(viper.AllSettings()))
+	if err := tmp.ReadConfig(defaultConfig); err != nil {
+		return nil, err
+	}
+	if err := config.MergeConfigMap(viper.AllSettings()); err != nil {
+		return nil, err
+	}
 
-	pflag.String("config", "config.yaml", "Config file location")
-	pflag.Bool("noheader", false, "Disable the startup header")
-	pflag.Parse()
-	checkErr(config.BindPFlags(pflag.CommandLine))
+	if err := config.BindPFlags(pflag.CommandLine); err != nil {
+		return nil, err
+	}
 
 	// File
-	config.SetConfigFile(config.GetString("config"))
-	config.AddConfigPath(".")
-	err := config.ReadInConfig()
-	if err == nil {
-		checkErr(config.MergeInConfig())
+	file := viper.New()
+	file.SetConfigFile(config.GetString("config"))
+	unknown := []string{}
+	if err := file.ReadInConfig(); err == nil {
+		if err = config.MergeConfigMap(file.AllSettings()); err != nil {
+			return nil, err
+		}
+		unknown = unknownKeys(file.AllKeys())
+	} else if !os.IsNotExist(err) {
+		return nil, err
 	}
 
 	BindEnvs(config, Config{})
@@ -53,11 +101,12 @@ func New() *Config {
 
 	// Print final config
 	c := &Config{}
-	checkErr(config.Unmarshal(c))
-
-	initLogging(c.Level)
+	if err := config.Unmarshal(c); err != nil {
+		return nil, err
+	}
+	c.unknown = unknown
 
-	return c
+	return c, nil
 }
 
 func BindEnvs(config *viper.Viper, iface interface{}, parts ...string) {
@@ -83,9 +132,15 @@ type Config struct {
 	Level      string `mapstructure:"level" json:"level"`
 	ConfigFile string `mapstructure:"config" json:"config"`
 	WebsiteURL string `mapstructure:"website_url" json:"website_url"`
+	CdnURL     string `mapstructure:"cdn_url" json:"cdn_url"`
 	NodeName   string `mapstructure:"node_name" json:"node_name"`
 	TempFolder string `mapstructure:"temp_folder" json:"temp_folder"`
 	NoHeader   bool   `mapstructure:"noheader" json:"noheader"`
+	// How long the node may take to drain and close its connections when shutting down, in seconds. Defaults to 60
+	ShutdownTimeout int `mapstructure:"shutdown_timeout" json:"shutdown_timeout"`
+	// How long the node keeps serving requests once it is marked as not ready, so that load balancers stop sending it traffic
+	// before it stops accepting connections, in seconds. This is part of the shutdown timeout
+	ShutdownDelay int `mapstructure:"shutdown_delay" json:"shutdown_delay"`
 
 	Redis struct {
 		URI      string `mapstructure:"uri" json:"uri"`
@@ -104,8 +159,37 @@ type Config struct {
 		Type         string `mapstructure:"type" json:"type"`
 		CookieDomain string `mapstructure:"cookie_domain" json:"cookie_domain"`
 		CookieSecure bool   `mapstructure:"cookie_secure" json:"cookie_secure"`
+		// Store panic reports in the errors collection, for lookup by their error ID
+		ErrorReports bool `mapstructure:"error_reports" json:"error_reports"`
+		// How long responses to requests with an Idempotency-Key are kept for replay, in seconds. Defaults to 24 hours
+		IdempotencyWindow int `mapstructure:"idempotency_window" json:"idempotency_window"`
 	} `mapstructure:"http" json:"http"`
 
+	Monitoring struct {
+		Enabled bool   `mapstructure:"enabled" json:"enabled"`
+		URI     string `mapstructure:"uri" json:"uri"`
+		Type    string `mapstructure:"type" json:"type"`
+	} `mapstructure:"monitoring" json:"monitoring"`
+
+	// The mode the API starts in. It can be changed at runtime through the admin listener
+	Maintenance struct {
+		// off, read-only or maintenance
+		Mode string `mapstructure:"mode" json:"mode"`
+		// A notice for clients, i.e the reason for the maintenance
+		Message string `mapstructure:"message" json:"message"`
+		// How long clients should wait before retrying, in seconds. Defaults to 300
+		RetryAfter int `mapstructure:"retry_after" json:"retry_after"`
+		// The path of a static .html or .json page, served in place of every response while in maintenance mode
+		Notice string `mapstructure:"notice" json:"notice"`
+	} `mapstructure:"maintenance" json:"maintenance"`
+
+	// The admin listener serves operator endpoints, and must not be reachable by clients
+	Admin struct {
+		Enabled bool   `mapstructure:"enabled" json:"enabled"`
+		URI     string `mapstructure:"uri" json:"uri"`
+		Type    string `mapstructure:"type" json:"type"`
+	} `mapstructure:"admin" json:"admin"`
+
 	Platforms struct {
 		Twitch struct {
 			ClientID     string `mapstructure:"client_id" json:"client_id"`
@@ -127,6 +211,25 @@ type Config struct {
 		UpdateQueueName string `mapstructure:"update_queue_name" json:"update_queue_name"`
 	} `mapstructure:"rmq" json:"rmq"`
 
+	Legacy struct {
+		// Serve the deprecated v2 API
+		Enabled bool `mapstructure:"enabled" json:"enabled"`
+		// The date at which the v2 API will be removed (RFC3339), announced in the Sunset header
+		Sunset string `mapstructure:"sunset" json:"sunset"`
+	} `mapstructure:"legacy" json:"legacy"`
+
+	Limits struct {
+		// Rate limit budgets, keyed by bucket name. These override the defaults set on each route
+		Buckets map[string]LimitBucket `mapstructure:"buckets" json:"buckets"`
+		// Size limits for emote uploads. Only read on startup, as request bodies are capped to the largest of them
+		Uploads UploadLimits `mapstructure:"uploads" json:"uploads"`
+	} `mapstructure:"limits" json:"limits"`
+
+	Emotes struct {
+		// How long the files of deleted emotes are kept, in seconds. Deletions can be undone until then. Defaults to 7 days
+		DeletionRetention int `mapstructure:"deletion_retention" json:"deletion_retention"`
+	} `mapstructure:"emotes" json:"emotes"`
+
 	Aws struct {
 		AccessToken string `mapstructure:"access_token" json:"access_token"`
 		SecretKey   string `mapstructure:"secret_key" json:"secret_key"`
@@ -134,4 +237,25 @@ type Config struct {
 		Bucket      string `mapstructure:"bucket" json:"bucket"`
 		Endpoint    string `mapstructure:"endpoint" json:"endpoint"`
 	} `mapstructure:"aws" json:"aws"`
+
+	// Settings found in the config file which are not part of the config
+	unknown []string
+}
+
+type LimitBucket struct {
+	// The amount of requests allowed within the window
+	Limit int64 `mapstructure:"limit" json:"limit"`
+	// The length of the sliding window, in seconds
+	Window int `mapstructure:"window" json:"window"`
+	// Limits for specific roles, keyed by role ID, which replace the default for actors with those roles.
+	// The highest limit among the actor's roles applies, even if it is lower than the default
+	Roles map[string]int64 `mapstructure:"roles" json:"roles"`
+}
+
+type UploadLimits struct {
+	// The maximum size of an upload, in bytes. Defaults to 2.5MB
+	MaxSize int64 `mapstructure:"max_size" json:"max_size"`
+	// Limits for specific roles, keyed by role ID, which replace the default for actors with those roles.
+	// The highest limit among the actor's roles applies, even if it is lower than the default
+	Roles map[string]int64 `mapstructure:"roles" json:"roles"`
 }
@@ -0,0 +1,96 @@
+package configure
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// keepStartupSettings: copy the settings which are only read while starting up from the current config into the next one.
+// The keys of those which were changed are returned
+func keepStartupSettings(current *Config, next *Config) []string {
+	restart := []string{}
+	for _, s := range []struct {
+		key     string
+		current interface{}
+		next    interface{}
+	}{
+		{"node_name", &current.NodeName, &next.NodeName},
+		{"redis", &current.Redis, &next.Redis},
+		{"mongo", &current.Mongo, &next.Mongo},
+		{"rmq", &current.Rmq, &next.Rmq},
+		{"http.uri", &current.Http.URI, &next.Http.URI},
+		{"http.type", &current.Http.Type, &next.Http.Type},
+		{"monitoring", &current.Monitoring, &next.Monitoring},
+		{"admin", &current.Admin, &next.Admin},
+		{"legacy.enabled", &current.Legacy.Enabled, &next.Legacy.Enabled},
+		// request bodies are capped to the largest upload when the http server starts
+		{"limits.uploads", &current.Limits.Uploads, &next.Limits.Uploads},
+		{"aws.access_token", &current.Aws.AccessToken, &next.Aws.AccessToken},
+		{"aws.secret_key", &current.Aws.SecretKey, &next.Aws.SecretKey},
+		{"aws.region", &current.Aws.Region, &next.Aws.Region},
+		{"aws.endpoint", &current.Aws.Endpoint, &next.Aws.Endpoint},
+		{"credentials.private_key", &current.Credentials.PrivateKey, &next.Credentials.PrivateKey},
+		{"credentials.public_key", &current.Credentials.PublicKey, &next.Credentials.PublicKey},
+	} {
+		cur := reflect.ValueOf(s.current).Elem()
+		nxt := reflect.ValueOf(s.next).Elem()
+		if !reflect.DeepEqual(cur.Interface(), nxt.Interface()) {
+			restart = append(restart, s.key)
+			nxt.Set(cur)
+		}
+	}
+
+	return restart
+}
+
+// Watch: call fn when the config file is changed or the process receives SIGHUP, until the context is done
+func Watch(ctx context.Context, file string, fn func()) {
+	trigger := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case trigger <- struct{}{}:
+		default:
+		}
+	}
+
+	if _, err := os.Stat(file); err == nil {
+		w := viper.New()
+		w.SetConfigFile(file)
+		w.OnConfigChange(func(e fsnotify.Event) {
+			notify()
+		})
+		w.WatchConfig()
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-hup:
+			notify()
+		case <-trigger:
+			// Editors often write a file in several steps, so wait for it to settle
+			select {
+			case <-time.After(time.Millisecond * 250):
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case <-trigger:
+			default:
+			}
+			fn()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
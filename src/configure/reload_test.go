@@ -0,0 +1,210 @@
+package configure
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// useConfigFile: point the config flag at a file, as if it was passed on the command line
+func useConfigFile(t *testing.T, path string) {
+	if pflag.Lookup("config") == nil {
+		pflag.String("config", "config.yaml", "Config file location")
+	}
+	prev := pflag.Lookup("config").Value.String()
+	if err := pflag.Set("config", path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = pflag.Set("config", prev)
+	})
+}
+
+const reloadBase = `
+level: info
+mongo:
+    uri: mongodb://127.0.0.1:27017
+    db: 7tv
+redis:
+    uri: 127.0.0.1:6379
+credentials:
+    jwt_secret: secret
+http:
+    uri: 127.0.0.1:3000
+    type: tcp
+`
+
+func TestReload(t *testing.T) {
+	tests := []struct {
+		name string
+		// the config file to reload
+		file string
+		// the keys expected to require a restart, and the level and mongo uri expected to be in effect
+		restart []string
+		level   string
+		mongo   string
+		err     bool
+	}{
+		{"unchanged", reloadBase, []string{}, "info", "mongodb://127.0.0.1:27017", false},
+		{"runtime settings are applied", reloadBase + "maintenance:\n    mode: read-only\n", []string{}, "info", "mongodb://127.0.0.1:27017", false},
+		{
+			"startup settings are kept",
+			`
+level: debug
+mongo:
+    uri: mongodb://10.0.0.1:27017
+    db: 7tv
+redis:
+    uri: 127.0.0.1:6379
+credentials:
+    jwt_secret: secret
+http:
+    uri: 0.0.0.0:3200
+    type: tcp
+`,
+			[]string{"mongo", "http.uri"}, "debug", "mongodb://127.0.0.1:27017", false,
+		},
+		{"invalid config is refused", reloadBase + "shutdown_timeout: -1\n", nil, "", "", true},
+		{"unknown settings are refused", reloadBase + "nope: 1\n", nil, "", "", true},
+		{"unreadable file is refused", "level: [", nil, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			useConfigFile(t, path)
+			if err := os.WriteFile(path, []byte(reloadBase), 0600); err != nil {
+				t.Fatal(err)
+			}
+			current, err := read()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err = os.WriteFile(path, []byte(tt.file), 0600); err != nil {
+				t.Fatal(err)
+			}
+			next, restart, err := Reload(current)
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected the reload to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(restart) != len(tt.restart) {
+				t.Fatalf("expected %v to require a restart, got %v", tt.restart, restart)
+			}
+			for i, k := range tt.restart {
+				if restart[i] != k {
+					t.Errorf("expected %v to require a restart, got %v", tt.restart, restart)
+				}
+			}
+			if next.Level != tt.level || next.Mongo.URI != tt.mongo {
+				t.Errorf("expected level %s and mongo %s, got %s and %s", tt.level, tt.mongo, next.Level, next.Mongo.URI)
+			}
+			if current.Level != "info" {
+				t.Error("the current config was changed")
+			}
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	// The default action of SIGHUP is to exit, so it is caught here too in case it arrives before Watch is listening
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	tests := []struct {
+		name    string
+		trigger func(path string) error
+	}{
+		{"file change", func(path string) error {
+			return os.WriteFile(path, []byte(reloadBase+"level: debug\n"), 0600)
+		}},
+		{"SIGHUP", func(path string) error {
+			return syscall.Kill(os.Getpid(), syscall.SIGHUP)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(reloadBase), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var calls int32
+			go Watch(ctx, path, func() {
+				atomic.AddInt32(&calls, 1)
+			})
+
+			// Keep triggering until Watch has picked it up, as it may not be listening yet
+			deadline := time.Now().Add(time.Second * 5)
+			for atomic.LoadInt32(&calls) == 0 && time.Now().Before(deadline) {
+				if err := tt.trigger(path); err != nil {
+					t.Fatal(err)
+				}
+				time.Sleep(time.Millisecond * 300)
+			}
+			if atomic.LoadInt32(&calls) == 0 {
+				t.Fatal("expected the config to be reloaded")
+			}
+		})
+	}
+}
+
+func TestKeepStartupSettings(t *testing.T) {
+	current := &Config{NodeName: "a", Level: "info"}
+	current.Mongo.URI = "mongodb://a"
+	current.Admin.Enabled = true
+
+	tests := []struct {
+		name    string
+		change  func(c *Config)
+		restart []string
+	}{
+		{"nothing changed", func(c *Config) {}, []string{}},
+		{"runtime setting", func(c *Config) { c.Level = "debug" }, []string{}},
+		{"startup settings", func(c *Config) {
+			c.NodeName = "b"
+			c.Mongo.URI = "mongodb://b"
+			c.Admin.Enabled = false
+			c.Limits.Uploads.MaxSize = 1
+		}, []string{"node_name", "mongo", "admin", "limits.uploads"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := *current
+			tt.change(&next)
+
+			restart := keepStartupSettings(current, &next)
+			if len(restart) != len(tt.restart) {
+				t.Fatalf("expected %v, got %v", tt.restart, restart)
+			}
+			for i, k := range tt.restart {
+				if restart[i] != k {
+					t.Errorf("expected %v, got %v", tt.restart, restart)
+				}
+			}
+			if next.NodeName != current.NodeName || next.Mongo != current.Mongo || next.Admin != current.Admin || next.Limits.Uploads.MaxSize != current.Limits.Uploads.MaxSize {
+				t.Error("expected the startup settings to keep their current values")
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package configure
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Problems: everything wrong with a config, by key
+type Problems []string
+
+func (p Problems) Error() string {
+	return fmt.Sprintf("invalid config, %d problem(s):\n\t%s", len(p), strings.Join(p, "\n\t"))
+}
+
+// Validate: check the whole config, reporting all of its problems together
+func (c *Config) Validate() error {
+	p := Problems{}
+	add := func(key string, format string, a ...interface{}) {
+		p = append(p, fmt.Sprintf("%s: %s", key, fmt.Sprintf(format, a...)))
+	}
+
+	for _, key := range c.unknown {
+		add(key, "unknown setting")
+	}
+
+	if c.Level != "" {
+		if _, err := logrus.ParseLevel(c.Level); err != nil {
+			add("level", "must be one of panic, fatal, error, warn, info, debug or trace")
+		}
+	}
+
+	// Required
+	for key, v := range map[string]string{
+		"mongo.uri":              c.Mongo.URI,
+		"mongo.db":               c.Mongo.DB,
+		"redis.uri":              c.Redis.URI,
+		"credentials.jwt_secret": c.Credentials.JWTSecret,
+	} {
+		if v == "" {
+			add(key, "is required")
+		}
+	}
+
+	// URIs
+	if c.Mongo.URI != "" {
+		validateURL(add, "mongo.uri", c.Mongo.URI, "mongodb", "mongodb+srv")
+	}
+	if c.Redis.URI != "" {
+		if !validAddress(c.Redis.URI) {
+			add("redis.uri", "must be an address in the host:port format")
+		}
+	}
+	if c.Rmq.ServerURL != "" {
+		validateURL(add, "rmq.server_url", c.Rmq.ServerURL, "amqp", "amqps")
+	}
+	for key, v := range map[string]string{
+		"website_url":                   c.WebsiteURL,
+		"cdn_url":                       c.CdnURL,
+		"aws.endpoint":                  c.Aws.Endpoint,
+		"platforms.twitch.redirect_uri": c.Platforms.Twitch.RedirectURI,
+	} {
+		if v != "" {
+			validateURL(add, key, v, "http", "https")
+		}
+	}
+
+	// Listeners
+	for key, l := range map[string]struct {
+		enabled bool
+		uri     string
+		typ     string
+	}{
+		"http":       {true, c.Http.URI, c.Http.Type},
+		"monitoring": {c.Monitoring.Enabled, c.Monitoring.URI, c.Monitoring.Type},
+		"admin":      {c.Admin.Enabled, c.Admin.URI, c.Admin.Type},
+	} {
+		if !l.enabled {
+			continue
+		}
+		switch l.typ {
+		case "tcp":
+			if !validAddress(l.uri) {
+				add(key+".uri", "must be an address in the host:port format")
+			} else if key == "admin" && !IsLoopback(l.uri) {
+				add(key+".uri", "must be a loopback address, as the admin endpoints are not authenticated")
+			}
+		case "unix":
+			if l.uri == "" {
+				add(key+".uri", "must be the path of the socket")
+			}
+		default:
+			add(key+".type", "must be tcp or unix")
+		}
+	}
+
+	switch c.Maintenance.Mode {
+	case "", "off", "read-only", "maintenance":
+	default:
+		add("maintenance.mode", "must be one of off, read-only or maintenance")
+	}
+	if c.Maintenance.Notice != "" {
+		switch strings.ToLower(filepath.Ext(c.Maintenance.Notice)) {
+		case ".html", ".htm", ".json":
+			if _, err := os.Stat(c.Maintenance.Notice); err != nil {
+				add("maintenance.notice", "must be a readable file: %s", err.Error())
+			}
+		default:
+			add("maintenance.notice", "must be an .html or .json file")
+		}
+	}
+
+	// Limits
+	for key, v := range map[string]int64{
+		"shutdown_timeout":          int64(c.ShutdownTimeout),
+		"shutdown_delay":            int64(c.ShutdownDelay),
+		"http.idempotency_window":   int64(c.Http.IdempotencyWindow),
+		"maintenance.retry_after":   int64(c.Maintenance.RetryAfter),
+		"limits.uploads.max_size":   c.Limits.Uploads.MaxSize,
+		"emotes.deletion_retention": int64(c.Emotes.DeletionRetention),
+		"redis.db":                  int64(c.Redis.Database),
+	} {
+		if v < 0 {
+			add(key, "must not be negative")
+		}
+	}
+	if timeout := c.ShutdownTimeout; c.ShutdownDelay > 0 {
+		if timeout == 0 {
+			timeout = 60
+		}
+		if c.ShutdownDelay >= timeout {
+			add("shutdown_delay", "must be less than the shutdown timeout")
+		}
+	}
+	for name, b := range c.Limits.Buckets {
+		if b.Limit < 0 || b.Window < 0 {
+			add("limits.buckets."+name, "limit and window must not be negative")
+		}
+	}
+
+	if len(p) == 0 {
+		return nil
+	}
+	sort.Strings(p)
+	return p
+}
+
+func validateURL(add func(key string, format string, a ...interface{}), key string, v string, schemes ...string) {
+	u, err := url.Parse(v)
+	if err != nil || u.Host == "" {
+		add(key, "must be an absolute URL")
+		return
+	}
+	for _, s := range schemes {
+		if u.Scheme == s {
+			return
+		}
+	}
+	add(key, "must use one of the schemes %s", strings.Join(schemes, ", "))
+}
+
+// validAddress: whether an address is in the host:port format, with a numeric port
+func validAddress(addr string) bool {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	_, err = strconv.ParseUint(port, 10, 16)
+	return err == nil
+}
+
+// unknownKeys: the keys of settings which are not part of the config
+func unknownKeys(keys []string) []string {
+	unknown := []string{}
+	for _, key := range keys {
+		if !knownKey(reflect.TypeOf(Config{}), strings.Split(key, ".")) {
+			unknown = append(unknown, key)
+		}
+	}
+	return unknown
+}
+
+func knownKey(t reflect.Type, parts []string) bool {
+	switch t.Kind() {
+	case reflect.Map:
+		// Maps are keyed freely, i.e by bucket name
+		return true
+	case reflect.Struct:
+		if len(parts) == 0 {
+			return false
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if tag, ok := t.Field(i).Tag.Lookup("mapstructure"); ok && strings.EqualFold(tag, parts[0]) {
+				return knownKey(t.Field(i).Type, parts[1:])
+			}
+		}
+		return false
+	}
+	return len(parts) == 0
+}
+
+// IsLoopback: whether an address in the host:port format can only be reached from the machine itself
+func IsLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
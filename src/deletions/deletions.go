@@ -0,0 +1,57 @@
+package deletions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const CollectionName mongo.CollectionName = "emote_deletions"
+
+// DefaultRetention: how long the files of deleted emotes are kept, unless configured otherwise
+const DefaultRetention = time.Hour * 24 * 7
+
+// Deletion: a record of an emote, or one of its versions, having been deleted
+//
+// The versions are only marked as deleted. Their files are removed once the deletion is purged,
+// and until then it can be undone by setting the versions back to the state they were in
+type Deletion struct {
+	ID primitive.ObjectID `json:"id" bson:"_id"`
+	// the emote the deleted versions belong to
+	EmoteID primitive.ObjectID `json:"emote_id" bson:"emote_id"`
+	// the deleted version, if a single version was deleted rather than the whole emote
+	VersionID *primitive.ObjectID `json:"version_id,omitempty" bson:"version_id,omitempty"`
+	// the versions which were deleted, with the state they were in
+	Versions []Version `json:"versions" bson:"versions"`
+	// the user who deleted the emote
+	ActorID primitive.ObjectID `json:"actor_id" bson:"actor_id"`
+	// why the emote was deleted
+	Reason string `json:"reason" bson:"reason"`
+	// the time at which the emote was deleted
+	DeletedAt time.Time `json:"deleted_at" bson:"deleted_at"`
+	// the time after which the files are removed, and the deletion can no longer be undone
+	PurgeAt time.Time `json:"purge_at" bson:"purge_at"`
+	// the time at which the files were removed
+	PurgedAt *time.Time `json:"purged_at,omitempty" bson:"purged_at,omitempty"`
+	// a node removing the files holds the deletion until then, so that others leave it be
+	LeaseUntil *time.Time `json:"-" bson:"lease_until,omitempty"`
+}
+
+// Version: a deleted version of an emote
+type Version struct {
+	ID primitive.ObjectID `json:"id" bson:"id"`
+	// the state of the version before it was deleted, which it is set back to if the deletion is undone
+	Lifecycle structures.EmoteLifecycle `json:"lifecycle" bson:"lifecycle"`
+}
+
+// FilePrefixes: the prefixes of the keys of the files stored for an emote version;
+// the file uploaded for processing, and the files output by the processor
+func FilePrefixes(versionID primitive.ObjectID) []string {
+	return []string{
+		fmt.Sprintf("internal/emote/%s.", versionID.Hex()),
+		fmt.Sprintf("emote/%s/", versionID.Hex()),
+	}
+}
@@ -0,0 +1,58 @@
+package fakes
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/Common/structures/v3/query"
+	"github.com/SevenTV/REST/src/configure"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/monitoring"
+)
+
+// NewConfig: a config suitable for running the service against fakes
+func NewConfig() *configure.Config {
+	config := &configure.Config{
+		Level:      "error",
+		WebsiteURL: "http://localhost",
+		CdnURL:     "http://cdn.localhost",
+		NodeName:   "fake",
+		TempFolder: "tmp",
+	}
+	config.Mongo.DB = "7tv"
+	config.Credentials.JWTSecret = "fake-jwt-secret"
+	config.Rmq.JobQueueName = "jobs"
+	config.Rmq.ResultQueueName = "results"
+	config.Rmq.UpdateQueueName = "updates"
+	config.Aws.Bucket = "fake-bucket"
+
+	return config
+}
+
+// NewContext: create a global context whose instances are all in-memory fakes.
+// When config is nil, NewConfig is used
+func NewContext(ctx context.Context, config *configure.Config) (global.Context, error) {
+	if config == nil {
+		config = NewConfig()
+	}
+
+	gCtx := global.New(ctx, config)
+	gCtx = global.WithValue(gCtx, "uptime", time.Now())
+
+	mongoInst, err := NewMongo(ctx, config.Mongo.DB)
+	if err != nil {
+		return nil, err
+	}
+	redisInst := NewRedis()
+
+	gCtx.Inst().Mongo = mongoInst
+	gCtx.Inst().Redis = redisInst
+	gCtx.Inst().Rmq = NewRmq()
+	gCtx.Inst().AwsS3 = NewS3()
+	gCtx.Inst().Prometheus = monitoring.New(monitoring.SetupOptions{
+		NodeName: config.NodeName,
+	})
+	gCtx.Inst().Query = query.New(mongoInst, redisInst)
+
+	return gCtx, nil
+}
@@ -0,0 +1,335 @@
+package fakes
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pipeline: run an aggregation pipeline over a set of documents
+func (s *mongoServer) pipeline(db string, docs []primitive.D, stages primitive.A, vars map[string]interface{}) ([]primitive.D, error) {
+	var err error
+	for _, st := range stages {
+		stage, _ := st.(primitive.D)
+		if len(stage) != 1 {
+			return nil, errBadValue("a pipeline stage specification object must contain exactly one field")
+		}
+
+		name, arg := stage[0].Key, stage[0].Value
+		spec, _ := arg.(primitive.D)
+		switch name {
+		case "$match":
+			out := []primitive.D{}
+			for _, d := range docs {
+				ok, err := match(d, spec, vars)
+				if err != nil {
+					return nil, errBadValue("%s", err)
+				}
+				if ok {
+					out = append(out, d)
+				}
+			}
+			docs = out
+		case "$sort":
+			sortDocs(docs, spec)
+		case "$skip":
+			docs = window(docs, int(toFloat(arg)), 0)
+		case "$limit":
+			docs = window(docs, 0, int(toFloat(arg)))
+		case "$project":
+			for i, d := range docs {
+				if docs[i], err = project(d, spec, vars); err != nil {
+					return nil, errBadValue("%s", err)
+				}
+			}
+		case "$set", "$addFields":
+			for i, d := range docs {
+				var v interface{} = d
+				for _, f := range spec {
+					val, err := eval(f.Value, d, vars)
+					if err != nil {
+						return nil, errBadValue("%s", err)
+					}
+					if _, rm := val.(removeMarker); rm {
+						v = unsetPath(v, splitPath(f.Key))
+						continue
+					}
+					if v, err = setPath(v, splitPath(f.Key), val); err != nil {
+						return nil, errBadValue("%s", err)
+					}
+				}
+				docs[i], _ = v.(primitive.D)
+			}
+		case "$unset":
+			fields := primitive.A{arg}
+			if a, ok := arg.(primitive.A); ok {
+				fields = a
+			}
+			for i, d := range docs {
+				var v interface{} = d
+				for _, f := range fields {
+					v = unsetPath(v, splitPath(fmt.Sprint(f)))
+				}
+				docs[i], _ = v.(primitive.D)
+			}
+		case "$replaceRoot", "$replaceWith":
+			expr := arg
+			if name == "$replaceRoot" {
+				expr, _ = getField(spec, "newRoot")
+			}
+			for i, d := range docs {
+				v, err := eval(expr, d, vars)
+				if err != nil {
+					return nil, errBadValue("%s", err)
+				}
+				nd, ok := v.(primitive.D)
+				if !ok {
+					return nil, errBadValue("'newRoot' expression must evaluate to an object")
+				}
+				docs[i] = nd
+			}
+		case "$unwind":
+			path, _ := arg.(string)
+			preserve := false
+			if spec != nil {
+				p, _ := getField(spec, "path")
+				path = fmt.Sprint(p)
+				pr, _ := getField(spec, "preserveNullAndEmptyArrays")
+				preserve = truthy(pr)
+			}
+			path = strings.TrimPrefix(path, "$")
+
+			out := []primitive.D{}
+			for _, d := range docs {
+				v, _ := resolve(d, splitPath(path))
+				arr, isArr := v.(primitive.A)
+				switch {
+				case !isArr && v != nil:
+					out = append(out, d)
+				case len(arr) == 0:
+					if preserve {
+						out = append(out, d)
+					}
+				default:
+					for _, el := range arr {
+						nv, _ := setPath(cloneDoc(d), splitPath(path), cloneValue(el))
+						out = append(out, nv.(primitive.D))
+					}
+				}
+			}
+			docs = out
+		case "$lookup":
+			if docs, err = s.lookup(db, docs, spec, vars); err != nil {
+				return nil, err
+			}
+		case "$count":
+			if n := len(docs); n > 0 {
+				docs = []primitive.D{{{Key: fmt.Sprint(arg), Value: int32(n)}}}
+			}
+		case "$group":
+			if docs, err = group(docs, spec, vars); err != nil {
+				return nil, err
+			}
+		case "$collStats":
+			// Only the document count is reported, as used by EstimatedDocumentCount
+			docs = []primitive.D{{{Key: "count", Value: int32(len(docs))}}}
+		case "$facet":
+			res := primitive.D{}
+			for _, f := range spec {
+				sub, _ := f.Value.(primitive.A)
+				out, err := s.pipeline(db, cloneDocs(docs), sub, vars)
+				if err != nil {
+					return nil, err
+				}
+				arr := make(primitive.A, len(out))
+				for i, d := range out {
+					arr[i] = d
+				}
+				res = append(res, primitive.E{Key: f.Key, Value: arr})
+			}
+			docs = []primitive.D{res}
+		default:
+			return nil, errBadValue("unsupported pipeline stage: %s", name)
+		}
+	}
+
+	return docs, nil
+}
+
+// lookup: join documents from another collection, by field equality and/or a sub-pipeline
+func (s *mongoServer) lookup(db string, docs []primitive.D, spec primitive.D, vars map[string]interface{}) ([]primitive.D, error) {
+	from, _ := getField(spec, "from")
+	as, _ := getField(spec, "as")
+	localField, hasLocal := getField(spec, "localField")
+	foreignField, _ := getField(spec, "foreignField")
+	let := docArg(spec, "let")
+	sub, _ := getField(spec, "pipeline")
+	stages, _ := sub.(primitive.A)
+
+	foreign := s.collection(db, fmt.Sprint(from)).docs
+	for i, d := range docs {
+		candidates := foreign
+		if hasLocal {
+			local, _ := resolve(d, splitPath(fmt.Sprint(localField)))
+			values := primitive.A{local}
+			if a, ok := local.(primitive.A); ok {
+				values = a
+			}
+
+			candidates = []primitive.D{}
+			for _, f := range foreign {
+				if matchPath(f, splitPath(fmt.Sprint(foreignField)), func(v interface{}, exists bool) bool {
+					for _, lv := range values {
+						if matchValue(v, exists, lv) {
+							return true
+						}
+					}
+					return false
+				}) {
+					candidates = append(candidates, f)
+				}
+			}
+		}
+
+		joined := cloneDocs(candidates)
+		if len(stages) > 0 {
+			lv := map[string]interface{}{}
+			for k, v := range vars {
+				lv[k] = v
+			}
+			for _, e := range let {
+				v, err := eval(e.Value, d, vars)
+				if err != nil {
+					return nil, errBadValue("%s", err)
+				}
+				lv[e.Key] = v
+			}
+
+			var err error
+			if joined, err = s.pipeline(db, joined, stages, lv); err != nil {
+				return nil, err
+			}
+		}
+
+		arr := make(primitive.A, len(joined))
+		for j, jd := range joined {
+			arr[j] = jd
+		}
+		v, err := setPath(d, splitPath(fmt.Sprint(as)), arr)
+		if err != nil {
+			return nil, errBadValue("%s", err)
+		}
+		docs[i] = v.(primitive.D)
+	}
+
+	return docs, nil
+}
+
+// group: the $group stage, supporting the common accumulators
+func group(docs []primitive.D, spec primitive.D, vars map[string]interface{}) ([]primitive.D, error) {
+	idExpr, _ := getField(spec, "_id")
+
+	type bucket struct {
+		id      interface{}
+		members []primitive.D
+	}
+	buckets := []*bucket{}
+	for _, d := range docs {
+		id, err := eval(idExpr, d, vars)
+		if err != nil {
+			return nil, errBadValue("%s", err)
+		}
+
+		var b *bucket
+		for _, x := range buckets {
+			if equal(x.id, id) {
+				b = x
+				break
+			}
+		}
+		if b == nil {
+			b = &bucket{id: id}
+			buckets = append(buckets, b)
+		}
+		b.members = append(b.members, d)
+	}
+
+	out := make([]primitive.D, len(buckets))
+	for i, b := range buckets {
+		res := primitive.D{{Key: "_id", Value: b.id}}
+		for _, f := range spec {
+			if f.Key == "_id" {
+				continue
+			}
+
+			acc, _ := f.Value.(primitive.D)
+			if len(acc) != 1 {
+				return nil, errBadValue("the field '%s' must be an accumulator object", f.Key)
+			}
+
+			values := primitive.A{}
+			for _, m := range b.members {
+				v, err := eval(acc[0].Value, m, vars)
+				if err != nil {
+					return nil, errBadValue("%s", err)
+				}
+				values = append(values, v)
+			}
+
+			var v interface{}
+			switch acc[0].Key {
+			case "$sum":
+				var total interface{} = int32(0)
+				for _, x := range values {
+					if typeOrder(x) == 2 {
+						total = addNumbers(total, x)
+					}
+				}
+				v = total
+			case "$avg":
+				total := 0.0
+				for _, x := range values {
+					total += toFloat(x)
+				}
+				if len(values) > 0 {
+					v = total / float64(len(values))
+				}
+			case "$min", "$max":
+				for _, x := range values {
+					if x == nil {
+						continue
+					}
+					c := compare(x, v)
+					if v == nil || (acc[0].Key == "$min" && c < 0) || (acc[0].Key == "$max" && c > 0) {
+						v = x
+					}
+				}
+			case "$first":
+				if len(values) > 0 {
+					v = values[0]
+				}
+			case "$last":
+				if len(values) > 0 {
+					v = values[len(values)-1]
+				}
+			case "$push":
+				v = values
+			case "$addToSet":
+				set := primitive.A{}
+				for _, x := range values {
+					if !containsValue(set, x) {
+						set = append(set, x)
+					}
+				}
+				v = set
+			default:
+				return nil, errBadValue("unknown group operator '%s'", acc[0].Key)
+			}
+			res = append(res, primitive.E{Key: f.Key, Value: v})
+		}
+		out[i] = res
+	}
+
+	return out, nil
+}
@@ -0,0 +1,577 @@
+package fakes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mongoError: a command error, sent to the client as { ok: 0 }
+type mongoError struct {
+	Code     int32
+	CodeName string
+	Message  string
+}
+
+func (e *mongoError) Error() string {
+	return e.Message
+}
+
+func errBadValue(format string, a ...interface{}) error {
+	return &mongoError{2, "BadValue", fmt.Sprintf(format, a...)}
+}
+
+// run: execute a command against a database and produce its reply
+func (s *mongoServer) run(db string, cmd primitive.D) primitive.D {
+	if len(cmd) == 0 {
+		return errorReply(errBadValue("empty command"))
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	name := cmd[0].Key
+	arg := cmd[0].Value
+	res, err := s.exec(db, name, arg, cmd)
+	if err != nil {
+		return errorReply(err)
+	}
+
+	return append(res, primitive.E{Key: "ok", Value: 1.0})
+}
+
+func errorReply(err error) primitive.D {
+	me, ok := err.(*mongoError)
+	if !ok {
+		me = &mongoError{2, "BadValue", err.Error()}
+	}
+
+	return primitive.D{
+		{Key: "ok", Value: 0.0},
+		{Key: "errmsg", Value: me.Message},
+		{Key: "code", Value: me.Code},
+		{Key: "codeName", Value: me.CodeName},
+	}
+}
+
+func cursorReply(db, col string, docs []primitive.D) primitive.D {
+	batch := make(primitive.A, len(docs))
+	for i, d := range docs {
+		batch[i] = d
+	}
+
+	return primitive.D{{Key: "cursor", Value: primitive.D{
+		{Key: "firstBatch", Value: batch},
+		{Key: "id", Value: int64(0)},
+		{Key: "ns", Value: db + "." + col},
+	}}}
+}
+
+func docArg(cmd primitive.D, key string) primitive.D {
+	v, _ := getField(cmd, key)
+	d, _ := v.(primitive.D)
+	return d
+}
+
+func intArg(cmd primitive.D, key string) int {
+	v, _ := getField(cmd, key)
+	n := int(toFloat(utilsOr(v, 0)))
+	if n < 0 {
+		n = -n
+	}
+	return n
+}
+
+func (s *mongoServer) exec(db, name string, arg interface{}, cmd primitive.D) (primitive.D, error) {
+	colName, _ := arg.(string)
+
+	switch strings.ToLower(name) {
+	case "ismaster", "hello":
+		return primitive.D{
+			{Key: "ismaster", Value: true},
+			{Key: "isWritablePrimary", Value: true},
+			{Key: "helloOk", Value: true},
+			{Key: "maxBsonObjectSize", Value: int32(16 * 1024 * 1024)},
+			{Key: "maxMessageSizeBytes", Value: int32(48000000)},
+			{Key: "maxWriteBatchSize", Value: int32(100000)},
+			{Key: "localTime", Value: primitive.NewDateTimeFromTime(time.Now())},
+			{Key: "minWireVersion", Value: int32(0)},
+			{Key: "maxWireVersion", Value: int32(13)},
+			{Key: "readOnly", Value: false},
+		}, nil
+	case "ping", "endsessions", "killcursors", "create", "dropindexes":
+		if name == "create" {
+			s.collection(db, colName)
+		}
+		return primitive.D{}, nil
+	case "buildinfo":
+		return primitive.D{
+			{Key: "version", Value: "5.0.0"},
+			{Key: "versionArray", Value: primitive.A{int32(5), int32(0), int32(0), int32(0)}},
+		}, nil
+	case "getmore":
+		col, _ := getField(cmd, "collection")
+		return primitive.D{{Key: "cursor", Value: primitive.D{
+			{Key: "nextBatch", Value: primitive.A{}},
+			{Key: "id", Value: int64(0)},
+			{Key: "ns", Value: fmt.Sprintf("%s.%v", db, col)},
+		}}}, nil
+	case "listcollections":
+		out := []primitive.D{}
+		for n := range s.dbs[db] {
+			out = append(out, primitive.D{{Key: "name", Value: n}, {Key: "type", Value: "collection"}})
+		}
+		return cursorReply(db, "$cmd.listCollections", out), nil
+	case "drop":
+		if _, ok := s.dbs[db][colName]; !ok {
+			return nil, &mongoError{26, "NamespaceNotFound", "ns not found"}
+		}
+		delete(s.dbs[db], colName)
+		return primitive.D{}, nil
+	case "dropdatabase":
+		delete(s.dbs, db)
+		return primitive.D{}, nil
+	case "createindexes":
+		col := s.collection(db, colName)
+		before := int32(len(col.indexes) + 1)
+		list, _ := getField(cmd, "indexes")
+		arr, _ := list.(primitive.A)
+		for _, v := range arr {
+			idx, _ := v.(primitive.D)
+			n, _ := getField(idx, "name")
+			if col.index(fmt.Sprint(n)) == nil {
+				col.indexes = append(col.indexes, idx)
+			}
+		}
+		return primitive.D{
+			{Key: "createdCollectionAutomatically", Value: false},
+			{Key: "numIndexesBefore", Value: before},
+			{Key: "numIndexesAfter", Value: int32(len(col.indexes) + 1)},
+		}, nil
+	case "listindexes":
+		col := s.collection(db, colName)
+		out := []primitive.D{{
+			{Key: "v", Value: int32(2)},
+			{Key: "key", Value: primitive.D{{Key: "_id", Value: int32(1)}}},
+			{Key: "name", Value: "_id_"},
+		}}
+		out = append(out, col.indexes...)
+		return cursorReply(db, colName, out), nil
+	case "insert":
+		return s.insert(db, colName, cmd)
+	case "find":
+		docs, err := s.find(db, colName, docArg(cmd, "filter"))
+		if err != nil {
+			return nil, err
+		}
+		if sort := docArg(cmd, "sort"); len(sort) > 0 {
+			sortDocs(docs, sort)
+		}
+		docs = window(docs, intArg(cmd, "skip"), intArg(cmd, "limit"))
+		if proj := docArg(cmd, "projection"); len(proj) > 0 {
+			for i, d := range docs {
+				if docs[i], err = project(d, proj, nil); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return cursorReply(db, colName, docs), nil
+	case "count":
+		docs, err := s.find(db, colName, docArg(cmd, "query"))
+		if err != nil {
+			return nil, err
+		}
+		docs = window(docs, intArg(cmd, "skip"), intArg(cmd, "limit"))
+		return primitive.D{{Key: "n", Value: int32(len(docs))}}, nil
+	case "distinct":
+		docs, err := s.find(db, colName, docArg(cmd, "query"))
+		if err != nil {
+			return nil, err
+		}
+		key, _ := getField(cmd, "key")
+		values := primitive.A{}
+		for _, d := range docs {
+			v, ok := resolve(d, splitPath(fmt.Sprint(key)))
+			if !ok {
+				continue
+			}
+			items := primitive.A{v}
+			if a, isArr := v.(primitive.A); isArr {
+				items = a
+			}
+			for _, it := range items {
+				if !containsValue(values, it) {
+					values = append(values, it)
+				}
+			}
+		}
+		return primitive.D{{Key: "values", Value: values}}, nil
+	case "update":
+		return s.update(db, colName, cmd)
+	case "delete":
+		return s.delete(db, colName, cmd)
+	case "findandmodify":
+		return s.findAndModify(db, colName, cmd)
+	case "aggregate":
+		var docs []primitive.D
+		if colName != "" {
+			docs = cloneDocs(s.collection(db, colName).docs)
+		}
+		pipeline, _ := getField(cmd, "pipeline")
+		stages, _ := pipeline.(primitive.A)
+		docs, err := s.pipeline(db, docs, stages, nil)
+		if err != nil {
+			return nil, err
+		}
+		return cursorReply(db, colName, docs), nil
+	}
+
+	return nil, &mongoError{59, "CommandNotFound", fmt.Sprintf("no such command: '%s'", name)}
+}
+
+func window(docs []primitive.D, skip, limit int) []primitive.D {
+	if skip >= len(docs) {
+		return []primitive.D{}
+	}
+	docs = docs[skip:]
+	if limit > 0 && limit < len(docs) {
+		docs = docs[:limit]
+	}
+	return docs
+}
+
+func cloneDocs(docs []primitive.D) []primitive.D {
+	out := make([]primitive.D, len(docs))
+	for i, d := range docs {
+		out[i] = cloneDoc(d)
+	}
+	return out
+}
+
+// find: get copies of the documents in a collection matching a filter
+func (s *mongoServer) find(db, colName string, filter primitive.D) ([]primitive.D, error) {
+	out := []primitive.D{}
+	for _, d := range s.collection(db, colName).docs {
+		ok, err := match(d, filter, nil)
+		if err != nil {
+			return nil, &mongoError{2, "BadValue", err.Error()}
+		}
+		if ok {
+			out = append(out, cloneDoc(d))
+		}
+	}
+	return out, nil
+}
+
+// index: find an index by name
+func (c *mongoCollection) index(name string) primitive.D {
+	for _, idx := range c.indexes {
+		if n, _ := getField(idx, "name"); n == name {
+			return idx
+		}
+	}
+	return nil
+}
+
+// checkUnique: verify that a document does not collide with another on _id or any unique index
+func (c *mongoCollection) checkUnique(db, colName string, doc primitive.D, skip int) error {
+	id, _ := getField(doc, "_id")
+	for i, d := range c.docs {
+		if i == skip {
+			continue
+		}
+
+		if other, _ := getField(d, "_id"); equal(id, other) {
+			return dupKeyError(db, colName, "_id_", id)
+		}
+		for _, idx := range c.indexes {
+			if u, _ := getField(idx, "unique"); !truthy(u) {
+				continue
+			}
+
+			keys := docArg(idx, "key")
+			same := len(keys) > 0
+			for _, k := range keys {
+				a, _ := resolve(doc, splitPath(k.Key))
+				b, _ := resolve(d, splitPath(k.Key))
+				if !equal(a, b) {
+					same = false
+					break
+				}
+			}
+			if same {
+				n, _ := getField(idx, "name")
+				return dupKeyError(db, colName, fmt.Sprint(n), id)
+			}
+		}
+	}
+
+	return nil
+}
+
+func dupKeyError(db, colName, index string, id interface{}) error {
+	return &mongoError{11000, "DuplicateKey", fmt.Sprintf(
+		"E11000 duplicate key error collection: %s.%s index: %s dup key: { %v }", db, colName, index, id,
+	)}
+}
+
+func writeError(i int, err error) primitive.D {
+	me, ok := err.(*mongoError)
+	if !ok {
+		me = &mongoError{2, "BadValue", err.Error()}
+	}
+
+	return primitive.D{
+		{Key: "index", Value: int32(i)},
+		{Key: "code", Value: me.Code},
+		{Key: "errmsg", Value: me.Message},
+	}
+}
+
+func writeReply(res primitive.D, errs primitive.A) primitive.D {
+	if len(errs) > 0 {
+		res = append(res, primitive.E{Key: "writeErrors", Value: errs})
+	}
+	return res
+}
+
+func (s *mongoServer) insert(db, colName string, cmd primitive.D) (primitive.D, error) {
+	col := s.collection(db, colName)
+	v, _ := getField(cmd, "documents")
+	docs, _ := v.(primitive.A)
+
+	n := int32(0)
+	errs := primitive.A{}
+	for i, it := range docs {
+		d, _ := it.(primitive.D)
+		d = cloneDoc(d)
+		if _, ok := getField(d, "_id"); !ok {
+			d = append(primitive.D{{Key: "_id", Value: primitive.NewObjectID()}}, d...)
+		}
+
+		if err := col.checkUnique(db, colName, d, -1); err != nil {
+			errs = append(errs, writeError(i, err))
+			if ordered, ok := getField(cmd, "ordered"); !ok || truthy(ordered) {
+				break
+			}
+			continue
+		}
+
+		col.docs = append(col.docs, d)
+		n++
+	}
+
+	return writeReply(primitive.D{{Key: "n", Value: n}}, errs), nil
+}
+
+// modify: apply an update to the document at an index, or insert a new document when i is -1
+func (s *mongoServer) modify(db, colName string, i int, filter, update primitive.D) (primitive.D, error) {
+	col := s.collection(db, colName)
+
+	var (
+		doc primitive.D
+		err error
+	)
+	if i < 0 {
+		// Upsert: seed the new document with the equality conditions of the filter
+		for _, e := range filter {
+			if strings.HasPrefix(e.Key, "$") || isOperatorDoc(e.Value) {
+				continue
+			}
+			v, _ := setPath(doc, splitPath(e.Key), cloneValue(e.Value))
+			doc, _ = v.(primitive.D)
+		}
+		if doc, err = applyUpdate(doc, update, filter, true); err != nil {
+			return nil, err
+		}
+		if _, ok := getField(doc, "_id"); !ok {
+			doc = append(primitive.D{{Key: "_id", Value: primitive.NewObjectID()}}, doc...)
+		}
+	} else if doc, err = applyUpdate(cloneDoc(col.docs[i]), update, filter, false); err != nil {
+		return nil, err
+	}
+
+	if err = col.checkUnique(db, colName, doc, i); err != nil {
+		return nil, err
+	}
+	if i < 0 {
+		col.docs = append(col.docs, doc)
+	} else {
+		col.docs[i] = doc
+	}
+
+	return doc, nil
+}
+
+// matching: get the indexes of documents in a collection matching a filter, in storage order
+func (s *mongoServer) matching(db, colName string, filter primitive.D, sort primitive.D) ([]int, error) {
+	col := s.collection(db, colName)
+
+	out := []int{}
+	for i, d := range col.docs {
+		ok, err := match(d, filter, nil)
+		if err != nil {
+			return nil, &mongoError{2, "BadValue", err.Error()}
+		}
+		if ok {
+			out = append(out, i)
+		}
+	}
+
+	if len(sort) > 0 && len(out) > 1 {
+		docs := make([]primitive.D, len(out))
+		for j, i := range out {
+			docs[j] = append(primitive.D{{Key: "__index", Value: int64(i)}}, col.docs[i]...)
+		}
+		sortDocs(docs, sort)
+		for j, d := range docs {
+			out[j] = int(d[0].Value.(int64))
+		}
+	}
+
+	return out, nil
+}
+
+func (s *mongoServer) update(db, colName string, cmd primitive.D) (primitive.D, error) {
+	v, _ := getField(cmd, "updates")
+	updates, _ := v.(primitive.A)
+
+	n, modified := int32(0), int32(0)
+	upserted := primitive.A{}
+	errs := primitive.A{}
+	for i, it := range updates {
+		u, _ := it.(primitive.D)
+		filter := docArg(u, "q")
+		upd := docArg(u, "u")
+		if upd == nil {
+			errs = append(errs, writeError(i, errBadValue("pipeline updates are not supported")))
+			break
+		}
+
+		idx, err := s.matching(db, colName, filter, nil)
+		if err != nil {
+			return nil, err
+		}
+		if multi, _ := getField(u, "multi"); !truthy(multi) && len(idx) > 1 {
+			idx = idx[:1]
+		}
+
+		if len(idx) == 0 {
+			if upsert, _ := getField(u, "upsert"); truthy(upsert) {
+				doc, err := s.modify(db, colName, -1, filter, upd)
+				if err != nil {
+					errs = append(errs, writeError(i, err))
+					break
+				}
+				id, _ := getField(doc, "_id")
+				upserted = append(upserted, primitive.D{{Key: "index", Value: int32(i)}, {Key: "_id", Value: id}})
+				n++
+			}
+			continue
+		}
+
+		for _, j := range idx {
+			before := cloneDoc(s.collection(db, colName).docs[j])
+			doc, err := s.modify(db, colName, j, filter, upd)
+			if err != nil {
+				errs = append(errs, writeError(i, err))
+				break
+			}
+			n++
+			if !equal(before, doc) {
+				modified++
+			}
+		}
+	}
+
+	res := primitive.D{{Key: "n", Value: n}, {Key: "nModified", Value: modified}}
+	if len(upserted) > 0 {
+		res = append(res, primitive.E{Key: "upserted", Value: upserted})
+	}
+	return writeReply(res, errs), nil
+}
+
+func (s *mongoServer) delete(db, colName string, cmd primitive.D) (primitive.D, error) {
+	col := s.collection(db, colName)
+	v, _ := getField(cmd, "deletes")
+	deletes, _ := v.(primitive.A)
+
+	n := int32(0)
+	for _, it := range deletes {
+		d, _ := it.(primitive.D)
+		idx, err := s.matching(db, colName, docArg(d, "q"), nil)
+		if err != nil {
+			return nil, err
+		}
+		if limit := intArg(d, "limit"); limit > 0 && len(idx) > limit {
+			idx = idx[:limit]
+		}
+
+		for j := len(idx) - 1; j >= 0; j-- {
+			i := idx[j]
+			col.docs = append(col.docs[:i], col.docs[i+1:]...)
+			n++
+		}
+	}
+
+	return primitive.D{{Key: "n", Value: n}}, nil
+}
+
+func (s *mongoServer) findAndModify(db, colName string, cmd primitive.D) (primitive.D, error) {
+	col := s.collection(db, colName)
+	filter := docArg(cmd, "query")
+	upd := docArg(cmd, "update")
+	remove, _ := getField(cmd, "remove")
+	returnNew, _ := getField(cmd, "new")
+	upsert, _ := getField(cmd, "upsert")
+
+	idx, err := s.matching(db, colName, filter, docArg(cmd, "sort"))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		value   interface{}
+		lastErr = primitive.D{{Key: "n", Value: int32(0)}, {Key: "updatedExisting", Value: false}}
+	)
+	switch {
+	case len(idx) == 0 && truthy(upsert) && !truthy(remove):
+		doc, err := s.modify(db, colName, -1, filter, upd)
+		if err != nil {
+			return nil, err
+		}
+		id, _ := getField(doc, "_id")
+		lastErr = primitive.D{{Key: "n", Value: int32(1)}, {Key: "updatedExisting", Value: false}, {Key: "upserted", Value: id}}
+		if truthy(returnNew) {
+			value = cloneDoc(doc)
+		}
+	case len(idx) == 0:
+	case truthy(remove):
+		value = col.docs[idx[0]]
+		col.docs = append(col.docs[:idx[0]], col.docs[idx[0]+1:]...)
+		lastErr = primitive.D{{Key: "n", Value: int32(1)}}
+	default:
+		before := cloneDoc(col.docs[idx[0]])
+		doc, err := s.modify(db, colName, idx[0], filter, upd)
+		if err != nil {
+			return nil, err
+		}
+		lastErr = primitive.D{{Key: "n", Value: int32(1)}, {Key: "updatedExisting", Value: true}}
+		value = before
+		if truthy(returnNew) {
+			value = cloneDoc(doc)
+		}
+	}
+
+	if d, ok := value.(primitive.D); ok {
+		if fields := docArg(cmd, "fields"); len(fields) > 0 {
+			if value, err = project(d, fields, nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return primitive.D{{Key: "lastErrorObject", Value: lastErr}, {Key: "value", Value: value}}, nil
+}
@@ -0,0 +1,298 @@
+package fakes
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/instance"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/wiremessage"
+)
+
+// Mongo: an in-memory mongo instance
+//
+// The real driver is used, connected to an in-process server speaking the wire protocol over net.Pipe,
+// so queries, cursors and decoding behave as they would against a real deployment
+type Mongo struct {
+	srv    *mongoServer
+	client *mongodriver.Client
+	db     *mongodriver.Database
+}
+
+var _ instance.Mongo = (*Mongo)(nil)
+
+// NewMongo: create an empty in-memory mongo instance using the given database name
+func NewMongo(ctx context.Context, dbName string) (*Mongo, error) {
+	srv := &mongoServer{dbs: map[string]map[string]*mongoCollection{}}
+
+	client, err := mongodriver.Connect(ctx, options.Client().
+		SetHosts([]string{"fake-mongo:27017"}).
+		SetDirect(true).
+		SetDialer(srv),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mongo{
+		srv:    srv,
+		client: client,
+		db:     client.Database(dbName),
+	}, nil
+}
+
+func (m *Mongo) Collection(name mongo.CollectionName) *mongodriver.Collection {
+	return m.db.Collection(string(name))
+}
+
+func (m *Mongo) Ping(ctx context.Context) error {
+	return m.client.Ping(ctx, nil)
+}
+
+func (m *Mongo) RawClient() *mongodriver.Client {
+	return m.client
+}
+
+func (m *Mongo) RawDatabase() *mongodriver.Database {
+	return m.db
+}
+
+func (m *Mongo) System(ctx context.Context) structures.System {
+	result := structures.System{}
+	_ = m.Collection(mongo.CollectionNameSystem).FindOne(ctx, bson.M{}).Decode(&result)
+	return result
+}
+
+// Seed: insert documents into a collection directly, bypassing the driver
+func (m *Mongo) Seed(name mongo.CollectionName, docs ...interface{}) error {
+	m.srv.mx.Lock()
+	defer m.srv.mx.Unlock()
+
+	col := m.srv.collection(m.db.Name(), string(name))
+	for _, v := range docs {
+		b, err := bson.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		d := primitive.D{}
+		if err = bson.Unmarshal(b, &d); err != nil {
+			return err
+		}
+		if _, ok := getField(d, "_id"); !ok {
+			d = append(primitive.D{{Key: "_id", Value: primitive.NewObjectID()}}, d...)
+		}
+		col.docs = append(col.docs, d)
+	}
+
+	return nil
+}
+
+// Documents: get a copy of all documents stored in a collection
+func (m *Mongo) Documents(name mongo.CollectionName) []bson.Raw {
+	m.srv.mx.Lock()
+	defer m.srv.mx.Unlock()
+
+	col := m.srv.collection(m.db.Name(), string(name))
+	out := make([]bson.Raw, len(col.docs))
+	for i, d := range col.docs {
+		out[i], _ = bson.Marshal(d)
+	}
+
+	return out
+}
+
+// Close: disconnect the client
+func (m *Mongo) Close(ctx context.Context) error {
+	return m.client.Disconnect(ctx)
+}
+
+type mongoServer struct {
+	mx  sync.Mutex
+	dbs map[string]map[string]*mongoCollection
+}
+
+type mongoCollection struct {
+	docs    []primitive.D
+	indexes []primitive.D
+}
+
+// DialContext: open a connection to the server. This satisfies options.ContextDialer
+func (s *mongoServer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	client, server := net.Pipe()
+	go s.serve(server)
+
+	return client, nil
+}
+
+// collection: get a collection, creating it if it does not exist. The lock must be held
+func (s *mongoServer) collection(db, name string) *mongoCollection {
+	cols, ok := s.dbs[db]
+	if !ok {
+		cols = map[string]*mongoCollection{}
+		s.dbs[db] = cols
+	}
+
+	col, ok := cols[name]
+	if !ok {
+		col = &mongoCollection{}
+		cols[name] = col
+	}
+
+	return col
+}
+
+// serve: read messages from a connection and reply to each of them
+func (s *mongoServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	header := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+
+		length := int32(binary.LittleEndian.Uint32(header))
+		if length < 16 {
+			return
+		}
+		msg := make([]byte, length)
+		copy(msg, header)
+		if _, err := io.ReadFull(conn, msg[16:]); err != nil {
+			return
+		}
+
+		reply, err := s.handleMessage(msg)
+		if err != nil {
+			return
+		}
+		if reply == nil {
+			continue
+		}
+		if _, err = conn.Write(reply); err != nil {
+			return
+		}
+	}
+}
+
+func (s *mongoServer) handleMessage(msg []byte) ([]byte, error) {
+	_, reqID, _, opcode, rem, ok := wiremessage.ReadHeader(msg)
+	if !ok {
+		return nil, fmt.Errorf("malformed header")
+	}
+
+	switch opcode {
+	case wiremessage.OpQuery:
+		// Only used by the driver for the initial handshake
+		_, rem, _ = wiremessage.ReadQueryFlags(rem)
+		ns, rem, _ := wiremessage.ReadQueryFullCollectionName(rem)
+		_, rem, _ = wiremessage.ReadQueryNumberToSkip(rem)
+		_, rem, _ = wiremessage.ReadQueryNumberToReturn(rem)
+		query, _, ok := wiremessage.ReadQueryQuery(rem)
+		if !ok {
+			return nil, fmt.Errorf("malformed query")
+		}
+
+		cmd := primitive.D{}
+		if err := bson.Unmarshal(query, &cmd); err != nil {
+			return nil, err
+		}
+		if inner, ok := getField(cmd, "$query"); ok {
+			cmd, _ = inner.(primitive.D)
+		}
+
+		db := ns
+		for i := range ns {
+			if ns[i] == '.' {
+				db = ns[:i]
+				break
+			}
+		}
+
+		doc, err := bson.Marshal(s.run(db, cmd))
+		if err != nil {
+			return nil, err
+		}
+
+		idx, b := wiremessage.AppendHeaderStart(nil, wiremessage.NextRequestID(), reqID, wiremessage.OpReply)
+		b = wiremessage.AppendReplyFlags(b, 0)
+		b = wiremessage.AppendReplyCursorID(b, 0)
+		b = wiremessage.AppendReplyStartingFrom(b, 0)
+		b = wiremessage.AppendReplyNumberReturned(b, 1)
+		b = append(b, doc...)
+		return bsoncore.UpdateLength(b, idx, int32(len(b[idx:]))), nil
+	case wiremessage.OpMsg:
+		flags, rem, _ := wiremessage.ReadMsgFlags(rem)
+		if flags&wiremessage.ChecksumPresent != 0 {
+			rem = rem[:len(rem)-4]
+		}
+
+		cmd := primitive.D{}
+		for len(rem) > 0 {
+			var stype wiremessage.SectionType
+			stype, rem, ok = wiremessage.ReadMsgSectionType(rem)
+			if !ok {
+				return nil, fmt.Errorf("malformed section")
+			}
+
+			switch stype {
+			case wiremessage.SingleDocument:
+				var doc bsoncore.Document
+				if doc, rem, ok = wiremessage.ReadMsgSectionSingleDocument(rem); !ok {
+					return nil, fmt.Errorf("malformed document")
+				}
+				body := primitive.D{}
+				if err := bson.Unmarshal(doc, &body); err != nil {
+					return nil, err
+				}
+				cmd = append(body, cmd...)
+			case wiremessage.DocumentSequence:
+				var (
+					id   string
+					docs []bsoncore.Document
+				)
+				if id, docs, rem, ok = wiremessage.ReadMsgSectionDocumentSequence(rem); !ok {
+					return nil, fmt.Errorf("malformed document sequence")
+				}
+				arr := primitive.A{}
+				for _, doc := range docs {
+					d := primitive.D{}
+					if err := bson.Unmarshal(doc, &d); err != nil {
+						return nil, err
+					}
+					arr = append(arr, d)
+				}
+				cmd = append(cmd, primitive.E{Key: id, Value: arr})
+			}
+		}
+
+		db, _ := getField(cmd, "$db")
+		res := s.run(fmt.Sprint(db), cmd)
+		if flags&wiremessage.MoreToCome != 0 {
+			return nil, nil // unacknowledged write
+		}
+
+		doc, err := bson.Marshal(res)
+		if err != nil {
+			return nil, err
+		}
+
+		idx, b := wiremessage.AppendHeaderStart(nil, wiremessage.NextRequestID(), reqID, wiremessage.OpMsg)
+		b = wiremessage.AppendMsgFlags(b, 0)
+		b = wiremessage.AppendMsgSectionType(b, wiremessage.SingleDocument)
+		b = append(b, doc...)
+		return bsoncore.UpdateLength(b, idx, int32(len(b[idx:]))), nil
+	}
+
+	return nil, fmt.Errorf("unsupported opcode %s", opcode)
+}
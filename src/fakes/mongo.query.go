@@ -0,0 +1,1158 @@
+package fakes
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// This file implements the subset of mongo's query, update and expression semantics used by this service.
+// Documents are handled in their decoded form: primitive.D for documents and primitive.A for arrays
+
+// getField: get the value of a top-level field in a document
+func getField(d primitive.D, key string) (interface{}, bool) {
+	for _, e := range d {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// resolve: get the value at a path, collecting the values of each element when passing through an array of documents
+func resolve(v interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return v, true
+	}
+
+	switch x := v.(type) {
+	case primitive.D:
+		val, ok := getField(x, path[0])
+		if !ok {
+			return nil, false
+		}
+		return resolve(val, path[1:])
+	case primitive.A:
+		if i, err := strconv.Atoi(path[0]); err == nil {
+			if i < 0 || i >= len(x) {
+				return nil, false
+			}
+			return resolve(x[i], path[1:])
+		}
+
+		out := primitive.A{}
+		for _, e := range x {
+			if r, ok := resolve(e, path); ok {
+				out = append(out, r)
+			}
+		}
+		return out, true
+	}
+
+	return nil, false
+}
+
+func splitPath(p string) []string {
+	return strings.Split(p, ".")
+}
+
+// setPath: set the value at a path, creating intermediate documents as needed. Numeric segments index into arrays
+func setPath(v interface{}, path []string, val interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return val, nil
+	}
+
+	switch x := v.(type) {
+	case nil:
+		return setPath(primitive.D{}, path, val)
+	case primitive.D:
+		for i, e := range x {
+			if e.Key == path[0] {
+				nv, err := setPath(e.Value, path[1:], val)
+				if err != nil {
+					return nil, err
+				}
+				x[i].Value = nv
+				return x, nil
+			}
+		}
+
+		nv, err := setPath(nil, path[1:], val)
+		if err != nil {
+			return nil, err
+		}
+		return append(x, primitive.E{Key: path[0], Value: nv}), nil
+	case primitive.A:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 {
+			return nil, fmt.Errorf("cannot create field '%s' in element {%v}", path[0], x)
+		}
+		for len(x) <= i {
+			x = append(x, nil)
+		}
+		nv, err := setPath(x[i], path[1:], val)
+		if err != nil {
+			return nil, err
+		}
+		x[i] = nv
+		return x, nil
+	}
+
+	return nil, fmt.Errorf("cannot create field '%s' in element {%v}", path[0], v)
+}
+
+// unsetPath: remove the value at a path, if it exists
+func unsetPath(v interface{}, path []string) interface{} {
+	switch x := v.(type) {
+	case primitive.D:
+		for i, e := range x {
+			if e.Key != path[0] {
+				continue
+			}
+			if len(path) == 1 {
+				return append(x[:i:i], x[i+1:]...)
+			}
+			x[i].Value = unsetPath(e.Value, path[1:])
+			return x
+		}
+	case primitive.A:
+		if i, err := strconv.Atoi(path[0]); err == nil && i >= 0 && i < len(x) {
+			if len(path) == 1 {
+				x[i] = nil
+			} else {
+				x[i] = unsetPath(x[i], path[1:])
+			}
+		}
+	}
+
+	return v
+}
+
+// Type brackets, in mongo's comparison order
+func typeOrder(v interface{}) int {
+	switch v.(type) {
+	case nil, primitive.Null, primitive.Undefined:
+		return 1
+	case int, int32, int64, float64, primitive.Decimal128:
+		return 2
+	case string, primitive.Symbol:
+		return 3
+	case primitive.D, bson.M:
+		return 4
+	case primitive.A:
+		return 5
+	case primitive.Binary:
+		return 6
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime, time.Time:
+		return 9
+	case primitive.Timestamp:
+		return 10
+	case primitive.Regex:
+		return 11
+	}
+	return 12
+}
+
+func toFloat(v interface{}) float64 {
+	switch x := v.(type) {
+	case int:
+		return float64(x)
+	case int32:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case float64:
+		return x
+	case primitive.Decimal128:
+		f, _ := strconv.ParseFloat(x.String(), 64)
+		return f
+	}
+	return math.NaN()
+}
+
+// compare: order two values the way mongo does
+func compare(a, b interface{}) int {
+	ta, tb := typeOrder(a), typeOrder(b)
+	if ta != tb {
+		return ta - tb
+	}
+
+	switch x := a.(type) {
+	case int, int32, int64, float64, primitive.Decimal128:
+		fa, fb := toFloat(x), toFloat(b)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(x, fmt.Sprint(b))
+	case primitive.D:
+		y := b.(primitive.D)
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := strings.Compare(x[i].Key, y[i].Key); c != 0 {
+				return c
+			}
+			if c := compare(x[i].Value, y[i].Value); c != 0 {
+				return c
+			}
+		}
+		return len(x) - len(y)
+	case primitive.A:
+		y := b.(primitive.A)
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := compare(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return len(x) - len(y)
+	case primitive.Binary:
+		return bytes.Compare(x.Data, b.(primitive.Binary).Data)
+	case primitive.ObjectID:
+		y := b.(primitive.ObjectID)
+		return bytes.Compare(x[:], y[:])
+	case bool:
+		y := b.(bool)
+		if x == y {
+			return 0
+		}
+		if !x {
+			return -1
+		}
+		return 1
+	case primitive.DateTime, time.Time:
+		da, db := toTime(a), toTime(b)
+		switch {
+		case da.Before(db):
+			return -1
+		case da.After(db):
+			return 1
+		}
+		return 0
+	case primitive.Timestamp:
+		y := b.(primitive.Timestamp)
+		if x.T != y.T {
+			return int(x.T) - int(y.T)
+		}
+		return int(x.I) - int(y.I)
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func toTime(v interface{}) time.Time {
+	switch x := v.(type) {
+	case primitive.DateTime:
+		return x.Time()
+	case time.Time:
+		return x
+	}
+	return time.Time{}
+}
+
+func equal(a, b interface{}) bool {
+	return typeOrder(a) == typeOrder(b) && compare(a, b) == 0
+}
+
+// matchPath: test the values at a path against a predicate
+//
+// As in mongo, arrays match when either the array itself or any of its elements does
+func matchPath(v interface{}, path []string, pred func(v interface{}, exists bool) bool) bool {
+	if len(path) == 0 {
+		if arr, ok := v.(primitive.A); ok {
+			if pred(arr, true) {
+				return true
+			}
+			for _, e := range arr {
+				if pred(e, true) {
+					return true
+				}
+			}
+			return false
+		}
+		return pred(v, true)
+	}
+
+	switch x := v.(type) {
+	case primitive.D:
+		val, ok := getField(x, path[0])
+		if !ok {
+			return pred(nil, false)
+		}
+		return matchPath(val, path[1:], pred)
+	case primitive.A:
+		if i, err := strconv.Atoi(path[0]); err == nil {
+			if i >= 0 && i < len(x) && matchPath(x[i], path[1:], pred) {
+				return true
+			}
+		}
+
+		found := false
+		for _, e := range x {
+			if d, ok := e.(primitive.D); ok {
+				if _, ok := getField(d, path[0]); ok {
+					found = true
+				}
+				if matchPath(d, path, pred) {
+					return true
+				}
+			}
+		}
+		if !found {
+			return pred(nil, false)
+		}
+		return false
+	}
+
+	return pred(nil, false)
+}
+
+func isOperatorDoc(v interface{}) bool {
+	d, ok := v.(primitive.D)
+	return ok && len(d) > 0 && strings.HasPrefix(d[0].Key, "$")
+}
+
+// match: test a document against a query filter
+func match(doc primitive.D, filter primitive.D, vars map[string]interface{}) (bool, error) {
+	for _, e := range filter {
+		ok := true
+		var err error
+
+		switch e.Key {
+		case "$and", "$or", "$nor":
+			subs, _ := e.Value.(primitive.A)
+			matched := 0
+			for _, s := range subs {
+				sd, _ := s.(primitive.D)
+				m, err := match(doc, sd, vars)
+				if err != nil {
+					return false, err
+				}
+				if m {
+					matched++
+				}
+			}
+			switch e.Key {
+			case "$and":
+				ok = matched == len(subs)
+			case "$or":
+				ok = matched > 0
+			case "$nor":
+				ok = matched == 0
+			}
+		case "$expr":
+			var v interface{}
+			if v, err = eval(e.Value, doc, vars); err != nil {
+				return false, err
+			}
+			ok = truthy(v)
+		case "$comment":
+		default:
+			if strings.HasPrefix(e.Key, "$") {
+				return false, fmt.Errorf("unknown top level operator: %s", e.Key)
+			}
+			ok, err = matchCondition(doc, splitPath(e.Key), e.Value)
+		}
+
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// matchCondition: test the values at a path against a condition, either a value or a document of operators
+func matchCondition(doc primitive.D, path []string, cond interface{}) (bool, error) {
+	if !isOperatorDoc(cond) {
+		return matchPath(doc, path, func(v interface{}, exists bool) bool {
+			return matchValue(v, exists, cond)
+		}), nil
+	}
+
+	ops := cond.(primitive.D)
+	for _, op := range ops {
+		var ok bool
+
+		switch op.Key {
+		case "$eq":
+			ok = matchPath(doc, path, func(v interface{}, exists bool) bool { return matchValue(v, exists, op.Value) })
+		case "$ne":
+			ok = !matchPath(doc, path, func(v interface{}, exists bool) bool { return matchValue(v, exists, op.Value) })
+		case "$gt", "$gte", "$lt", "$lte":
+			ok = matchPath(doc, path, func(v interface{}, exists bool) bool {
+				if !exists || typeOrder(v) != typeOrder(op.Value) {
+					return false
+				}
+				c := compare(v, op.Value)
+				switch op.Key {
+				case "$gt":
+					return c > 0
+				case "$gte":
+					return c >= 0
+				case "$lt":
+					return c < 0
+				}
+				return c <= 0
+			})
+		case "$in", "$nin":
+			list, _ := op.Value.(primitive.A)
+			ok = matchPath(doc, path, func(v interface{}, exists bool) bool {
+				for _, x := range list {
+					if matchValue(v, exists, x) {
+						return true
+					}
+				}
+				return false
+			})
+			if op.Key == "$nin" {
+				ok = !ok
+			}
+		case "$exists":
+			ok = matchPath(doc, path, func(v interface{}, exists bool) bool { return exists })
+			if !truthy(op.Value) {
+				ok = !ok
+			}
+		case "$regex":
+			opts, _ := getField(ops, "$options")
+			re, err := compileRegex(fmt.Sprint(op.Value), fmt.Sprint(utilsOr(opts, "")))
+			if err != nil {
+				return false, err
+			}
+			ok = matchPath(doc, path, func(v interface{}, exists bool) bool {
+				s, isStr := v.(string)
+				return isStr && re.MatchString(s)
+			})
+		case "$options":
+			ok = true
+		case "$not":
+			m, err := matchCondition(doc, path, utilsRegexCond(op.Value))
+			if err != nil {
+				return false, err
+			}
+			ok = !m
+		case "$size":
+			ok = matchPath(doc, path, func(v interface{}, exists bool) bool {
+				a, isArr := v.(primitive.A)
+				return isArr && float64(len(a)) == toFloat(op.Value)
+			})
+		case "$bitsAllSet", "$bitsAllClear", "$bitsAnySet", "$bitsAnyClear":
+			// Only numeric bitmasks are supported
+			mask := int64(toFloat(op.Value))
+			ok = matchPath(doc, path, func(v interface{}, exists bool) bool {
+				if !exists || typeOrder(v) != typeOrder(int64(0)) {
+					return false
+				}
+				n := int64(toFloat(v))
+				switch op.Key {
+				case "$bitsAllSet":
+					return n&mask == mask
+				case "$bitsAllClear":
+					return n&mask == 0
+				case "$bitsAnySet":
+					return n&mask != 0
+				}
+				return n&mask != mask
+			})
+		case "$all":
+			list, _ := op.Value.(primitive.A)
+			ok = true
+			for _, x := range list {
+				if !matchPath(doc, path, func(v interface{}, exists bool) bool { return matchValue(v, exists, x) }) {
+					ok = false
+					break
+				}
+			}
+		case "$elemMatch":
+			sub, _ := op.Value.(primitive.D)
+			var err error
+			ok = false
+			val, _ := resolve(doc, path)
+			arr, _ := val.(primitive.A)
+			for _, el := range arr {
+				var m bool
+				if ed, isDoc := el.(primitive.D); isDoc && !isOperatorDoc(sub) {
+					m, err = match(ed, sub, nil)
+				} else {
+					m, err = matchCondition(primitive.D{{Key: "v", Value: el}}, []string{"v"}, sub)
+				}
+				if err != nil {
+					return false, err
+				}
+				if m {
+					ok = true
+					break
+				}
+			}
+		default:
+			return false, fmt.Errorf("unknown operator: %s", op.Key)
+		}
+
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// matchValue: test a single value against an equality condition
+func matchValue(v interface{}, exists bool, cond interface{}) bool {
+	if re, ok := cond.(primitive.Regex); ok {
+		s, isStr := v.(string)
+		if !isStr {
+			return false
+		}
+		r, err := compileRegex(re.Pattern, re.Options)
+		return err == nil && r.MatchString(s)
+	}
+	if cond == nil {
+		return !exists || v == nil
+	}
+
+	return exists && equal(v, cond)
+}
+
+func compileRegex(pattern, options string) (*regexp.Regexp, error) {
+	flags := ""
+	for _, o := range options {
+		switch o {
+		case 'i', 'm', 's':
+			flags += string(o)
+		}
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+
+	return regexp.Compile(pattern)
+}
+
+func utilsOr(v interface{}, def interface{}) interface{} {
+	if v == nil {
+		return def
+	}
+	return v
+}
+
+// utilsRegexCond: a regex given to $not is treated as a $regex condition
+func utilsRegexCond(v interface{}) interface{} {
+	if re, ok := v.(primitive.Regex); ok {
+		return primitive.D{{Key: "$regex", Value: re.Pattern}, {Key: "$options", Value: re.Options}}
+	}
+	return v
+}
+
+func truthy(v interface{}) bool {
+	switch x := v.(type) {
+	case nil, primitive.Null, primitive.Undefined:
+		return false
+	case bool:
+		return x
+	case int, int32, int64, float64:
+		return toFloat(x) != 0
+	}
+	return true
+}
+
+// applyUpdate: apply an update document (or a replacement) to a document.
+// The filter which selected the document resolves the positional operator ($) in field paths
+func applyUpdate(doc primitive.D, update primitive.D, filter primitive.D, inserting bool) (primitive.D, error) {
+	if !isOperatorDoc(update) {
+		// Replacement, keeping the ID
+		id, _ := getField(doc, "_id")
+		out := primitive.D{{Key: "_id", Value: id}}
+		for _, e := range update {
+			if e.Key != "_id" {
+				out = append(out, e)
+			}
+		}
+		return out, nil
+	}
+
+	var (
+		v   interface{} = doc
+		err error
+	)
+	for _, op := range update {
+		fields, _ := op.Value.(primitive.D)
+		for _, f := range fields {
+			path, perr := positionalPath(v, splitPath(f.Key), filter)
+			if perr != nil {
+				return nil, perr
+			}
+
+			cur, exists := resolve(v, path)
+			switch op.Key {
+			case "$set":
+				v, err = setPath(v, path, cloneValue(f.Value))
+			case "$setOnInsert":
+				if inserting {
+					v, err = setPath(v, path, cloneValue(f.Value))
+				}
+			case "$unset":
+				v = unsetPath(v, path)
+			case "$inc":
+				n := f.Value
+				if exists {
+					n = addNumbers(cur, f.Value)
+				}
+				v, err = setPath(v, path, n)
+			case "$min", "$max":
+				c := compare(f.Value, cur)
+				if !exists || (op.Key == "$min" && c < 0) || (op.Key == "$max" && c > 0) {
+					v, err = setPath(v, path, cloneValue(f.Value))
+				}
+			case "$currentDate":
+				v, err = setPath(v, path, primitive.NewDateTimeFromTime(time.Now()))
+			case "$push", "$addToSet":
+				arr, _ := cur.(primitive.A)
+				if exists && cur != nil && arr == nil {
+					return nil, fmt.Errorf("the field '%s' must be an array", f.Key)
+				}
+
+				items := primitive.A{f.Value}
+				if d, ok := f.Value.(primitive.D); ok && len(d) > 0 && d[0].Key == "$each" {
+					items, _ = d[0].Value.(primitive.A)
+				}
+				for _, it := range items {
+					if op.Key == "$addToSet" && containsValue(arr, it) {
+						continue
+					}
+					arr = append(arr, cloneValue(it))
+				}
+				if arr == nil {
+					arr = primitive.A{}
+				}
+				v, err = setPath(v, path, arr)
+			case "$pull":
+				arr, _ := cur.(primitive.A)
+				out := primitive.A{}
+				for _, el := range arr {
+					var m bool
+					if cd, ok := f.Value.(primitive.D); ok {
+						if ed, isDoc := el.(primitive.D); isDoc && !isOperatorDoc(cd) {
+							m, err = match(ed, cd, nil)
+						} else {
+							m, err = matchCondition(primitive.D{{Key: "v", Value: el}}, []string{"v"}, cd)
+						}
+						if err != nil {
+							return nil, err
+						}
+					} else {
+						m = equal(el, f.Value)
+					}
+					if !m {
+						out = append(out, el)
+					}
+				}
+				if exists {
+					v, err = setPath(v, path, out)
+				}
+			default:
+				return nil, fmt.Errorf("unknown modifier: %s", op.Key)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	d, _ := v.(primitive.D)
+	return d, nil
+}
+
+// positionalPath: replace the positional operator in a path with the index of the first element
+// of the array of documents before it which matches the conditions the filter puts on that array
+func positionalPath(doc interface{}, path []string, filter primitive.D) ([]string, error) {
+	for i, p := range path {
+		if p != "$" {
+			if strings.HasPrefix(p, "$") {
+				return nil, fmt.Errorf("unsupported positional operator %s", p)
+			}
+			continue
+		}
+
+		prefix := strings.Join(path[:i], ".")
+		cond := primitive.D{}
+		for _, e := range filter {
+			switch {
+			case strings.HasPrefix(e.Key, prefix+"."):
+				cond = append(cond, primitive.E{Key: strings.TrimPrefix(e.Key, prefix+"."), Value: e.Value})
+			case e.Key == prefix:
+				if d, ok := e.Value.(primitive.D); ok && len(d) == 1 && d[0].Key == "$elemMatch" {
+					em, _ := d[0].Value.(primitive.D)
+					cond = append(cond, em...)
+				}
+			}
+		}
+
+		v, _ := resolve(doc, path[:i])
+		arr, _ := v.(primitive.A)
+		for j, el := range arr {
+			ed, _ := el.(primitive.D)
+			if len(cond) == 0 || ed == nil {
+				break
+			}
+			ok, err := match(ed, cond, nil)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				out := append(append([]string{}, path[:i]...), strconv.Itoa(j))
+				return append(out, path[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("the positional operator did not find the match needed from the query (%s)", strings.Join(path, "."))
+	}
+	return path, nil
+}
+
+func containsValue(arr primitive.A, v interface{}) bool {
+	for _, e := range arr {
+		if equal(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func addNumbers(a, b interface{}) interface{} {
+	switch x := a.(type) {
+	case int32:
+		if y, ok := b.(int32); ok {
+			return x + y
+		}
+	case int64:
+		switch y := b.(type) {
+		case int32:
+			return x + int64(y)
+		case int64:
+			return x + y
+		}
+	}
+	return toFloat(a) + toFloat(b)
+}
+
+// cloneValue: deep copy a decoded value
+func cloneValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case primitive.D:
+		out := make(primitive.D, len(x))
+		for i, e := range x {
+			out[i] = primitive.E{Key: e.Key, Value: cloneValue(e.Value)}
+		}
+		return out
+	case primitive.A:
+		out := make(primitive.A, len(x))
+		for i, e := range x {
+			out[i] = cloneValue(e)
+		}
+		return out
+	}
+	return v
+}
+
+func cloneDoc(d primitive.D) primitive.D {
+	return cloneValue(d).(primitive.D)
+}
+
+// sortDocs: sort documents by a sort specification
+func sortDocs(docs []primitive.D, spec primitive.D) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, s := range spec {
+			path := splitPath(s.Key)
+			a, _ := resolve(docs[i], path)
+			b, _ := resolve(docs[j], path)
+
+			c := compare(a, b)
+			if toFloat(s.Value) < 0 {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+// project: apply a projection to a document. Inclusions, exclusions and (in aggregations) expressions are supported
+func project(doc primitive.D, spec primitive.D, vars map[string]interface{}) (primitive.D, error) {
+	inclusion := false
+	for _, s := range spec {
+		if s.Key == "_id" {
+			continue
+		}
+		if isInclusion(s.Value) || !isExclusion(s.Value) {
+			inclusion = true
+		}
+	}
+
+	if !inclusion {
+		out := cloneDoc(doc)
+		for _, s := range spec {
+			out, _ = unsetPath(out, splitPath(s.Key)).(primitive.D)
+		}
+		return out, nil
+	}
+
+	var (
+		out interface{} = primitive.D{}
+		err error
+	)
+	if id, ok := getField(doc, "_id"); ok {
+		if v, set := getField(spec, "_id"); !set || !isExclusion(v) {
+			out = primitive.D{{Key: "_id", Value: id}}
+		}
+	}
+	for _, s := range spec {
+		if s.Key == "_id" && (isInclusion(s.Value) || isExclusion(s.Value)) {
+			continue
+		}
+
+		var val interface{}
+		if isInclusion(s.Value) {
+			v, ok := resolve(doc, splitPath(s.Key))
+			if !ok {
+				continue
+			}
+			val = cloneValue(v)
+		} else if val, err = eval(s.Value, doc, vars); err != nil {
+			return nil, err
+		}
+
+		if out, err = setPath(out, splitPath(s.Key), val); err != nil {
+			return nil, err
+		}
+	}
+
+	d, _ := out.(primitive.D)
+	return d, nil
+}
+
+func isInclusion(v interface{}) bool {
+	switch x := v.(type) {
+	case bool:
+		return x
+	case int, int32, int64, float64:
+		return toFloat(x) != 0
+	}
+	return false
+}
+
+func isExclusion(v interface{}) bool {
+	switch x := v.(type) {
+	case bool:
+		return !x
+	case int, int32, int64, float64:
+		return toFloat(x) == 0
+	}
+	return false
+}
+
+// removeMarker: the value of $$REMOVE, which drops a field when assigned to it
+type removeMarker struct{}
+
+// eval: evaluate an aggregation expression against a document
+func eval(expr interface{}, root primitive.D, vars map[string]interface{}) (interface{}, error) {
+	switch x := expr.(type) {
+	case string:
+		switch {
+		case strings.HasPrefix(x, "$$"):
+			parts := splitPath(x[2:])
+			var base interface{}
+			switch parts[0] {
+			case "ROOT", "CURRENT":
+				base = root
+			case "REMOVE":
+				return removeMarker{}, nil
+			default:
+				v, ok := vars[parts[0]]
+				if !ok {
+					return nil, fmt.Errorf("use of undefined variable: %s", parts[0])
+				}
+				base = v
+			}
+			v, _ := resolve(base, parts[1:])
+			return v, nil
+		case strings.HasPrefix(x, "$"):
+			v, _ := resolve(root, splitPath(x[1:]))
+			return v, nil
+		}
+		return x, nil
+	case primitive.A:
+		out := make(primitive.A, len(x))
+		for i, e := range x {
+			v, err := eval(e, root, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	case primitive.D:
+		if isOperatorDoc(x) {
+			return evalOperator(x[0].Key, x[0].Value, root, vars)
+		}
+
+		out := primitive.D{}
+		for _, e := range x {
+			v, err := eval(e.Value, root, vars)
+			if err != nil {
+				return nil, err
+			}
+			if _, rm := v.(removeMarker); rm {
+				continue
+			}
+			out = append(out, primitive.E{Key: e.Key, Value: v})
+		}
+		return out, nil
+	}
+
+	return expr, nil
+}
+
+func evalArgs(arg interface{}, root primitive.D, vars map[string]interface{}) (primitive.A, error) {
+	v, err := eval(arg, root, vars)
+	if err != nil {
+		return nil, err
+	}
+	if a, ok := v.(primitive.A); ok {
+		if _, literal := arg.(primitive.A); literal {
+			return a, nil
+		}
+	}
+	return primitive.A{v}, nil
+}
+
+func withVar(vars map[string]interface{}, name string, v interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(vars)+1)
+	for k, val := range vars {
+		out[k] = val
+	}
+	out[name] = v
+	return out
+}
+
+func evalOperator(op string, arg interface{}, root primitive.D, vars map[string]interface{}) (interface{}, error) {
+	// Operators which take named arguments
+	switch op {
+	case "$literal":
+		return arg, nil
+	case "$map", "$filter":
+		d, _ := arg.(primitive.D)
+		input, _ := getField(d, "input")
+		as, ok := getField(d, "as")
+		if !ok {
+			as = "this"
+		}
+		in, _ := getField(d, utilsTernary(op == "$map", "in", "cond"))
+
+		iv, err := eval(input, root, vars)
+		if err != nil {
+			return nil, err
+		}
+		arr, _ := iv.(primitive.A)
+		out := primitive.A{}
+		for _, el := range arr {
+			v, err := eval(in, root, withVar(vars, fmt.Sprint(as), el))
+			if err != nil {
+				return nil, err
+			}
+			if op == "$map" {
+				out = append(out, v)
+			} else if truthy(v) {
+				out = append(out, el)
+			}
+		}
+		return out, nil
+	case "$cond":
+		var cond, then, els interface{}
+		switch c := arg.(type) {
+		case primitive.D:
+			cond, _ = getField(c, "if")
+			then, _ = getField(c, "then")
+			els, _ = getField(c, "else")
+		case primitive.A:
+			if len(c) == 3 {
+				cond, then, els = c[0], c[1], c[2]
+			}
+		}
+		v, err := eval(cond, root, vars)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(v) {
+			return eval(then, root, vars)
+		}
+		return eval(els, root, vars)
+	}
+
+	args, err := evalArgs(arg, root, vars)
+	if err != nil {
+		return nil, err
+	}
+	argAt := func(i int) interface{} {
+		if i < len(args) {
+			return args[i]
+		}
+		return nil
+	}
+
+	switch op {
+	case "$eq":
+		return equal(argAt(0), argAt(1)), nil
+	case "$ne":
+		return !equal(argAt(0), argAt(1)), nil
+	case "$gt":
+		return compare(argAt(0), argAt(1)) > 0, nil
+	case "$gte":
+		return compare(argAt(0), argAt(1)) >= 0, nil
+	case "$lt":
+		return compare(argAt(0), argAt(1)) < 0, nil
+	case "$lte":
+		return compare(argAt(0), argAt(1)) <= 0, nil
+	case "$and":
+		for _, a := range args {
+			if !truthy(a) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case "$or":
+		for _, a := range args {
+			if truthy(a) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$not":
+		return !truthy(argAt(0)), nil
+	case "$in":
+		arr, _ := argAt(1).(primitive.A)
+		return containsValue(arr, argAt(0)), nil
+	case "$size":
+		arr, _ := argAt(0).(primitive.A)
+		return int32(len(arr)), nil
+	case "$concatArrays":
+		out := primitive.A{}
+		for _, a := range args {
+			if a == nil {
+				return nil, nil
+			}
+			arr, _ := a.(primitive.A)
+			out = append(out, arr...)
+		}
+		return out, nil
+	case "$arrayElemAt":
+		arr, _ := argAt(0).(primitive.A)
+		i := int(toFloat(argAt(1)))
+		if i < 0 {
+			i += len(arr)
+		}
+		if i < 0 || i >= len(arr) {
+			return removeMarker{}, nil
+		}
+		return arr[i], nil
+	case "$first", "$last":
+		arr, _ := argAt(0).(primitive.A)
+		if len(arr) == 0 {
+			return removeMarker{}, nil
+		}
+		if op == "$first" {
+			return arr[0], nil
+		}
+		return arr[len(arr)-1], nil
+	case "$indexOfArray":
+		arr, _ := argAt(0).(primitive.A)
+		for i, el := range arr {
+			if equal(el, argAt(1)) {
+				return int32(i), nil
+			}
+		}
+		return int32(-1), nil
+	case "$mergeObjects":
+		out := primitive.D{}
+		for _, a := range args {
+			d, _ := a.(primitive.D)
+			for _, e := range d {
+				v, _ := setPath(out, []string{e.Key}, e.Value)
+				out = v.(primitive.D)
+			}
+		}
+		return out, nil
+	case "$ifNull":
+		for _, a := range args {
+			if a != nil {
+				if _, rm := a.(removeMarker); !rm {
+					return a, nil
+				}
+			}
+		}
+		return nil, nil
+	case "$add", "$sum":
+		var total interface{} = int32(0)
+		for _, a := range args {
+			if arr, ok := a.(primitive.A); ok && op == "$sum" {
+				for _, el := range arr {
+					if typeOrder(el) == 2 {
+						total = addNumbers(total, el)
+					}
+				}
+			} else if typeOrder(a) == 2 {
+				total = addNumbers(total, a)
+			}
+		}
+		return total, nil
+	case "$subtract":
+		return toFloat(argAt(0)) - toFloat(argAt(1)), nil
+	case "$concat":
+		sb := strings.Builder{}
+		for _, a := range args {
+			s, ok := a.(string)
+			if !ok {
+				return nil, nil
+			}
+			sb.WriteString(s)
+		}
+		return sb.String(), nil
+	case "$toLower":
+		return strings.ToLower(fmt.Sprint(utilsOr(argAt(0), ""))), nil
+	case "$toString":
+		switch x := argAt(0).(type) {
+		case primitive.ObjectID:
+			return x.Hex(), nil
+		case nil:
+			return nil, nil
+		default:
+			return fmt.Sprint(x), nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported expression operator: %s", op)
+}
+
+func utilsTernary(cond bool, a, b string) string {
+	if cond {
+		return a
+	}
+	return b
+}
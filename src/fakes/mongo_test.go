@@ -0,0 +1,343 @@
+package fakes
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func newTestMongo(t *testing.T, docs ...interface{}) *mongo.Collection {
+	m, err := NewMongo(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Seed("items", docs...); err != nil {
+		t.Fatal(err)
+	}
+	return m.Collection("items")
+}
+
+// names: the names of the documents matched by a cursor, sorted unless ordered
+func names(t *testing.T, cur *mongo.Cursor, ordered bool) []string {
+	docs := []struct {
+		Name string `bson:"name"`
+	}{}
+	if err := cur.All(context.Background(), &docs); err != nil {
+		t.Fatal(err)
+	}
+
+	result := make([]string, len(docs))
+	for i, d := range docs {
+		result[i] = d.Name
+	}
+	if !ordered {
+		sort.Strings(result)
+	}
+	return result
+}
+
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+var testItems = []interface{}{
+	bson.M{"_id": 1, "name": "a", "n": 1, "flags": 1, "tags": bson.A{"x", "y"}, "sub": bson.M{"id": "s1"}},
+	bson.M{"_id": 2, "name": "b", "n": 2, "flags": 3, "tags": bson.A{"y"}, "sub": bson.M{"id": "s2"}},
+	bson.M{"_id": 3, "name": "c", "n": 3, "flags": 0, "tags": bson.A{}},
+	bson.M{"_id": 4, "name": "D", "n": 4.5, "flags": 2, "items": bson.A{bson.M{"k": 1, "v": "one"}, bson.M{"k": 2, "v": "two"}}},
+}
+
+func TestMongoFind(t *testing.T) {
+	col := newTestMongo(t, testItems...)
+
+	tests := []struct {
+		name   string
+		filter bson.M
+		want   []string
+	}{
+		{"everything", bson.M{}, []string{"D", "a", "b", "c"}},
+		{"equality", bson.M{"name": "a"}, []string{"a"}},
+		{"nested field", bson.M{"sub.id": "s2"}, []string{"b"}},
+		{"array element", bson.M{"tags": "y"}, []string{"a", "b"}},
+		{"array of documents", bson.M{"items.k": 2}, []string{"D"}},
+		{"elemMatch", bson.M{"items": bson.M{"$elemMatch": bson.M{"k": 1, "v": "one"}}}, []string{"D"}},
+		{"comparison across number types", bson.M{"n": bson.M{"$gt": 2, "$lte": 4.5}}, []string{"D", "c"}},
+		{"in", bson.M{"_id": bson.M{"$in": bson.A{1, 3, 9}}}, []string{"a", "c"}},
+		{"nin", bson.M{"_id": bson.M{"$nin": bson.A{1, 3}}}, []string{"D", "b"}},
+		{"ne", bson.M{"name": bson.M{"$ne": "a"}}, []string{"D", "b", "c"}},
+		{"exists", bson.M{"sub": bson.M{"$exists": false}}, []string{"D", "c"}},
+		{"size", bson.M{"tags": bson.M{"$size": 0}}, []string{"c"}},
+		{"all", bson.M{"tags": bson.M{"$all": bson.A{"x", "y"}}}, []string{"a"}},
+		{"and", bson.M{"$and": bson.A{bson.M{"n": bson.M{"$gte": 2}}, bson.M{"tags": "y"}}}, []string{"b"}},
+		{"or", bson.M{"$or": bson.A{bson.M{"name": "a"}, bson.M{"n": 3}}}, []string{"a", "c"}},
+		{"nor", bson.M{"$nor": bson.A{bson.M{"name": "a"}, bson.M{"n": 3}}}, []string{"D", "b"}},
+		{"not", bson.M{"n": bson.M{"$not": bson.M{"$gt": 1}}}, []string{"a"}},
+		{"regex", bson.M{"name": bson.M{"$regex": "^d", "$options": "i"}}, []string{"D"}},
+		{"bits all set", bson.M{"flags": bson.M{"$bitsAllSet": 3}}, []string{"b"}},
+		{"bits all clear", bson.M{"flags": bson.M{"$bitsAllClear": 1}}, []string{"D", "c"}},
+		{"bits any set", bson.M{"flags": bson.M{"$bitsAnySet": 2}}, []string{"D", "b"}},
+		{"expr", bson.M{"$expr": bson.M{"$gt": bson.A{"$n", "$flags"}}}, []string{"D", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cur, err := col.Find(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(t, cur, false); !sameNames(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMongoFindOptions(t *testing.T) {
+	col := newTestMongo(t, testItems...)
+
+	tests := []struct {
+		name string
+		opts *options.FindOptions
+		want []string
+	}{
+		{"sort", options.Find().SetSort(bson.D{{Key: "n", Value: -1}}), []string{"D", "c", "b", "a"}},
+		{"skip and limit", options.Find().SetSort(bson.D{{Key: "n", Value: 1}}).SetSkip(1).SetLimit(2), []string{"b", "c"}},
+		{"small batches", options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetBatchSize(1), []string{"a", "b", "c", "D"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cur, err := col.Find(context.Background(), bson.M{}, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(t, cur, true); !sameNames(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMongoUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		update bson.M
+		upsert bool
+		filter bson.M
+		// the expected fields, a field which should be gone, and whether the document was inserted
+		want     bson.M
+		absent   string
+		upserted bool
+	}{
+		{"set", bson.M{"$set": bson.M{"name": "z", "sub.id": "s9"}}, false, bson.M{"_id": 1}, bson.M{"name": "z", "sub": bson.M{"id": "s9"}}, "", false},
+		{"unset", bson.M{"$unset": bson.M{"sub": 1}}, false, bson.M{"_id": 1}, bson.M{"name": "a"}, "sub", false},
+		{"inc", bson.M{"$inc": bson.M{"n": 2}}, false, bson.M{"_id": 1}, bson.M{"n": int32(3)}, "", false},
+		{"push", bson.M{"$push": bson.M{"tags": "z"}}, false, bson.M{"_id": 1}, bson.M{"tags": bson.A{"x", "y", "z"}}, "", false},
+		{"addToSet existing", bson.M{"$addToSet": bson.M{"tags": "x"}}, false, bson.M{"_id": 1}, bson.M{"tags": bson.A{"x", "y"}}, "", false},
+		{"pull", bson.M{"$pull": bson.M{"tags": "x"}}, false, bson.M{"_id": 1}, bson.M{"tags": bson.A{"y"}}, "", false},
+		{"upsert", bson.M{"$set": bson.M{"name": "new"}, "$setOnInsert": bson.M{"n": 9}}, true, bson.M{"_id": 9}, bson.M{"name": "new", "n": int32(9)}, "", true},
+		{"positional", bson.M{"$set": bson.M{"items.$.v": "deux"}}, false, bson.M{"_id": 4, "items.k": 2}, bson.M{"items": bson.A{bson.M{"k": 1, "v": "one"}, bson.M{"k": 2, "v": "deux"}}}, "", false},
+		{"positional with elemMatch", bson.M{"$set": bson.M{"items.$.v": "un"}}, false, bson.M{"_id": 4, "items": bson.M{"$elemMatch": bson.M{"k": 1}}}, bson.M{"items": bson.A{bson.M{"k": 1, "v": "un"}, bson.M{"k": 2, "v": "two"}}}, "", false},
+		{"set on insert is ignored on update", bson.M{"$setOnInsert": bson.M{"n": 9}}, true, bson.M{"_id": 1}, bson.M{"n": int32(1)}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			col := newTestMongo(t, testItems...)
+
+			res, err := col.UpdateOne(context.Background(), tt.filter, tt.update, options.Update().SetUpsert(tt.upsert))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (res.UpsertedID != nil) != tt.upserted {
+				t.Errorf("expected upserted=%t, got %v", tt.upserted, res.UpsertedID)
+			}
+
+			got := bson.M{}
+			if err = col.FindOne(context.Background(), tt.filter).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.want {
+				if !reflect.DeepEqual(toBSONValue(t, got[k]), toBSONValue(t, v)) {
+					t.Errorf("%s: expected %v, got %v", k, v, got[k])
+				}
+			}
+			if _, ok := got[tt.absent]; ok && tt.absent != "" {
+				t.Errorf("expected %s to be unset", tt.absent)
+			}
+		})
+	}
+}
+
+// toBSONValue: round trip a value through bson, so that values compare regardless of their go types and field order
+func toBSONValue(t *testing.T, v interface{}) interface{} {
+	b, err := bson.Marshal(bson.M{"v": v})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := bson.M{}
+	if err = bson.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m["v"]
+}
+
+func TestMongoFindOneAndUpdate(t *testing.T) {
+	col := newTestMongo(t, testItems...)
+
+	tests := []struct {
+		name   string
+		after  bool
+		filter bson.M
+		want   string
+		err    error
+	}{
+		{"returns the document before", false, bson.M{"_id": 2}, "b", nil},
+		{"returns the document after", true, bson.M{"_id": 3}, "updated", nil},
+		{"no match", true, bson.M{"_id": 99}, "", mongo.ErrNoDocuments},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := struct {
+				Name string `bson:"name"`
+			}{}
+			opts := options.FindOneAndUpdate()
+			if tt.after {
+				opts.SetReturnDocument(options.After)
+			}
+
+			err := col.FindOneAndUpdate(context.Background(), tt.filter, bson.M{"$set": bson.M{"name": "updated"}}, opts).Decode(&doc)
+			if err != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if doc.Name != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, doc.Name)
+			}
+		})
+	}
+}
+
+func TestMongoDelete(t *testing.T) {
+	col := newTestMongo(t, testItems...)
+
+	res, err := col.DeleteMany(context.Background(), bson.M{"n": bson.M{"$lt": 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.DeletedCount != 2 {
+		t.Errorf("expected 2 deleted, got %d", res.DeletedCount)
+	}
+
+	n, err := col.CountDocuments(context.Background(), bson.M{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 left, got %d", n)
+	}
+}
+
+func TestMongoUniqueIndex(t *testing.T) {
+	col := newTestMongo(t, testItems...)
+
+	if _, err := col.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		doc  bson.M
+		dup  bool
+	}{
+		{"new value", bson.M{"_id": 10, "name": "e"}, false},
+		{"duplicate value", bson.M{"_id": 11, "name": "a"}, true},
+		{"duplicate id", bson.M{"_id": 1, "name": "f"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := col.InsertOne(context.Background(), tt.doc)
+			if mongo.IsDuplicateKeyError(err) != tt.dup {
+				t.Errorf("expected duplicate=%t, got %v", tt.dup, err)
+			}
+		})
+	}
+}
+
+func TestMongoAggregate(t *testing.T) {
+	m, err := NewMongo(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Seed("items", testItems...)
+	m.Seed("owners", bson.M{"_id": "o1", "item_id": 1, "name": "first"}, bson.M{"_id": "o2", "item_id": 1, "name": "second"})
+
+	tests := []struct {
+		name     string
+		pipeline bson.A
+		want     []string
+	}{
+		{
+			"match, sort and limit",
+			bson.A{bson.M{"$match": bson.M{"tags": "y"}}, bson.M{"$sort": bson.M{"n": -1}}, bson.M{"$limit": 1}},
+			[]string{"b"},
+		},
+		{
+			"lookup and unwind",
+			bson.A{
+				bson.M{"$lookup": bson.M{"from": "owners", "localField": "_id", "foreignField": "item_id", "as": "owners"}},
+				bson.M{"$unwind": "$owners"},
+				bson.M{"$sort": bson.M{"owners.name": 1}},
+				bson.M{"$project": bson.M{"name": "$owners.name"}},
+			},
+			[]string{"first", "second"},
+		},
+		{
+			"group",
+			bson.A{
+				bson.M{"$unwind": "$tags"},
+				bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.M{"_id": 1}},
+				bson.M{"$project": bson.M{"name": bson.M{"$concat": bson.A{"$_id", ":", bson.M{"$toString": "$count"}}}}},
+			},
+			[]string{"x:1", "y:2"},
+		},
+		{
+			"facet",
+			bson.A{
+				bson.M{"$facet": bson.M{"all": bson.A{bson.M{"$count": "n"}}}},
+				bson.M{"$project": bson.M{"name": bson.M{"$toString": bson.M{"$arrayElemAt": bson.A{"$all.n", 0}}}}},
+			},
+			[]string{"4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cur, err := m.Collection("items").Aggregate(context.Background(), tt.pipeline)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(t, cur, true); !sameNames(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
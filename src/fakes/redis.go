@@ -0,0 +1,1035 @@
+package fakes
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	commonredis "github.com/SevenTV/Common/redis"
+	"github.com/SevenTV/REST/src/instance"
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis: an in-memory redis instance
+//
+// The real client is used, connected to an in-process server speaking RESP over net.Pipe.
+// Strings, sets, sorted sets, hashes, expiry, transactions and pub/sub are supported
+type Redis struct {
+	srv *redisServer
+	cl  *redis.Client
+}
+
+var _ instance.Redis = (*Redis)(nil)
+
+// NewRedis: create an empty in-memory redis instance
+func NewRedis() *Redis {
+	srv := &redisServer{
+		data: map[string]*redisValue{},
+		subs: map[*redisConn]struct{}{},
+	}
+
+	return &Redis{
+		srv: srv,
+		cl: redis.NewClient(&redis.Options{
+			Addr: "fake-redis:6379",
+			Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				client, server := net.Pipe()
+				go srv.serve(server)
+				return client, nil
+			},
+		}),
+	}
+}
+
+func (r *Redis) Ping(ctx context.Context) error {
+	return r.cl.Ping(ctx).Err()
+}
+
+func (r *Redis) RawClient() *redis.Client {
+	return r.cl
+}
+
+func (r *Redis) ComposeKey(svc, name string) commonredis.Key {
+	return commonredis.Key(fmt.Sprintf("7tv-%s:%s", svc, name))
+}
+
+// Keys: list the live keys matching a glob pattern
+func (r *Redis) Keys(pattern string) []string {
+	r.srv.mx.Lock()
+	defer r.srv.mx.Unlock()
+
+	return r.srv.keys(pattern)
+}
+
+// Close: close the client
+func (r *Redis) Close() error {
+	return r.cl.Close()
+}
+
+type redisKind int
+
+const (
+	redisString redisKind = iota
+	redisSet
+	redisZSet
+	redisHash
+)
+
+type redisValue struct {
+	kind    redisKind
+	str     string
+	members map[string]float64 // set and sorted set members, with scores for the latter
+	hash    map[string]string
+	expires time.Time
+}
+
+type redisServer struct {
+	mx   sync.Mutex
+	data map[string]*redisValue
+	subs map[*redisConn]struct{}
+}
+
+type redisConn struct {
+	out      chan []byte
+	channels map[string]struct{}
+	patterns map[string]*regexp.Regexp
+	queue    [][]string
+	multi    bool
+}
+
+func (c *redisConn) subscriptions() int {
+	return len(c.channels) + len(c.patterns)
+}
+
+// serve: read commands from a connection and reply to each of them
+func (s *redisServer) serve(conn net.Conn) {
+	c := &redisConn{
+		out:      make(chan []byte, 1024),
+		channels: map[string]struct{}{},
+		patterns: map[string]*regexp.Regexp{},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for b := range c.out {
+			if _, err := conn.Write(b); err != nil {
+				return
+			}
+		}
+	}()
+
+	defer func() {
+		s.mx.Lock()
+		delete(s.subs, c)
+		s.mx.Unlock()
+
+		close(c.out)
+		_ = conn.Close()
+		<-done
+	}()
+
+	rd := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(rd)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		c.out <- s.handle(c, args)
+	}
+}
+
+func readLine(rd *bufio.Reader) (string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readCommand: read a command, sent as an array of bulk strings
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := readLine(rd)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil // inline command
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if line, err = readLine(rd); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimPrefix(line, "$"))
+		if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+
+	return args, nil
+}
+
+// RESP encoding
+
+type respSimple string
+
+type respError string
+
+func respErr(format string, a ...interface{}) respError {
+	return respError(fmt.Sprintf(format, a...))
+}
+
+var errWrongType = respError("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+func encode(v interface{}) []byte {
+	switch x := v.(type) {
+	case nil:
+		return []byte("$-1\r\n")
+	case respSimple:
+		return []byte("+" + string(x) + "\r\n")
+	case respError:
+		return []byte("-" + string(x) + "\r\n")
+	case int:
+		return []byte(":" + strconv.Itoa(x) + "\r\n")
+	case int64:
+		return []byte(":" + strconv.FormatInt(x, 10) + "\r\n")
+	case string:
+		return []byte("$" + strconv.Itoa(len(x)) + "\r\n" + x + "\r\n")
+	case []string:
+		b := []byte("*" + strconv.Itoa(len(x)) + "\r\n")
+		for _, s := range x {
+			b = append(b, encode(s)...)
+		}
+		return b
+	case []interface{}:
+		if x == nil {
+			return []byte("*-1\r\n")
+		}
+		b := []byte("*" + strconv.Itoa(len(x)) + "\r\n")
+		for _, e := range x {
+			b = append(b, encode(e)...)
+		}
+		return b
+	}
+
+	return encode(fmt.Sprint(v))
+}
+
+// globRegex: convert a redis glob pattern to a regular expression
+func globRegex(pattern string) *regexp.Regexp {
+	sb := strings.Builder{}
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "^") {
+				class = "^" + regexp.QuoteMeta(class[1:])
+			} else {
+				class = regexp.QuoteMeta(class)
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\-`, "-") + "]")
+			i += end
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	sb.WriteString("$")
+
+	return regexp.MustCompile(sb.String())
+}
+
+// get: get a live value. The lock must be held
+func (s *redisServer) get(key string) *redisValue {
+	v, ok := s.data[key]
+	if !ok {
+		return nil
+	}
+	if !v.expires.IsZero() && !time.Now().Before(v.expires) {
+		delete(s.data, key)
+		return nil
+	}
+	return v
+}
+
+// getKind: get a live value of a kind, creating it if requested and it does not exist
+func (s *redisServer) getKind(key string, kind redisKind, create bool) (*redisValue, respError) {
+	v := s.get(key)
+	if v == nil {
+		if !create {
+			return nil, ""
+		}
+		v = &redisValue{kind: kind, members: map[string]float64{}, hash: map[string]string{}}
+		s.data[key] = v
+	}
+	if v.kind != kind {
+		return nil, errWrongType
+	}
+	return v, ""
+}
+
+func (s *redisServer) keys(pattern string) []string {
+	re := globRegex(pattern)
+	out := []string{}
+	for k := range s.data {
+		if s.get(k) != nil && re.MatchString(k) {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// handle: process a command from a connection and produce its encoded reply
+func (s *redisServer) handle(c *redisConn, args []string) []byte {
+	name := strings.ToUpper(args[0])
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	switch name {
+	case "MULTI":
+		if c.multi {
+			return encode(respError("ERR MULTI calls can not be nested"))
+		}
+		c.multi = true
+		c.queue = nil
+		return encode(respSimple("OK"))
+	case "EXEC":
+		if !c.multi {
+			return encode(respError("ERR EXEC without MULTI"))
+		}
+		c.multi = false
+		results := make([]interface{}, len(c.queue))
+		for i, q := range c.queue {
+			results[i] = s.exec(c, strings.ToUpper(q[0]), q[1:])
+		}
+		c.queue = nil
+		return encode(results)
+	case "DISCARD":
+		if !c.multi {
+			return encode(respError("ERR DISCARD without MULTI"))
+		}
+		c.multi = false
+		c.queue = nil
+		return encode(respSimple("OK"))
+	}
+
+	if c.multi {
+		c.queue = append(c.queue, args)
+		return encode(respSimple("QUEUED"))
+	}
+
+	return encode(s.exec(c, name, args[1:]))
+}
+
+func argInt(s string) (int64, respError) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, respError("ERR value is not an integer or out of range")
+	}
+	return n, ""
+}
+
+func parseScore(s string) (float64, bool, respError) {
+	exclusive := strings.HasPrefix(s, "(")
+	s = strings.TrimPrefix(s, "(")
+	switch strings.ToLower(s) {
+	case "-inf":
+		return math.Inf(-1), exclusive, ""
+	case "+inf", "inf":
+		return math.Inf(1), exclusive, ""
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false, respError("ERR min or max is not a float")
+	}
+	return f, exclusive, ""
+}
+
+func formatScore(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func sortedMembers(v *redisValue) []string {
+	out := make([]string, 0, len(v.members))
+	for m := range v.members {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := v.members[out[i]], v.members[out[j]]
+		if a != b {
+			return a < b
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+func rangeIndexes(start, stop int64, n int) (int, int) {
+	if start < 0 {
+		start += int64(n)
+	}
+	if stop < 0 {
+		stop += int64(n)
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= int64(n) {
+		stop = int64(n) - 1
+	}
+	return int(start), int(stop)
+}
+
+// exec: run a command. The lock must be held
+func (s *redisServer) exec(c *redisConn, name string, args []string) interface{} {
+	arity := func(n int) bool { return len(args) >= n }
+	wrongArgs := respErr("ERR wrong number of arguments for '%s' command", strings.ToLower(name))
+
+	switch name {
+	case "PING":
+		if c.subscriptions() > 0 {
+			msg := ""
+			if len(args) > 0 {
+				msg = args[0]
+			}
+			return []interface{}{"pong", msg}
+		}
+		if len(args) > 0 {
+			return args[0]
+		}
+		return respSimple("PONG")
+	case "SELECT", "AUTH", "CLIENT", "READONLY":
+		return respSimple("OK")
+	case "FLUSHDB", "FLUSHALL":
+		s.data = map[string]*redisValue{}
+		return respSimple("OK")
+	case "GET":
+		if !arity(1) {
+			return wrongArgs
+		}
+		v, err := s.getKind(args[0], redisString, false)
+		if err != "" {
+			return err
+		}
+		if v == nil {
+			return nil
+		}
+		return v.str
+	case "MGET":
+		out := make([]interface{}, len(args))
+		for i, k := range args {
+			if v, _ := s.getKind(k, redisString, false); v != nil {
+				out[i] = v.str
+			}
+		}
+		return out
+	case "SET", "SETNX", "SETEX":
+		if !arity(2) {
+			return wrongArgs
+		}
+
+		key, val := args[0], args[1]
+		var (
+			ttl             time.Duration
+			nx, xx, keepTTL bool
+		)
+		switch name {
+		case "SETNX":
+			nx = true
+		case "SETEX":
+			if !arity(3) {
+				return wrongArgs
+			}
+			n, err := argInt(args[1])
+			if err != "" {
+				return err
+			}
+			ttl, val = time.Duration(n)*time.Second, args[2]
+		}
+		for i := 2; name == "SET" && i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				nx = true
+			case "XX":
+				xx = true
+			case "KEEPTTL":
+				keepTTL = true
+			case "EX", "PX":
+				if i+1 >= len(args) {
+					return respError("ERR syntax error")
+				}
+				n, err := argInt(args[i+1])
+				if err != "" {
+					return err
+				}
+				unit := time.Second
+				if strings.ToUpper(args[i]) == "PX" {
+					unit = time.Millisecond
+				}
+				ttl = time.Duration(n) * unit
+				i++
+			default:
+				return respError("ERR syntax error")
+			}
+		}
+
+		existing := s.get(key)
+		if (nx && existing != nil) || (xx && existing == nil) {
+			if name == "SETNX" {
+				return 0
+			}
+			return nil
+		}
+
+		v := &redisValue{kind: redisString, str: val}
+		if ttl > 0 {
+			v.expires = time.Now().Add(ttl)
+		} else if keepTTL && existing != nil {
+			v.expires = existing.expires
+		}
+		s.data[key] = v
+		if name == "SETNX" {
+			return 1
+		}
+		return respSimple("OK")
+	case "GETDEL":
+		if !arity(1) {
+			return wrongArgs
+		}
+		v, err := s.getKind(args[0], redisString, false)
+		if err != "" {
+			return err
+		}
+		if v == nil {
+			return nil
+		}
+		delete(s.data, args[0])
+		return v.str
+	case "INCR", "DECR", "INCRBY", "DECRBY":
+		if !arity(1) {
+			return wrongArgs
+		}
+		by := int64(1)
+		if strings.HasSuffix(name, "BY") {
+			if !arity(2) {
+				return wrongArgs
+			}
+			n, err := argInt(args[1])
+			if err != "" {
+				return err
+			}
+			by = n
+		}
+		if strings.HasPrefix(name, "DECR") {
+			by = -by
+		}
+
+		v, err := s.getKind(args[0], redisString, true)
+		if err != "" {
+			return err
+		}
+		cur := int64(0)
+		if v.str != "" {
+			if cur, err = argInt(v.str); err != "" {
+				return err
+			}
+		}
+		cur += by
+		v.str = strconv.FormatInt(cur, 10)
+		return cur
+	case "DEL", "UNLINK":
+		n := 0
+		for _, k := range args {
+			if s.get(k) != nil {
+				delete(s.data, k)
+				n++
+			}
+		}
+		return n
+	case "EXISTS":
+		n := 0
+		for _, k := range args {
+			if s.get(k) != nil {
+				n++
+			}
+		}
+		return n
+	case "TYPE":
+		if !arity(1) {
+			return wrongArgs
+		}
+		v := s.get(args[0])
+		if v == nil {
+			return respSimple("none")
+		}
+		return respSimple([]string{"string", "set", "zset", "hash"}[v.kind])
+	case "EXPIRE", "PEXPIRE":
+		if !arity(2) {
+			return wrongArgs
+		}
+		n, err := argInt(args[1])
+		if err != "" {
+			return err
+		}
+		v := s.get(args[0])
+		if v == nil {
+			return 0
+		}
+		unit := time.Second
+		if name == "PEXPIRE" {
+			unit = time.Millisecond
+		}
+		v.expires = time.Now().Add(time.Duration(n) * unit)
+		return 1
+	case "PERSIST":
+		if !arity(1) {
+			return wrongArgs
+		}
+		v := s.get(args[0])
+		if v == nil || v.expires.IsZero() {
+			return 0
+		}
+		v.expires = time.Time{}
+		return 1
+	case "TTL", "PTTL":
+		if !arity(1) {
+			return wrongArgs
+		}
+		v := s.get(args[0])
+		switch {
+		case v == nil:
+			return -2
+		case v.expires.IsZero():
+			return -1
+		}
+		d := time.Until(v.expires)
+		if name == "PTTL" {
+			return d.Milliseconds()
+		}
+		return int64((d + time.Second - 1) / time.Second)
+	case "KEYS":
+		if !arity(1) {
+			return wrongArgs
+		}
+		return s.keys(args[0])
+	case "SCAN":
+		// All matches are returned in a single iteration
+		pattern := "*"
+		for i := 1; i+1 < len(args); i += 2 {
+			if strings.ToUpper(args[i]) == "MATCH" {
+				pattern = args[i+1]
+			}
+		}
+		return []interface{}{"0", s.keys(pattern)}
+	case "SADD", "SREM":
+		if !arity(2) {
+			return wrongArgs
+		}
+		v, err := s.getKind(args[0], redisSet, name == "SADD")
+		if err != "" {
+			return err
+		}
+		if v == nil {
+			return 0
+		}
+		n := 0
+		for _, m := range args[1:] {
+			_, ok := v.members[m]
+			if name == "SADD" && !ok {
+				v.members[m] = 0
+				n++
+			} else if name == "SREM" && ok {
+				delete(v.members, m)
+				n++
+			}
+		}
+		if len(v.members) == 0 {
+			delete(s.data, args[0])
+		}
+		return n
+	case "SMEMBERS":
+		if !arity(1) {
+			return wrongArgs
+		}
+		v, err := s.getKind(args[0], redisSet, false)
+		if err != "" {
+			return err
+		}
+		out := []string{}
+		if v != nil {
+			out = sortedMembers(v)
+		}
+		return out
+	case "SISMEMBER":
+		if !arity(2) {
+			return wrongArgs
+		}
+		v, err := s.getKind(args[0], redisSet, false)
+		if err != "" {
+			return err
+		}
+		if v == nil {
+			return 0
+		}
+		if _, ok := v.members[args[1]]; ok {
+			return 1
+		}
+		return 0
+	case "SCARD", "ZCARD":
+		if !arity(1) {
+			return wrongArgs
+		}
+		kind := redisSet
+		if name == "ZCARD" {
+			kind = redisZSet
+		}
+		v, err := s.getKind(args[0], kind, false)
+		if err != "" {
+			return err
+		}
+		if v == nil {
+			return 0
+		}
+		return len(v.members)
+	case "ZADD":
+		if !arity(3) || len(args)%2 == 0 {
+			return wrongArgs
+		}
+		v, err := s.getKind(args[0], redisZSet, true)
+		if err != "" {
+			return err
+		}
+		n := 0
+		for i := 1; i+1 < len(args); i += 2 {
+			score, _, err := parseScore(args[i])
+			if err != "" {
+				return respError("ERR value is not a valid float")
+			}
+			if _, ok := v.members[args[i+1]]; !ok {
+				n++
+			}
+			v.members[args[i+1]] = score
+		}
+		return n
+	case "ZREM":
+		if !arity(2) {
+			return wrongArgs
+		}
+		v, err := s.getKind(args[0], redisZSet, false)
+		if err != "" {
+			return err
+		}
+		if v == nil {
+			return 0
+		}
+		n := 0
+		for _, m := range args[1:] {
+			if _, ok := v.members[m]; ok {
+				delete(v.members, m)
+				n++
+			}
+		}
+		return n
+	case "ZSCORE":
+		if !arity(2) {
+			return wrongArgs
+		}
+		v, err := s.getKind(args[0], redisZSet, false)
+		if err != "" {
+			return err
+		}
+		if v == nil {
+			return nil
+		}
+		score, ok := v.members[args[1]]
+		if !ok {
+			return nil
+		}
+		return formatScore(score)
+	case "ZRANGE", "ZREVRANGE":
+		if !arity(3) {
+			return wrongArgs
+		}
+		start, err := argInt(args[1])
+		if err != "" {
+			return err
+		}
+		stop, err := argInt(args[2])
+		if err != "" {
+			return err
+		}
+		withScores := len(args) > 3 && strings.ToUpper(args[3]) == "WITHSCORES"
+
+		v, err := s.getKind(args[0], redisZSet, false)
+		if err != "" {
+			return err
+		}
+		out := []string{}
+		if v == nil {
+			return out
+		}
+		members := sortedMembers(v)
+		if name == "ZREVRANGE" {
+			for i, j := 0, len(members)-1; i < j; i, j = i+1, j-1 {
+				members[i], members[j] = members[j], members[i]
+			}
+		}
+		from, to := rangeIndexes(start, stop, len(members))
+		for i := from; i <= to; i++ {
+			out = append(out, members[i])
+			if withScores {
+				out = append(out, formatScore(v.members[members[i]]))
+			}
+		}
+		return out
+	case "ZRANGEBYSCORE", "ZREMRANGEBYSCORE", "ZCOUNT":
+		if !arity(3) {
+			return wrongArgs
+		}
+		min, minEx, err := parseScore(args[1])
+		if err != "" {
+			return err
+		}
+		max, maxEx, err := parseScore(args[2])
+		if err != "" {
+			return err
+		}
+
+		v, err := s.getKind(args[0], redisZSet, false)
+		if err != "" {
+			return err
+		}
+		matched := []string{}
+		if v != nil {
+			for _, m := range sortedMembers(v) {
+				sc := v.members[m]
+				if sc < min || (minEx && sc == min) || sc > max || (maxEx && sc == max) {
+					continue
+				}
+				matched = append(matched, m)
+			}
+		}
+
+		switch name {
+		case "ZREMRANGEBYSCORE":
+			for _, m := range matched {
+				delete(v.members, m)
+			}
+			return len(matched)
+		case "ZCOUNT":
+			return len(matched)
+		}
+		return matched
+	case "HSET", "HSETNX":
+		if !arity(3) || len(args)%2 == 0 {
+			return wrongArgs
+		}
+		v, err := s.getKind(args[0], redisHash, true)
+		if err != "" {
+			return err
+		}
+		n := 0
+		for i := 1; i+1 < len(args); i += 2 {
+			_, ok := v.hash[args[i]]
+			if ok && name == "HSETNX" {
+				continue
+			}
+			if !ok {
+				n++
+			}
+			v.hash[args[i]] = args[i+1]
+		}
+		return n
+	case "HGET":
+		if !arity(2) {
+			return wrongArgs
+		}
+		v, err := s.getKind(args[0], redisHash, false)
+		if err != "" {
+			return err
+		}
+		if v == nil {
+			return nil
+		}
+		if val, ok := v.hash[args[1]]; ok {
+			return val
+		}
+		return nil
+	case "HDEL":
+		if !arity(2) {
+			return wrongArgs
+		}
+		v, err := s.getKind(args[0], redisHash, false)
+		if err != "" {
+			return err
+		}
+		if v == nil {
+			return 0
+		}
+		n := 0
+		for _, f := range args[1:] {
+			if _, ok := v.hash[f]; ok {
+				delete(v.hash, f)
+				n++
+			}
+		}
+		return n
+	case "HGETALL":
+		if !arity(1) {
+			return wrongArgs
+		}
+		v, err := s.getKind(args[0], redisHash, false)
+		if err != "" {
+			return err
+		}
+		out := []string{}
+		if v != nil {
+			fields := make([]string, 0, len(v.hash))
+			for f := range v.hash {
+				fields = append(fields, f)
+			}
+			sort.Strings(fields)
+			for _, f := range fields {
+				out = append(out, f, v.hash[f])
+			}
+		}
+		return out
+	case "HINCRBY":
+		if !arity(3) {
+			return wrongArgs
+		}
+		by, err := argInt(args[2])
+		if err != "" {
+			return err
+		}
+		v, err := s.getKind(args[0], redisHash, true)
+		if err != "" {
+			return err
+		}
+		cur := int64(0)
+		if str, ok := v.hash[args[1]]; ok {
+			if cur, err = argInt(str); err != "" {
+				return err
+			}
+		}
+		cur += by
+		v.hash[args[1]] = strconv.FormatInt(cur, 10)
+		return cur
+	case "PUBLISH":
+		if !arity(2) {
+			return wrongArgs
+		}
+		n := 0
+		for sub := range s.subs {
+			if _, ok := sub.channels[args[0]]; ok {
+				sub.out <- encode([]interface{}{"message", args[0], args[1]})
+				n++
+			}
+			for p, re := range sub.patterns {
+				if re.MatchString(args[0]) {
+					sub.out <- encode([]interface{}{"pmessage", p, args[0], args[1]})
+					n++
+				}
+			}
+		}
+		return n
+	case "SUBSCRIBE", "PSUBSCRIBE", "UNSUBSCRIBE", "PUNSUBSCRIBE":
+		return s.subscribe(c, name, args)
+	}
+
+	return respErr("ERR unknown command '%s'", strings.ToLower(name))
+}
+
+// subscribe: change the subscriptions of a connection
+//
+// Each affected channel gets its own confirmation, so all but the last are written to the connection directly
+func (s *redisServer) subscribe(c *redisConn, name string, args []string) interface{} {
+	kind := strings.ToLower(name)
+	pattern := strings.HasPrefix(name, "P")
+	subscribing := !strings.Contains(name, "UNSUB")
+
+	if !subscribing && len(args) == 0 {
+		if pattern {
+			for p := range c.patterns {
+				args = append(args, p)
+			}
+		} else {
+			for ch := range c.channels {
+				args = append(args, ch)
+			}
+		}
+		sort.Strings(args)
+	}
+
+	replies := []interface{}{}
+	for _, a := range args {
+		switch {
+		case subscribing && pattern:
+			c.patterns[a] = globRegex(a)
+		case subscribing:
+			c.channels[a] = struct{}{}
+		case pattern:
+			delete(c.patterns, a)
+		default:
+			delete(c.channels, a)
+		}
+		replies = append(replies, []interface{}{kind, a, c.subscriptions()})
+	}
+	if len(replies) == 0 {
+		replies = append(replies, []interface{}{kind, nil, c.subscriptions()})
+	}
+
+	if c.subscriptions() > 0 {
+		s.subs[c] = struct{}{}
+	} else {
+		delete(s.subs, c)
+	}
+
+	for _, r := range replies[:len(replies)-1] {
+		c.out <- encode(r)
+	}
+	return replies[len(replies)-1]
+}
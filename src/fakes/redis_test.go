@@ -0,0 +1,157 @@
+package fakes
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestRedisCommands(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// commands run in order; the reply of the last is compared against want
+		cmds [][]interface{}
+		want string
+	}{
+		{"get missing", [][]interface{}{{"get", "k"}}, "redis: nil"},
+		{"set and get", [][]interface{}{{"set", "k", "v"}, {"get", "k"}}, "v"},
+		{"set nx on existing key", [][]interface{}{{"set", "k", "v"}, {"set", "k", "w", "nx"}, {"get", "k"}}, "v"},
+		{"getdel", [][]interface{}{{"set", "k", "v"}, {"getdel", "k"}, {"exists", "k"}}, "0"},
+		{"incrby", [][]interface{}{{"incr", "n"}, {"incrby", "n", "4"}}, "5"},
+		{"incr on a string", [][]interface{}{{"set", "k", "v"}, {"incr", "k"}}, "ERR value is not an integer or out of range"},
+		{"del counts removed keys", [][]interface{}{{"set", "a", "1"}, {"set", "b", "1"}, {"del", "a", "b", "c"}}, "2"},
+		{"type", [][]interface{}{{"sadd", "s", "a"}, {"type", "s"}}, "set"},
+		{"wrong type", [][]interface{}{{"sadd", "s", "a"}, {"get", "s"}}, "WRONGTYPE Operation against a key holding the wrong kind of value"},
+		{"sets", [][]interface{}{{"sadd", "s", "b", "a", "a"}, {"srem", "s", "c"}, {"smembers", "s"}}, "[a b]"},
+		{"sismember", [][]interface{}{{"sadd", "s", "a"}, {"sismember", "s", "a"}}, "1"},
+		{"sorted sets", [][]interface{}{{"zadd", "z", "2", "b", "1", "a", "3", "c"}, {"zrange", "z", "0", "-1"}}, "[a b c]"},
+		{"zrangebyscore", [][]interface{}{{"zadd", "z", "1", "a", "2", "b", "3", "c"}, {"zrangebyscore", "z", "(1", "+inf"}}, "[b c]"},
+		{"zremrangebyscore", [][]interface{}{{"zadd", "z", "1", "a", "2", "b"}, {"zremrangebyscore", "z", "-inf", "1"}, {"zcard", "z"}}, "1"},
+		{"zcount", [][]interface{}{{"zadd", "z", "1", "a", "2", "b", "3", "c"}, {"zcount", "z", "2", "3"}}, "2"},
+		{"hashes", [][]interface{}{{"hset", "h", "f", "1"}, {"hincrby", "h", "f", "2"}, {"hget", "h", "f"}}, "3"},
+		{"hgetall", [][]interface{}{{"hset", "h", "a", "1", "b", "2"}, {"hdel", "h", "a"}, {"hgetall", "h"}}, "[b 2]"},
+		{"keys", [][]interface{}{{"set", "a:1", "v"}, {"set", "a:2", "v"}, {"set", "b:1", "v"}, {"keys", "a:*"}}, "[a:1 a:2]"},
+		{"ttl without expiry", [][]interface{}{{"set", "k", "v"}, {"ttl", "k"}}, "-1"},
+		{"ttl of a missing key", [][]interface{}{{"ttl", "k"}}, "-2"},
+		{"persist", [][]interface{}{{"set", "k", "v", "ex", "10"}, {"persist", "k"}, {"ttl", "k"}}, "-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRedis()
+			defer r.Close()
+
+			var cmd *redis.Cmd
+			for _, args := range tt.cmds {
+				cmd = r.RawClient().Do(ctx, args...)
+			}
+
+			got := ""
+			if v, err := cmd.Result(); err != nil {
+				got = err.Error()
+			} else {
+				got = fmt.Sprint(v)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRedisExpiry(t *testing.T) {
+	ctx := context.Background()
+	r := NewRedis()
+	defer r.Close()
+	cl := r.RawClient()
+
+	cl.Set(ctx, "short", "v", time.Millisecond*50)
+	cl.Set(ctx, "long", "v", time.Minute)
+	cl.SAdd(ctx, "set", "a")
+	cl.PExpire(ctx, "set", time.Millisecond*50)
+
+	if ttl := cl.TTL(ctx, "long").Val(); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("expected a ttl of up to a minute, got %s", ttl)
+	}
+
+	time.Sleep(time.Millisecond * 100)
+	for _, k := range []string{"short", "set"} {
+		if n := cl.Exists(ctx, k).Val(); n != 0 {
+			t.Errorf("expected %s to have expired", k)
+		}
+	}
+	if keys := r.Keys("*"); len(keys) != 1 || keys[0] != "long" {
+		t.Errorf("expected only the long lived key to remain, got %v", keys)
+	}
+}
+
+func TestRedisTransaction(t *testing.T) {
+	ctx := context.Background()
+	r := NewRedis()
+	defer r.Close()
+
+	pipe := r.RawClient().TxPipeline()
+	incr := pipe.Incr(ctx, "n")
+	pipe.Expire(ctx, "n", time.Minute)
+	get := pipe.Get(ctx, "n")
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if incr.Val() != 1 || get.Val() != "1" {
+		t.Errorf("expected the queued commands to run in order, got %d and %q", incr.Val(), get.Val())
+	}
+}
+
+func TestRedisPubSub(t *testing.T) {
+	ctx := context.Background()
+	r := NewRedis()
+	defer r.Close()
+	cl := r.RawClient()
+
+	sub := cl.PSubscribe(ctx, "events:*")
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		channel   string
+		receivers int64
+	}{
+		{"events:a", 1},
+		{"other:a", 0},
+		{"events:b", 1},
+	}
+
+	want := []string{}
+	for _, tt := range tests {
+		n, err := cl.Publish(ctx, tt.channel, "payload").Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != tt.receivers {
+			t.Errorf("%s: expected %d receivers, got %d", tt.channel, tt.receivers, n)
+		}
+		if n > 0 {
+			want = append(want, tt.channel)
+		}
+	}
+
+	ch := sub.Channel()
+	for _, channel := range want {
+		select {
+		case msg := <-ch:
+			if msg.Channel != channel || msg.Pattern != "events:*" || msg.Payload != "payload" {
+				t.Errorf("expected a message on %s, got %+v", channel, msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no message received on %s", channel)
+		}
+	}
+}
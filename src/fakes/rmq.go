@@ -0,0 +1,168 @@
+package fakes
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SevenTV/REST/src/instance"
+	"github.com/streadway/amqp"
+)
+
+// Rmq: an in-memory message queue
+//
+// Published messages are recorded and delivered to subscribers of the queue, if any
+type Rmq struct {
+	mx        sync.Mutex
+	queues    map[string]chan amqp.Delivery
+	published map[string][]amqp.Publishing
+	acks      []Ack
+	tag       uint64
+	closed    bool
+}
+
+var _ instance.Rmq = (*Rmq)(nil)
+
+// Ack: the outcome of a delivery, as reported by its consumer
+type Ack struct {
+	Queue       string
+	DeliveryTag uint64
+	Ack         bool
+	Requeue     bool
+}
+
+// NewRmq: create an in-memory message queue
+func NewRmq() *Rmq {
+	return &Rmq{
+		queues:    map[string]chan amqp.Delivery{},
+		published: map[string][]amqp.Publishing{},
+	}
+}
+
+// queue: get the channel of a queue. The lock must be held
+func (r *Rmq) queue(name string) chan amqp.Delivery {
+	ch, ok := r.queues[name]
+	if !ok {
+		ch = make(chan amqp.Delivery, 1024)
+		r.queues[name] = ch
+	}
+	return ch
+}
+
+func (r *Rmq) Subscribe(queue string) (<-chan amqp.Delivery, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if r.closed {
+		return nil, amqp.ErrClosed
+	}
+	return r.queue(queue), nil
+}
+
+func (r *Rmq) Publish(queue string, contentType string, deliveryMode uint8, headers amqp.Table, msg []byte) error {
+	r.mx.Lock()
+	r.published[queue] = append(r.published[queue], amqp.Publishing{
+		Headers:      headers,
+		ContentType:  contentType,
+		DeliveryMode: deliveryMode,
+		Timestamp:    time.Now(),
+		Body:         msg,
+	})
+	r.mx.Unlock()
+
+	return r.Deliver(queue, headers, msg)
+}
+
+// Deliver: deliver a message to the subscribers of a queue without recording it as published
+func (r *Rmq) Deliver(queue string, headers amqp.Table, body []byte) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if r.closed {
+		return amqp.ErrClosed
+	}
+	r.tag++
+	select {
+	case r.queue(queue) <- amqp.Delivery{
+		Acknowledger: &rmqAcknowledger{r, queue},
+		Headers:      headers,
+		DeliveryTag:  r.tag,
+		Timestamp:    time.Now(),
+		RoutingKey:   queue,
+		Body:         body,
+	}:
+	default:
+		return fmt.Errorf("queue %s is full", queue)
+	}
+
+	return nil
+}
+
+// QueueDepth: get the amount of messages delivered to a queue which are yet to be received by a subscriber
+func (r *Rmq) QueueDepth(queue string) (int, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if r.closed {
+		return 0, amqp.ErrClosed
+	}
+	return len(r.queue(queue)), nil
+}
+
+// Shutdown: close the queues, ending the deliveries to their subscribers. Unacknowledged messages can no longer be acknowledged
+func (r *Rmq) Shutdown() {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if r.closed {
+		return
+	}
+	r.closed = true
+	for _, ch := range r.queues {
+		close(ch)
+	}
+}
+
+// Published: get the messages published to a queue
+func (r *Rmq) Published(queue string) []amqp.Publishing {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	return append([]amqp.Publishing{}, r.published[queue]...)
+}
+
+// Acks: get the acknowledgements made by consumers
+func (r *Rmq) Acks() []Ack {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	return append([]Ack{}, r.acks...)
+}
+
+type rmqAcknowledger struct {
+	r     *Rmq
+	queue string
+}
+
+func (a *rmqAcknowledger) record(tag uint64, ack, requeue bool) error {
+	a.r.mx.Lock()
+	defer a.r.mx.Unlock()
+
+	if a.r.closed {
+		return amqp.ErrClosed
+	}
+	a.r.acks = append(a.r.acks, Ack{a.queue, tag, ack, requeue})
+	return nil
+}
+
+func (a *rmqAcknowledger) Ack(tag uint64, multiple bool) error {
+	return a.record(tag, true, false)
+}
+
+func (a *rmqAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	return a.record(tag, false, requeue)
+}
+
+func (a *rmqAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.record(tag, false, requeue)
+}
@@ -0,0 +1,76 @@
+package fakes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+func TestRmq(t *testing.T) {
+	r := NewRmq()
+
+	ch, err := r.Subscribe("jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Publish("jobs", "application/json", amqp.Persistent, amqp.Table{"k": "v"}, []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Deliver("jobs", nil, []byte("two")); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, _ := r.QueueDepth("jobs"); n != 2 {
+		t.Errorf("expected 2 messages waiting, got %d", n)
+	}
+	if pub := r.Published("jobs"); len(pub) != 1 || string(pub[0].Body) != "one" || pub[0].Headers["k"] != "v" {
+		t.Errorf("expected only the published message to be recorded, got %+v", pub)
+	}
+
+	// Each delivery reports its outcome
+	outcomes := []struct {
+		body    string
+		settle  func(d amqp.Delivery) error
+		ack     bool
+		requeue bool
+	}{
+		{"one", func(d amqp.Delivery) error { return d.Ack(false) }, true, false},
+		{"two", func(d amqp.Delivery) error { return d.Nack(false, true) }, false, true},
+	}
+	for _, o := range outcomes {
+		select {
+		case d := <-ch:
+			if string(d.Body) != o.body {
+				t.Fatalf("expected %s, got %s", o.body, d.Body)
+			}
+			if err = o.settle(d); err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s was not delivered", o.body)
+		}
+	}
+
+	acks := r.Acks()
+	if len(acks) != len(outcomes) {
+		t.Fatalf("expected %d acks, got %+v", len(outcomes), acks)
+	}
+	for i, o := range outcomes {
+		if acks[i].Queue != "jobs" || acks[i].Ack != o.ack || acks[i].Requeue != o.requeue {
+			t.Errorf("%s: unexpected ack %+v", o.body, acks[i])
+		}
+	}
+
+	// Once shut down, deliveries end and nothing more is accepted
+	r.Shutdown()
+	if _, ok := <-ch; ok {
+		t.Error("expected the delivery channel to be closed")
+	}
+	if err = r.Publish("jobs", "", 0, nil, nil); err != amqp.ErrClosed {
+		t.Errorf("expected publishing to fail, got %v", err)
+	}
+	if _, err = r.Subscribe("jobs"); err != amqp.ErrClosed {
+		t.Errorf("expected subscribing to fail, got %v", err)
+	}
+}
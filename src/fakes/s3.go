@@ -0,0 +1,146 @@
+package fakes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/SevenTV/REST/src/instance"
+)
+
+// S3: an in-memory object store
+type S3 struct {
+	mx      sync.Mutex
+	objects map[string]Object
+	// when set, every call fails with this error
+	err error
+}
+
+var _ instance.AwsS3 = (*S3)(nil)
+
+// Object: a stored object
+type Object struct {
+	Data         []byte
+	ContentType  string
+	ACL          string
+	CacheControl string
+}
+
+// NewS3: create an empty in-memory object store
+func NewS3() *S3 {
+	return &S3{objects: map[string]Object{}}
+}
+
+func objectKey(bucket, key string) string {
+	return bucket + "/" + key
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func (s *S3) UploadFile(ctx context.Context, bucket, key string, data io.Reader, contentType, acl, cacheControl *string) error {
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("failed to upload file, %v", err)
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.err != nil {
+		return fmt.Errorf("failed to upload file, %v", s.err)
+	}
+
+	s.objects[objectKey(bucket, key)] = Object{
+		Data:         b,
+		ContentType:  deref(contentType),
+		ACL:          deref(acl),
+		CacheControl: deref(cacheControl),
+	}
+	return nil
+}
+
+func (s *S3) DownloadFile(ctx context.Context, bucket, key string, file io.WriterAt) error {
+	s.mx.Lock()
+	obj, ok := s.objects[objectKey(bucket, key)]
+	failure := s.err
+	s.mx.Unlock()
+
+	if failure != nil {
+		return fmt.Errorf("failed to download file, %v", failure)
+	}
+	if !ok {
+		return fmt.Errorf("failed to download file, NoSuchKey: %s/%s", bucket, key)
+	}
+	if _, err := file.WriteAt(obj.Data, 0); err != nil {
+		return fmt.Errorf("failed to download file, %v", err)
+	}
+
+	return nil
+}
+
+func (s *S3) ListObjects(ctx context.Context, bucket, prefix string) ([]string, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.err != nil {
+		return nil, fmt.Errorf("failed to list files, %v", s.err)
+	}
+
+	keys := []string{}
+	for k := range s.objects {
+		if strings.HasPrefix(k, objectKey(bucket, prefix)) {
+			keys = append(keys, strings.TrimPrefix(k, objectKey(bucket, "")))
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *S3) DeleteObjects(ctx context.Context, bucket string, keys []string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.err != nil {
+		return fmt.Errorf("failed to delete files, %v", s.err)
+	}
+
+	for _, k := range keys {
+		delete(s.objects, objectKey(bucket, k))
+	}
+	return nil
+}
+
+func (s *S3) HeadBucket(ctx context.Context, bucket string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.err != nil {
+		return fmt.Errorf("failed to access bucket, %v", s.err)
+	}
+	return nil
+}
+
+// Fail: make every call fail with err, as if the store was unreachable. A nil error makes it available again
+func (s *S3) Fail(err error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.err = err
+}
+
+// Object: get a stored object
+func (s *S3) Object(bucket, key string) (Object, bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	obj, ok := s.objects[objectKey(bucket, key)]
+	return obj, ok
+}
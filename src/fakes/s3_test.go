@@ -0,0 +1,100 @@
+package fakes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestS3(t *testing.T) {
+	ctx := context.Background()
+	s := NewS3()
+
+	for _, key := range []string{"emote/1/1x.webp", "emote/1/2x.webp", "emote/2/1x.webp"} {
+		if err := s.UploadFile(ctx, "bucket", key, strings.NewReader(key), aws.String("image/webp"), aws.String("public-read"), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	obj, ok := s.Object("bucket", "emote/1/1x.webp")
+	if !ok || string(obj.Data) != "emote/1/1x.webp" || obj.ContentType != "image/webp" || obj.ACL != "public-read" || obj.CacheControl != "" {
+		t.Errorf("unexpected object %+v", obj)
+	}
+
+	tests := []struct {
+		name   string
+		bucket string
+		prefix string
+		want   []string
+	}{
+		{"by prefix", "bucket", "emote/1/", []string{"emote/1/1x.webp", "emote/1/2x.webp"}},
+		{"everything", "bucket", "", []string{"emote/1/1x.webp", "emote/1/2x.webp", "emote/2/1x.webp"}},
+		{"other bucket", "other", "", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := s.ListObjects(ctx, tt.bucket, tt.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expected %v, got %v", tt.want, keys)
+			}
+		})
+	}
+
+	f, err := os.Create(path.Join(t.TempDir(), "download"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = s.DownloadFile(ctx, "bucket", "emote/2/1x.webp", f); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(f.Name()); !bytes.Equal(b, []byte("emote/2/1x.webp")) {
+		t.Errorf("unexpected download %q", b)
+	}
+	if err = s.DownloadFile(ctx, "bucket", "missing", f); err == nil {
+		t.Error("expected downloading a missing object to fail")
+	}
+
+	if err = s.DeleteObjects(ctx, "bucket", []string{"emote/1/1x.webp", "emote/1/2x.webp"}); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := s.ListObjects(ctx, "bucket", ""); len(keys) != 1 {
+		t.Errorf("expected one object left, got %v", keys)
+	}
+}
+
+func TestS3Fail(t *testing.T) {
+	ctx := context.Background()
+	s := NewS3()
+	s.Fail(errors.New("unreachable"))
+
+	calls := []struct {
+		name string
+		call func() error
+	}{
+		{"upload", func() error { return s.UploadFile(ctx, "bucket", "k", strings.NewReader(""), nil, nil, nil) }},
+		{"download", func() error { return s.DownloadFile(ctx, "bucket", "k", &os.File{}) }},
+		{"list", func() error { _, err := s.ListObjects(ctx, "bucket", ""); return err }},
+		{"delete", func() error { return s.DeleteObjects(ctx, "bucket", []string{"k"}) }},
+		{"head bucket", func() error { return s.HeadBucket(ctx, "bucket") }},
+	}
+	for _, c := range calls {
+		if err := c.call(); err == nil || !strings.Contains(err.Error(), "unreachable") {
+			t.Errorf("%s: expected the failure, got %v", c.name, err)
+		}
+	}
+
+	s.Fail(nil)
+	if err := s.HeadBucket(ctx, "bucket"); err != nil {
+		t.Errorf("expected the store to be available again, got %v", err)
+	}
+}
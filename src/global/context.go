@@ -2,6 +2,7 @@ package global
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	"github.com/SevenTV/REST/src/configure"
@@ -13,15 +14,36 @@ type Context interface {
 	Value(key interface{}) interface{}
 	Done() <-chan struct{}
 	Config() *configure.Config
+	// SetConfig: replace the config, for this context and every context sharing it
+	SetConfig(config *configure.Config)
 	Inst() *Instances
 }
 
 type gCtx struct {
 	ctx    context.Context
-	config *configure.Config
+	config *configHolder
 	inst   *Instances
 }
 
+// configHolder: the config shared by a context and those derived from it, so that it can be swapped at runtime
+type configHolder struct {
+	v atomic.Value
+}
+
+func newConfigHolder(config *configure.Config) *configHolder {
+	h := &configHolder{}
+	h.v.Store(config)
+	return h
+}
+
+// holderOf: the config holder of a context, to be shared with the contexts derived from it
+func holderOf(ctx Context) *configHolder {
+	if g, ok := ctx.(*gCtx); ok {
+		return g.config
+	}
+	return newConfigHolder(ctx.Config())
+}
+
 func (g *gCtx) Deadline() (time.Time, bool) {
 	return g.ctx.Deadline()
 }
@@ -39,7 +61,11 @@ func (g *gCtx) Value(key interface{}) interface{} {
 }
 
 func (g *gCtx) Config() *configure.Config {
-	return g.config
+	return g.config.v.Load().(*configure.Config)
+}
+
+func (g *gCtx) SetConfig(config *configure.Config) {
+	g.config.v.Store(config)
 }
 
 func (g *gCtx) Inst() *Instances {
@@ -49,13 +75,13 @@ func (g *gCtx) Inst() *Instances {
 func New(ctx context.Context, config *configure.Config) Context {
 	return &gCtx{
 		ctx:    ctx,
-		config: config,
+		config: newConfigHolder(config),
 		inst:   &Instances{},
 	}
 }
 
 func WithCancel(ctx Context) (Context, context.CancelFunc) {
-	cfg := ctx.Config()
+	cfg := holderOf(ctx)
 	inst := ctx.Inst()
 
 	c, cancel := context.WithCancel(ctx)
@@ -68,7 +94,7 @@ func WithCancel(ctx Context) (Context, context.CancelFunc) {
 }
 
 func WithDeadline(ctx Context, deadline time.Time) (Context, context.CancelFunc) {
-	cfg := ctx.Config()
+	cfg := holderOf(ctx)
 	inst := ctx.Inst()
 
 	c, cancel := context.WithDeadline(ctx, deadline)
@@ -81,7 +107,7 @@ func WithDeadline(ctx Context, deadline time.Time) (Context, context.CancelFunc)
 }
 
 func WithValue(ctx Context, key interface{}, value interface{}) Context {
-	cfg := ctx.Config()
+	cfg := holderOf(ctx)
 	inst := ctx.Inst()
 
 	return &gCtx{
@@ -92,7 +118,7 @@ func WithValue(ctx Context, key interface{}, value interface{}) Context {
 }
 
 func WithTimeout(ctx Context, timeout time.Duration) (Context, context.CancelFunc) {
-	cfg := ctx.Config()
+	cfg := holderOf(ctx)
 	inst := ctx.Inst()
 
 	c, cancel := context.WithTimeout(ctx, timeout)
@@ -6,10 +6,11 @@ import (
 )
 
 type Instances struct {
-	Redis instance.Redis
-	Mongo instance.Mongo
-	Auth  instance.Auth
-	Rmq   instance.Rmq
-	AwsS3 instance.AwsS3
-	Query *query.Query
+	Redis      instance.Redis
+	Mongo      instance.Mongo
+	Auth       instance.Auth
+	Rmq        instance.Rmq
+	AwsS3      instance.AwsS3
+	Prometheus instance.Prometheus
+	Query      *query.Query
 }
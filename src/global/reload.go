@@ -0,0 +1,32 @@
+package global
+
+import (
+	"sync"
+
+	"github.com/SevenTV/REST/src/configure"
+	"github.com/sirupsen/logrus"
+)
+
+var reloadMx sync.Mutex
+
+// ReloadConfig: read the config again and swap it in, if it is valid. The current config is kept otherwise
+//
+// The keys of changed settings which only take effect after a restart are returned
+func ReloadConfig(gCtx Context) ([]string, error) {
+	reloadMx.Lock()
+	defer reloadMx.Unlock()
+
+	config, restart, err := configure.Reload(gCtx.Config())
+	if err != nil {
+		logrus.WithError(err).Error("config, reload failed, keeping the current config")
+		return nil, err
+	}
+	gCtx.SetConfig(config)
+
+	if len(restart) > 0 {
+		logrus.WithField("keys", restart).Warn("config, some changes require a restart to take effect")
+	}
+	logrus.Info("config reloaded")
+
+	return restart, nil
+}
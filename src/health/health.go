@@ -0,0 +1,182 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SevenTV/REST/src/global"
+)
+
+type Service string
+
+const (
+	ServiceMongo Service = "mongo"
+	ServiceRedis Service = "redis"
+	ServiceRmq   Service = "rmq"
+	ServiceS3    Service = "s3"
+)
+
+// Required: whether the node is unable to serve requests without the service.
+// Optional services only affect some features, so the node is degraded rather than unavailable without them
+func (s Service) Required() bool {
+	return s == ServiceMongo || s == ServiceRedis
+}
+
+type Status string
+
+const (
+	StatusOK          Status = "OK"
+	StatusDegraded    Status = "DEGRADED"
+	StatusUnavailable Status = "UNAVAILABLE"
+	StatusTimeout     Status = "TIMED_OUT"
+)
+
+var ready int32
+
+// SetReady: mark whether or not the node should be sent traffic.
+// It is marked ready once it has started, and no longer ready as soon as it begins to shut down
+func SetReady(v bool) {
+	var i int32
+	if v {
+		i = 1
+	}
+	atomic.StoreInt32(&ready, i)
+}
+
+// Ready: whether or not the node should be sent traffic
+func Ready() bool {
+	return atomic.LoadInt32(&ready) == 1
+}
+
+// Drain: mark the node as not ready, then wait for the delay so that load balancers see it and stop sending traffic
+// before the node stops accepting connections. It returns early if the context is done first
+func Drain(ctx context.Context, delay time.Duration) {
+	SetReady(false)
+	if delay <= 0 {
+		return
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+type Result struct {
+	Status  Status
+	Latency time.Duration
+	Error   error
+	// The most recent failure of the service, which may have been during an earlier check
+	LastFailure *Failure
+}
+
+// Failure: an error returned by a service when it was checked
+type Failure struct {
+	Error string
+	At    time.Time
+}
+
+type Report map[Service]Result
+
+// Status: the overall status of the node. It is unavailable if a required service is down, or degraded if an optional one is
+func (r Report) Status() Status {
+	status := StatusOK
+	for svc, res := range r {
+		if res.Status == StatusOK {
+			continue
+		}
+		if svc.Required() {
+			return StatusUnavailable
+		}
+		status = StatusDegraded
+	}
+	return status
+}
+
+var (
+	failures   = map[Service]*Failure{}
+	failuresMx sync.Mutex
+)
+
+// Check: probe the service's dependencies, giving each of them up to the specified timeout to respond
+//
+// The outcome is also recorded to the metrics instance, if one is set up
+func Check(ctx context.Context, gCtx global.Context, timeout time.Duration) Report {
+	notSetUp := fmt.Errorf("not set up")
+	report := Report{
+		ServiceMongo: {Status: StatusUnavailable, Error: notSetUp},
+		ServiceRedis: {Status: StatusUnavailable, Error: notSetUp},
+		ServiceRmq:   {Status: StatusUnavailable, Error: notSetUp},
+		ServiceS3:    {Status: StatusUnavailable, Error: notSetUp},
+	}
+
+	lctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	mx := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	probe := func(svc Service, ping func(ctx context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+			err := ping(lctx)
+			res := Result{Status: StatusOK, Latency: time.Since(start), Error: err}
+			if err != nil {
+				res.Status = StatusUnavailable
+				if lctx.Err() != nil {
+					res.Status = StatusTimeout
+				}
+			}
+
+			mx.Lock()
+			report[svc] = res
+			mx.Unlock()
+		}()
+	}
+	if gCtx.Inst().Mongo != nil {
+		probe(ServiceMongo, gCtx.Inst().Mongo.Ping)
+	}
+	if gCtx.Inst().Redis != nil {
+		probe(ServiceRedis, gCtx.Inst().Redis.Ping)
+	}
+	if gCtx.Inst().Rmq != nil {
+		probe(ServiceRmq, func(ctx context.Context) error {
+			_, err := gCtx.Inst().Rmq.QueueDepth(gCtx.Config().Rmq.JobQueueName)
+			return err
+		})
+	}
+	if gCtx.Inst().AwsS3 != nil {
+		probe(ServiceS3, func(ctx context.Context) error {
+			return gCtx.Inst().AwsS3.HeadBucket(ctx, gCtx.Config().Aws.Bucket)
+		})
+	}
+	wg.Wait()
+
+	// Remember the failures, so that they can still be looked into once the service has recovered
+	failuresMx.Lock()
+	for svc, res := range report {
+		if res.Error != nil {
+			failures[svc] = &Failure{Error: res.Error.Error(), At: time.Now()}
+		}
+		if f := failures[svc]; f != nil {
+			res.LastFailure = &Failure{Error: f.Error, At: f.At}
+			report[svc] = res
+		}
+	}
+	failuresMx.Unlock()
+
+	if gCtx.Inst().Prometheus != nil {
+		for svc, res := range report {
+			gCtx.Inst().Prometheus.SetDependency(string(svc), res.Status == StatusOK, res.Latency)
+		}
+	}
+
+	return report
+}
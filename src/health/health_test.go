@@ -0,0 +1,173 @@
+package health_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/health"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(gCtx global.Context)
+		status health.Status
+		// the expected status of each service
+		services map[health.Service]health.Status
+	}{
+		{
+			name:   "every service is up",
+			setup:  func(gCtx global.Context) {},
+			status: health.StatusOK,
+			services: map[health.Service]health.Status{
+				health.ServiceMongo: health.StatusOK,
+				health.ServiceRedis: health.StatusOK,
+				health.ServiceRmq:   health.StatusOK,
+				health.ServiceS3:    health.StatusOK,
+			},
+		},
+		{
+			name: "an unreachable bucket degrades the node",
+			setup: func(gCtx global.Context) {
+				gCtx.Inst().AwsS3.(*fakes.S3).Fail(fmt.Errorf("connection refused"))
+			},
+			status: health.StatusDegraded,
+			services: map[health.Service]health.Status{
+				health.ServiceMongo: health.StatusOK,
+				health.ServiceS3:    health.StatusUnavailable,
+			},
+		},
+		{
+			name: "optional services which are not set up degrade the node",
+			setup: func(gCtx global.Context) {
+				gCtx.Inst().AwsS3 = nil
+				gCtx.Inst().Rmq = nil
+			},
+			status: health.StatusDegraded,
+			services: map[health.Service]health.Status{
+				health.ServiceRmq: health.StatusUnavailable,
+				health.ServiceS3:  health.StatusUnavailable,
+			},
+		},
+		{
+			name: "required services which are not set up make the node unavailable",
+			setup: func(gCtx global.Context) {
+				gCtx.Inst().Redis = nil
+			},
+			status: health.StatusUnavailable,
+			services: map[health.Service]health.Status{
+				health.ServiceRedis: health.StatusUnavailable,
+				health.ServiceS3:    health.StatusOK,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gCtx, err := fakes.NewContext(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setup(gCtx)
+
+			report := health.Check(gCtx, gCtx, time.Second)
+			if report.Status() != tt.status {
+				t.Errorf("expected status %s, got %s (%+v)", tt.status, report.Status(), report)
+			}
+			for svc, status := range tt.services {
+				if report[svc].Status != status {
+					t.Errorf("expected %s to be %s, got %s (%v)", svc, status, report[svc].Status, report[svc].Error)
+				}
+			}
+
+			// Failures are reported to the dependency gauges
+			up := map[string]float64{}
+			families, err := gCtx.Inst().Prometheus.Registry().Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range families {
+				if f.GetName() != "rest_dependency_up" {
+					continue
+				}
+				for _, m := range f.GetMetric() {
+					for _, l := range m.GetLabel() {
+						if l.GetName() == "service" {
+							up[l.GetValue()] = m.GetGauge().GetValue()
+						}
+					}
+				}
+			}
+			for svc, status := range tt.services {
+				want := 0.0
+				if status == health.StatusOK {
+					want = 1
+				}
+				if up[string(svc)] != want {
+					t.Errorf("expected the %s gauge to be %v, got %v", svc, want, up[string(svc)])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckRemembersFailures(t *testing.T) {
+	gCtx, err := fakes.NewContext(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3 := gCtx.Inst().AwsS3.(*fakes.S3)
+
+	s3.Fail(fmt.Errorf("connection refused"))
+	_ = health.Check(gCtx, gCtx, time.Second)
+	s3.Fail(nil)
+
+	res := health.Check(gCtx, gCtx, time.Second)[health.ServiceS3]
+	if res.Status != health.StatusOK {
+		t.Fatalf("expected s3 to have recovered, got %s", res.Status)
+	}
+	if res.LastFailure == nil || res.LastFailure.Error != "failed to access bucket, connection refused" {
+		t.Errorf("expected the last failure to be kept, got %+v", res.LastFailure)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name  string
+		delay time.Duration
+		// how long until the context is done, if it is
+		deadline time.Duration
+		// the range the drain is expected to take
+		min, max time.Duration
+	}{
+		{"no delay", 0, 0, 0, time.Millisecond * 50},
+		{"waits for the delay", time.Millisecond * 100, 0, time.Millisecond * 100, time.Millisecond * 300},
+		{"stops at the deadline", time.Second * 10, time.Millisecond * 100, time.Millisecond * 100, time.Millisecond * 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.deadline > 0 {
+				ctx, cancel = context.WithTimeout(context.Background(), tt.deadline)
+			}
+			defer cancel()
+
+			health.SetReady(true)
+			start := time.Now()
+			health.Drain(ctx, tt.delay)
+			took := time.Since(start)
+
+			if health.Ready() {
+				t.Error("expected the node to no longer be ready")
+			}
+			if took < tt.min || took > tt.max {
+				t.Errorf("expected the drain to take between %s and %s, took %s", tt.min, tt.max, took)
+			}
+		})
+	}
+}
@@ -8,4 +8,7 @@ import (
 type AwsS3 interface {
 	UploadFile(ctx context.Context, bucket, key string, data io.Reader, contentType, acl, cacheControl *string) error
 	DownloadFile(ctx context.Context, bucket, key string, file io.WriterAt) error
+	ListObjects(ctx context.Context, bucket, prefix string) ([]string, error)
+	DeleteObjects(ctx context.Context, bucket string, keys []string) error
+	HeadBucket(ctx context.Context, bucket string) error
 }
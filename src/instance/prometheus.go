@@ -0,0 +1,16 @@
+package instance
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type Prometheus interface {
+	Registry() *prometheus.Registry
+	ObserveRequest(method, route string, status int, duration time.Duration)
+	EmoteJobPublished()
+	EmoteJobStarted()
+	EmoteJobFinished(success bool)
+	SetDependency(name string, up bool, latency time.Duration)
+}
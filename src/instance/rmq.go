@@ -4,5 +4,7 @@ import "github.com/streadway/amqp"
 
 type Rmq interface {
 	Subscribe(queue string) (<-chan amqp.Delivery, error)
-	Publish(queue string, contentType string, deliveryMode uint8, msg []byte) error
+	Publish(queue string, contentType string, deliveryMode uint8, headers amqp.Table, msg []byte) error
+	QueueDepth(queue string) (int, error)
+	Shutdown()
 }
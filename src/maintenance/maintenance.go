@@ -0,0 +1,224 @@
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SevenTV/REST/src/global"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+type Mode string
+
+const (
+	// The API is fully available
+	ModeOff Mode = "off"
+	// Only GET requests are served; all other requests are rejected
+	ModeReadOnly Mode = "read-only"
+	// No requests are served; every route responds with the maintenance notice, or the configured notice page
+	ModeMaintenance Mode = "maintenance"
+)
+
+// Valid: whether or not the mode is known
+func (m Mode) Valid() bool {
+	return m == ModeOff || m == ModeReadOnly || m == ModeMaintenance
+}
+
+const (
+	// The key of the mode set at runtime, shared between nodes. It takes precedence over the configured mode
+	RedisKey = "rest:maintenance"
+	// Nodes are notified on this channel when the mode is changed
+	RedisChannel = "rest:maintenance"
+
+	// How often the mode is read again, in case a notification was missed
+	refreshInterval = time.Second * 30
+	// The Retry-After sent to clients, unless configured otherwise
+	defaultRetryAfter = 300
+)
+
+// State: the mode of the API, and what to tell clients about it
+type State struct {
+	Mode Mode `json:"mode"`
+	// A notice for clients, i.e the reason for the maintenance or when it is expected to end
+	Message string `json:"message,omitempty"`
+	// How long clients should wait before retrying, in seconds
+	RetryAfter int `json:"retry_after,omitempty"`
+	// When the mode was set at runtime. Zero when the mode is the configured one
+	SetAt time.Time `json:"set_at"`
+}
+
+var (
+	override   *State
+	overrideMx sync.RWMutex
+
+	notice   noticePage
+	noticeMx sync.Mutex
+)
+
+// noticePage: the configured notice, as it was last read
+type noticePage struct {
+	path        string
+	modTime     time.Time
+	size        int64
+	body        []byte
+	contentType string
+}
+
+// Current: get the mode of the API. The mode set at runtime applies if there is one, otherwise the configured mode
+func Current(gCtx global.Context) State {
+	overrideMx.RLock()
+	o := override
+	overrideMx.RUnlock()
+
+	s := State{}
+	if o != nil {
+		s = *o
+	} else {
+		cfg := gCtx.Config().Maintenance
+		s = State{Mode: Mode(cfg.Mode), Message: cfg.Message, RetryAfter: cfg.RetryAfter}
+	}
+
+	if !s.Mode.Valid() {
+		s.Mode = ModeOff
+	}
+	if s.RetryAfter <= 0 {
+		s.RetryAfter = defaultRetryAfter
+	}
+	return s
+}
+
+// Notice: the configured page to serve in place of every response while in maintenance mode, and its content type.
+// The body is nil if no page is configured. The file is read again when it changes
+func Notice(gCtx global.Context) ([]byte, string, error) {
+	path := gCtx.Config().Maintenance.Notice
+	if path == "" {
+		return nil, "", nil
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	noticeMx.Lock()
+	defer noticeMx.Unlock()
+	if notice.path == path && notice.modTime.Equal(fi.ModTime()) && notice.size == fi.Size() {
+		return notice.body, notice.contentType, nil
+	}
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	contentType := "text/html; charset=utf-8"
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		contentType = "application/json"
+	}
+
+	notice = noticePage{path: path, modTime: fi.ModTime(), size: fi.Size(), body: body, contentType: contentType}
+	return body, contentType, nil
+}
+
+// Set: change the mode of the API at runtime, on every node
+func Set(ctx context.Context, gCtx global.Context, s State) error {
+	if !s.Mode.Valid() {
+		return fmt.Errorf("unknown mode %q", s.Mode)
+	}
+	s.SetAt = time.Now()
+
+	if gCtx.Inst().Redis != nil {
+		b, _ := json.Marshal(&s)
+		cl := gCtx.Inst().Redis.RawClient()
+		if err := cl.Set(ctx, RedisKey, b, 0).Err(); err != nil {
+			return err
+		}
+		cl.Publish(ctx, RedisChannel, "1")
+	}
+
+	apply(gCtx, &s)
+	return nil
+}
+
+// Clear: remove the mode set at runtime on every node, returning to the configured mode
+func Clear(ctx context.Context, gCtx global.Context) error {
+	if gCtx.Inst().Redis != nil {
+		cl := gCtx.Inst().Redis.RawClient()
+		if err := cl.Del(ctx, RedisKey).Err(); err != nil {
+			return err
+		}
+		cl.Publish(ctx, RedisChannel, "1")
+	}
+
+	apply(gCtx, nil)
+	return nil
+}
+
+// Watch: keep the mode in sync with the one shared through redis, for as long as the global context lives
+func Watch(gCtx global.Context) {
+	if gCtx.Inst().Redis == nil {
+		return
+	}
+
+	sub := gCtx.Inst().Redis.RawClient().Subscribe(gCtx, RedisChannel)
+	defer sub.Close()
+
+	refresh(gCtx)
+
+	ch := sub.Channel()
+	tick := time.NewTicker(refreshInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+			refresh(gCtx)
+		case <-tick.C:
+			refresh(gCtx)
+		case <-gCtx.Done():
+			return
+		}
+	}
+}
+
+// refresh: read the mode shared through redis
+func refresh(gCtx global.Context) {
+	ctx, cancel := context.WithTimeout(gCtx, time.Second*5)
+	defer cancel()
+
+	b, err := gCtx.Inst().Redis.RawClient().Get(ctx, RedisKey).Bytes()
+	if err == redis.Nil {
+		apply(gCtx, nil)
+		return
+	} else if err != nil {
+		logrus.WithError(err).Error("redis, failed to get maintenance mode")
+		return
+	}
+
+	s := &State{}
+	if err = json.Unmarshal(b, s); err != nil {
+		logrus.WithError(err).Error("maintenance, bad state stored in redis")
+		return
+	}
+	apply(gCtx, s)
+}
+
+func apply(gCtx global.Context, s *State) {
+	prev := Current(gCtx).Mode
+
+	overrideMx.Lock()
+	override = s
+	overrideMx.Unlock()
+
+	if mode := Current(gCtx).Mode; mode != prev {
+		logrus.WithField("mode", mode).Info("maintenance, mode changed")
+	}
+}
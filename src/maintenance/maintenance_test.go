@@ -0,0 +1,83 @@
+package maintenance
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/REST/src/fakes"
+)
+
+func TestNotice(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string, mod time.Time) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		// the path of the notice, and a change to make to it after it is first read
+		path   func() string
+		change func(path string)
+		// the expected body and content type once changed, or whether reading fails
+		body        string
+		contentType string
+		err         bool
+	}{
+		{"not configured", func() string { return "" }, nil, "", "", false},
+		{"html", func() string { return write("a.html", "<p>a</p>", now) }, nil, "<p>a</p>", "text/html; charset=utf-8", false},
+		{"json", func() string { return write("b.JSON", `{"a":1}`, now) }, nil, `{"a":1}`, "application/json", false},
+		{"missing", func() string { return filepath.Join(dir, "missing.html") }, nil, "", "", true},
+		{
+			"read again once changed",
+			func() string { return write("c.html", "<p>c</p>", now) },
+			func(path string) { write("c.html", "<p>changed</p>", now.Add(time.Second)) },
+			"<p>changed</p>", "text/html; charset=utf-8", false,
+		},
+		{
+			"removed",
+			func() string { return write("d.html", "<p>d</p>", now) },
+			func(path string) { _ = os.Remove(path) },
+			"", "", true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := fakes.NewConfig()
+			config.Maintenance.Notice = tt.path()
+			gCtx, err := fakes.NewContext(context.Background(), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.change != nil {
+				if _, _, err := Notice(gCtx); err != nil {
+					t.Fatal(err)
+				}
+				tt.change(config.Maintenance.Notice)
+			}
+
+			body, contentType, err := Notice(gCtx)
+			if tt.err != (err != nil) {
+				t.Fatalf("expected err=%t, got %v", tt.err, err)
+			}
+			if string(body) != tt.body || contentType != tt.contentType {
+				t.Errorf("expected %q (%s), got %q (%s)", tt.body, tt.contentType, body, contentType)
+			}
+			if tt.body == "" && body != nil {
+				t.Errorf("expected no body, got %q", body)
+			}
+		})
+	}
+}
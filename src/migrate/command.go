@@ -0,0 +1,70 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/REST/src/configure"
+	"github.com/SevenTV/REST/src/global"
+)
+
+const commandUsage = `usage: rest migrate <command>
+
+commands:
+  up      create the declared indexes and apply the pending migrations
+  status  list the missing indexes and the state of each migration`
+
+// Command: run the "migrate" subcommand, returning the exit code of the process
+func Command(config *configure.Config, args []string) int {
+	if len(args) == 0 || (args[0] != "up" && args[0] != "status") {
+		fmt.Fprintln(os.Stderr, commandUsage)
+		return 2
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gCtx := global.New(ctx, config)
+
+	lctx, lcancel := context.WithTimeout(ctx, time.Second*15)
+	mongoInst, err := mongo.Setup(lctx, mongo.SetupOptions{
+		URI: config.Mongo.URI,
+		DB:  config.Mongo.DB,
+	})
+	lcancel()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to connect to mongo:", err.Error())
+		return 1
+	}
+	gCtx.Inst().Mongo = mongoInst
+	defer func() {
+		_ = mongoInst.RawClient().Disconnect(context.Background())
+	}()
+
+	switch args[0] {
+	case "up":
+		if err := Up(gCtx, gCtx); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+		fmt.Println("migrations applied")
+	case "status":
+		s, err := GetStatus(gCtx, gCtx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+
+		b, _ := json.MarshalIndent(s, "", "    ")
+		fmt.Println(string(b))
+		if s.Pending() {
+			return 3
+		}
+	}
+
+	return 0
+}
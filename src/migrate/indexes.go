@@ -0,0 +1,93 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/REST/src/configure"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// createIndexes: create the indexes declared in configure.Indexes. Those which already exist are left as they are
+func createIndexes(ctx context.Context, gCtx global.Context) error {
+	for col, indexes := range declaredIndexes() {
+		names, err := gCtx.Inst().Mongo.Collection(col).Indexes().CreateMany(ctx, indexes)
+		if err != nil {
+			return fmt.Errorf("failed to create the indexes of %s: %s", col, err.Error())
+		}
+		logrus.WithFields(logrus.Fields{"collection": col, "indexes": names}).Info("migrate, indexes ok")
+	}
+
+	return nil
+}
+
+// missingIndexes: the declared indexes which do not exist, as collection.index_name
+func missingIndexes(ctx context.Context, gCtx global.Context) ([]string, error) {
+	missing := []string{}
+	for col, indexes := range declaredIndexes() {
+		cur, err := gCtx.Inst().Mongo.Collection(col).Indexes().List(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		existing := []struct {
+			Name string `bson:"name"`
+		}{}
+		if err = cur.All(ctx, &existing); err != nil {
+			return nil, err
+		}
+
+		have := map[string]bool{}
+		for _, idx := range existing {
+			have[idx.Name] = true
+		}
+		for _, idx := range indexes {
+			name, err := indexName(idx)
+			if err != nil {
+				return nil, err
+			}
+			if !have[name] {
+				missing = append(missing, fmt.Sprintf("%s.%s", col, name))
+			}
+		}
+	}
+
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// declaredIndexes: the indexes of configure.Indexes, by collection
+func declaredIndexes() map[mongo.CollectionName][]mongo.IndexModel {
+	out := map[mongo.CollectionName][]mongo.IndexModel{}
+	for _, ref := range configure.Indexes {
+		out[ref.Collection] = append(out[ref.Collection], ref.Index)
+	}
+	return out
+}
+
+// indexName: the name of an index, which mongo derives from its keys unless one is set
+func indexName(idx mongo.IndexModel) (string, error) {
+	if idx.Options != nil && idx.Options.Name != nil {
+		return *idx.Options.Name, nil
+	}
+
+	b, err := bson.Marshal(idx.Keys)
+	if err != nil {
+		return "", err
+	}
+	keys := bson.D{}
+	if err = bson.Unmarshal(b, &keys); err != nil {
+		return "", err
+	}
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s_%v", k.Key, k.Value)
+	}
+	return strings.Join(parts, "_"), nil
+}
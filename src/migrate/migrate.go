@@ -0,0 +1,267 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The collection recording the applied migrations, and holding the lock
+const CollectionName mongo.CollectionName = "migrations"
+
+const lockID = "lock"
+
+// How long the lock is held for unless it is renewed, so that it is released should the node holding it die
+var lockTTL = time.Minute * 5
+
+// Migration: a versioned change to the data, applied once
+type Migration struct {
+	// The order in which the migration is applied. It must never be changed once released
+	Version int32
+	Name    string
+	Up      func(ctx context.Context, gCtx global.Context) error
+}
+
+// Record: a migration which was applied
+type Record struct {
+	Version   int32     `bson:"_id" json:"version"`
+	Name      string    `bson:"name" json:"name"`
+	AppliedAt time.Time `bson:"applied_at" json:"applied_at"`
+	// How long the migration took to apply, in milliseconds
+	Duration int64 `bson:"duration" json:"duration"`
+}
+
+// Up: create the declared indexes and apply the pending migrations, in order
+//
+// Only one node may migrate at a time. ErrLocked is returned while another one is.
+// Should the lock fail to be renewed, the migration in progress is canceled and ErrLockLost is returned
+func Up(ctx context.Context, gCtx global.Context) (err error) {
+	ctx, release, err := lock(ctx, gCtx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if lerr := release(); lerr != nil {
+			err = lerr
+		}
+	}()
+
+	if err := createIndexes(ctx, gCtx); err != nil {
+		return err
+	}
+
+	applied, err := appliedRecords(ctx, gCtx)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range Migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		l := logrus.WithFields(logrus.Fields{"version": m.Version, "name": m.Name})
+		l.Info("migrate, applying")
+
+		start := time.Now()
+		if err := m.Up(ctx, gCtx); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %s", m.Version, m.Name, err.Error())
+		}
+
+		if _, err := gCtx.Inst().Mongo.Collection(CollectionName).InsertOne(ctx, &Record{
+			Version:   m.Version,
+			Name:      m.Name,
+			AppliedAt: time.Now(),
+			Duration:  time.Since(start).Milliseconds(),
+		}); err != nil {
+			return err
+		}
+		l.WithField("took", time.Since(start)).Info("migrate, applied")
+	}
+
+	return nil
+}
+
+// Status: the state of the declared indexes and of each migration
+type Status struct {
+	// The declared indexes which have not been created, as collection.index_name
+	MissingIndexes []string          `json:"missing_indexes"`
+	Migrations     []MigrationStatus `json:"migrations"`
+}
+
+type MigrationStatus struct {
+	Version int32  `json:"version"`
+	Name    string `json:"name"`
+	// The record of the migration, or nil if it is pending
+	Applied *Record `json:"applied"`
+}
+
+// Pending: whether anything is left to apply
+func (s Status) Pending() bool {
+	if len(s.MissingIndexes) > 0 {
+		return true
+	}
+	for _, m := range s.Migrations {
+		if m.Applied == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// GetStatus: find out what has been applied
+func GetStatus(ctx context.Context, gCtx global.Context) (Status, error) {
+	s := Status{}
+
+	missing, err := missingIndexes(ctx, gCtx)
+	if err != nil {
+		return s, err
+	}
+	s.MissingIndexes = missing
+
+	applied, err := appliedRecords(ctx, gCtx)
+	if err != nil {
+		return s, err
+	}
+	for _, m := range Migrations {
+		ms := MigrationStatus{Version: m.Version, Name: m.Name}
+		if r, ok := applied[m.Version]; ok {
+			ms.Applied = r
+		}
+		s.Migrations = append(s.Migrations, ms)
+	}
+
+	return s, nil
+}
+
+func appliedRecords(ctx context.Context, gCtx global.Context) (map[int32]*Record, error) {
+	cur, err := gCtx.Inst().Mongo.Collection(CollectionName).Find(ctx, bson.M{"_id": bson.M{"$ne": lockID}})
+	if err != nil {
+		return nil, err
+	}
+
+	records := []*Record{}
+	if err = cur.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := map[int32]*Record{}
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// ErrLocked: another node is migrating
+type ErrLocked struct {
+	Owner     string
+	ExpiresAt time.Time
+}
+
+func (e *ErrLocked) Error() string {
+	return fmt.Sprintf("another node is migrating (%s, lock expires at %s)", e.Owner, e.ExpiresAt.Format(time.RFC3339))
+}
+
+// ErrLockLost: the lock could not be renewed, so the run was stopped as another node may take it over
+type ErrLockLost struct {
+	Err error
+}
+
+func (e *ErrLockLost) Error() string {
+	return fmt.Sprintf("lost the migration lock, the run was stopped: %s", e.Err.Error())
+}
+
+func (e *ErrLockLost) Unwrap() error {
+	return e.Err
+}
+
+type lockDoc struct {
+	ID        string    `bson:"_id"`
+	Owner     string    `bson:"owner"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// lock: take the migration lock, renewing it until it is released
+//
+// The returned context is canceled if the lock fails to be renewed, in which case release returns ErrLockLost
+func lock(ctx context.Context, gCtx global.Context) (context.Context, func() error, error) {
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s/%s/%d", gCtx.Config().NodeName, host, os.Getpid())
+	col := gCtx.Inst().Mongo.Collection(CollectionName)
+
+	// Take the lock if nobody holds it, or if its holder failed to renew it.
+	// If it is held, the upsert collides with the existing lock
+	take := func(ctx context.Context) error {
+		_, err := col.UpdateOne(ctx, bson.M{
+			"_id": lockID,
+			"$or": bson.A{bson.M{"owner": owner}, bson.M{"expires_at": bson.M{"$lt": time.Now()}}},
+		}, bson.M{
+			"$set": bson.M{"owner": owner, "expires_at": time.Now().Add(lockTTL)},
+		}, options.Update().SetUpsert(true))
+		return err
+	}
+	if err := take(ctx); err != nil {
+		if !mongodriver.IsDuplicateKeyError(err) {
+			return nil, nil, err
+		}
+
+		held := &lockDoc{}
+		if err := col.FindOne(ctx, bson.M{"_id": lockID}).Decode(held); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, &ErrLocked{Owner: held.Owner, ExpiresAt: held.ExpiresAt}
+	}
+
+	rctx, cancel := context.WithCancel(ctx)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	var lost error
+	go func() {
+		defer close(done)
+
+		tick := time.NewTicker(lockTTL / 5)
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+				// A lock taken over by another node collides with theirs, and is lost too
+				if err := take(rctx); err != nil {
+					if rctx.Err() != nil {
+						return
+					}
+					logrus.WithError(err).Error("migrate, failed to renew the lock")
+					lost = &ErrLockLost{Err: err}
+					cancel()
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return rctx, func() error {
+		close(stop)
+		<-done
+		cancel()
+		if lost != nil {
+			// The lock may belong to another node by now, so it is left alone
+			return lost
+		}
+
+		lctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+		if _, err := col.DeleteOne(lctx, bson.M{"_id": lockID, "owner": owner}); err != nil {
+			logrus.WithError(err).Error("migrate, failed to release the lock")
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,243 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/global"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUp(t *testing.T) {
+	type run struct {
+		// the migrations expected to have been applied once the run is over, in order, and whether it fails
+		applied []int32
+		err     bool
+	}
+
+	tests := []struct {
+		name string
+		// the lock held by another node, if it expires after the given duration
+		held *time.Duration
+		// a migration which fails, if set
+		fail int32
+		runs []run
+	}{
+		{"applies pending migrations once", nil, 0, []run{
+			{applied: []int32{1, 2, 3}},
+			{applied: []int32{1, 2, 3}},
+		}},
+		{"stops at a failed migration", nil, 2, []run{
+			{applied: []int32{1}, err: true},
+		}},
+		{"locked by another node", durationPtr(time.Minute), 0, []run{
+			{applied: []int32{}, err: true},
+		}},
+		{"takes over an expired lock", durationPtr(-time.Minute), 0, []run{
+			{applied: []int32{1, 2, 3}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gCtx := newContext(t)
+			mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+			if tt.held != nil {
+				_ = mgo.Seed(CollectionName, &lockDoc{ID: lockID, Owner: "other", ExpiresAt: time.Now().Add(*tt.held)})
+			}
+
+			order := []int32{}
+			Migrations = nil
+			for v := int32(1); v <= 3; v++ {
+				v := v
+				Migrations = append(Migrations, Migration{Version: v, Name: fmt.Sprintf("m%d", v), Up: func(ctx context.Context, gCtx global.Context) error {
+					if v == tt.fail {
+						return fmt.Errorf("boom")
+					}
+					order = append(order, v)
+					return nil
+				}})
+			}
+
+			for i, r := range tt.runs {
+				err := Up(gCtx, gCtx)
+				if r.err != (err != nil) {
+					t.Fatalf("run %d: expected err=%t, got %v", i, r.err, err)
+				}
+				if tt.held != nil && *tt.held > 0 && !errors.As(err, new(*ErrLocked)) {
+					t.Errorf("run %d: expected the lock to be held, got %v", i, err)
+				}
+
+				applied, err := appliedRecords(gCtx, gCtx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(applied) != len(r.applied) {
+					t.Fatalf("run %d: expected %v to be applied, got %d records", i, r.applied, len(applied))
+				}
+				for _, v := range r.applied {
+					if applied[v] == nil || applied[v].Name != fmt.Sprintf("m%d", v) {
+						t.Errorf("run %d: expected migration %d to be recorded", i, v)
+					}
+				}
+			}
+			if len(order) != len(tt.runs[0].applied) {
+				t.Errorf("expected each migration to run once, in order, got %v", order)
+			}
+			for i, v := range order {
+				if v != int32(i+1) {
+					t.Errorf("expected migrations to run in order, got %v", order)
+				}
+			}
+
+			// The lock is released, unless it belongs to another node
+			lock := bson.M{}
+			err := gCtx.Inst().Mongo.Collection(CollectionName).FindOne(gCtx, bson.M{"_id": lockID}).Decode(&lock)
+			if tt.held != nil && *tt.held > 0 {
+				if err != nil || lock["owner"] != "other" {
+					t.Errorf("expected the other node to keep the lock, got %v %v", lock, err)
+				}
+			} else if err == nil {
+				t.Errorf("expected the lock to be released, got %v", lock)
+			}
+		})
+	}
+}
+
+func TestUpLockLost(t *testing.T) {
+	gCtx := newContext(t)
+
+	prev := lockTTL
+	lockTTL = time.Millisecond * 250
+	defer func() { lockTTL = prev }()
+
+	// The migration runs until it is canceled, while another node takes over the lock
+	canceled := make(chan struct{})
+	Migrations = []Migration{{Version: 1, Name: "slow", Up: func(ctx context.Context, gCtx global.Context) error {
+		_, err := gCtx.Inst().Mongo.Collection(CollectionName).UpdateOne(ctx, bson.M{"_id": lockID}, bson.M{
+			"$set": bson.M{"owner": "other", "expires_at": time.Now().Add(time.Hour)},
+		})
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			close(canceled)
+			return ctx.Err()
+		case <-time.After(time.Second * 5):
+			return nil
+		}
+	}}}
+
+	err := Up(gCtx, gCtx)
+	if !errors.As(err, new(*ErrLockLost)) {
+		t.Fatalf("expected the lock to be lost, got %v", err)
+	}
+	select {
+	case <-canceled:
+	default:
+		t.Error("expected the migration to be canceled")
+	}
+
+	applied, err := appliedRecords(gCtx, gCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("expected the migration not to be recorded, got %v", applied)
+	}
+
+	lock := &lockDoc{}
+	if err := gCtx.Inst().Mongo.Collection(CollectionName).FindOne(gCtx, bson.M{"_id": lockID}).Decode(lock); err != nil || lock.Owner != "other" {
+		t.Errorf("expected the other node to keep the lock, got %+v %v", lock, err)
+	}
+}
+
+func TestLowercaseUsernames(t *testing.T) {
+	gCtx := newContext(t)
+	mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+
+	tests := []struct {
+		username string
+		want     string
+	}{
+		{"already_lower", "already_lower"},
+		{"MixedCase", "mixedcase"},
+		{"UPPER", "upper"},
+		{"with_number_1A", "with_number_1a"},
+	}
+
+	ids := make([]primitive.ObjectID, len(tests))
+	for i, tt := range tests {
+		ids[i] = primitive.NewObjectID()
+		_ = mgo.Seed(mongo.CollectionNameUsers, &structures.User{ID: ids[i], Username: tt.username, RoleIDs: []primitive.ObjectID{}})
+	}
+
+	// It is safe to run again
+	for run := 0; run < 2; run++ {
+		if err := lowercaseUsernames(gCtx, gCtx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, tt := range tests {
+		u := &structures.User{}
+		if err := mgo.Collection(mongo.CollectionNameUsers).FindOne(gCtx, bson.M{"_id": ids[i]}).Decode(u); err != nil {
+			t.Fatal(err)
+		}
+		if u.Username != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.username, tt.want, u.Username)
+		}
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	gCtx := newContext(t)
+	Migrations = []Migration{
+		{Version: 1, Name: "a", Up: func(ctx context.Context, gCtx global.Context) error { return nil }},
+	}
+
+	s, err := GetStatus(gCtx, gCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Pending() || len(s.MissingIndexes) == 0 || s.Migrations[0].Applied != nil {
+		t.Fatalf("expected everything to be pending, got %+v", s)
+	}
+
+	if err = Up(gCtx, gCtx); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = GetStatus(gCtx, gCtx); err != nil {
+		t.Fatal(err)
+	}
+	if s.Pending() || s.Migrations[0].Applied == nil {
+		t.Errorf("expected nothing to be pending, got %+v", s)
+	}
+}
+
+// newContext: a global context with fake instances. The declared migrations are restored once the test is over
+func newContext(t *testing.T) global.Context {
+	gCtx, err := fakes.NewContext(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := Migrations
+	t.Cleanup(func() {
+		Migrations = prev
+	})
+	return gCtx
+}
+
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
@@ -0,0 +1,59 @@
+package migrate
+
+import (
+	"context"
+	"strings"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/popularity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migrations: the data migrations, in the order they are applied
+//
+// Append new migrations to the end with the next version. Each one is applied once, and must be safe to run again
+// should it fail part-way, as it is only recorded as applied once it has completed
+var Migrations = []Migration{
+	{Version: 1, Name: "lowercase-usernames", Up: lowercaseUsernames},
+	{Version: 2, Name: "emote-popularity", Up: emotePopularity},
+}
+
+// lowercaseUsernames: users are looked up by their username in lowercase (i.e by the v2 API),
+// so those stored with capitals before logins were normalised could not be found
+func lowercaseUsernames(ctx context.Context, gCtx global.Context) error {
+	col := gCtx.Inst().Mongo.Collection(mongo.CollectionNameUsers)
+	cur, err := col.Find(ctx, bson.M{
+		"username": bson.M{"$regex": "[A-Z]"},
+	}, options.Find().SetProjection(bson.M{"username": 1}))
+	if err != nil {
+		return err
+	}
+
+	users := []struct {
+		ID       primitive.ObjectID `bson:"_id"`
+		Username string             `bson:"username"`
+	}{}
+	if err = cur.All(ctx, &users); err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		// The username is matched too, so that one changed since it was read is left alone
+		if _, err = col.UpdateOne(ctx, bson.M{"_id": u.ID, "username": u.Username}, bson.M{
+			"$set": bson.M{"username": strings.ToLower(u.Username)},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// emotePopularity: emotes are sorted by their stored popularity, which those created before it was kept do not have
+func emotePopularity(ctx context.Context, gCtx global.Context) error {
+	_, err := popularity.Update(ctx, gCtx)
+	return err
+}
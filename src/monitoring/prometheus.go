@@ -0,0 +1,127 @@
+package monitoring
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/SevenTV/REST/src/instance"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+type SetupOptions struct {
+	// The name of this node, attached to every metric
+	NodeName string
+}
+
+type mon struct {
+	registry *prometheus.Registry
+
+	httpDuration  *prometheus.HistogramVec
+	httpResponses *prometheus.CounterVec
+
+	emoteJobs         *prometheus.CounterVec
+	emoteJobsActive   prometheus.Gauge
+	dependencyUp      *prometheus.GaugeVec
+	dependencyLatency *prometheus.GaugeVec
+}
+
+func New(opts SetupOptions) instance.Prometheus {
+	labels := prometheus.Labels{"node": opts.NodeName}
+
+	m := &mon{
+		registry: prometheus.NewRegistry(),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   "rest",
+			Subsystem:   "http",
+			Name:        "request_duration_seconds",
+			Help:        "The time taken to handle requests, by route",
+			ConstLabels: labels,
+			Buckets:     prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		httpResponses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "rest",
+			Subsystem:   "http",
+			Name:        "responses_total",
+			Help:        "The amount of responses sent, by route and status code",
+			ConstLabels: labels,
+		}, []string{"method", "route", "status"}),
+		emoteJobs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "rest",
+			Subsystem:   "emote_jobs",
+			Name:        "total",
+			Help:        "The amount of emote processing jobs, by event (published, started, completed, failed)",
+			ConstLabels: labels,
+		}, []string{"event"}),
+		emoteJobsActive: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   "rest",
+			Subsystem:   "emote_jobs",
+			Name:        "active",
+			Help:        "The amount of emote processing jobs which have started but not yet finished",
+			ConstLabels: labels,
+		}),
+		dependencyUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   "rest",
+			Subsystem:   "dependency",
+			Name:        "up",
+			Help:        "Whether or not a dependency was reachable during the last health check",
+			ConstLabels: labels,
+		}, []string{"service"}),
+		dependencyLatency: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   "rest",
+			Subsystem:   "dependency",
+			Name:        "latency_seconds",
+			Help:        "The time taken by a dependency to respond during the last health check",
+			ConstLabels: labels,
+		}, []string{"service"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpDuration,
+		m.httpResponses,
+		m.emoteJobs,
+		m.emoteJobsActive,
+		m.dependencyUp,
+		m.dependencyLatency,
+	)
+
+	return m
+}
+
+func (m *mon) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+func (m *mon) ObserveRequest(method, route string, status int, duration time.Duration) {
+	m.httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+	m.httpResponses.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+}
+
+func (m *mon) EmoteJobPublished() {
+	m.emoteJobs.WithLabelValues("published").Inc()
+}
+
+func (m *mon) EmoteJobStarted() {
+	m.emoteJobs.WithLabelValues("started").Inc()
+	m.emoteJobsActive.Inc()
+}
+
+func (m *mon) EmoteJobFinished(success bool) {
+	if success {
+		m.emoteJobs.WithLabelValues("completed").Inc()
+	} else {
+		m.emoteJobs.WithLabelValues("failed").Inc()
+	}
+	m.emoteJobsActive.Dec()
+}
+
+func (m *mon) SetDependency(name string, up bool, latency time.Duration) {
+	v := 0.0
+	if up {
+		v = 1
+	}
+	m.dependencyUp.WithLabelValues(name).Set(v)
+	m.dependencyLatency.WithLabelValues(name).Set(latency.Seconds())
+}
@@ -0,0 +1,75 @@
+package monitoring
+
+import (
+	"net"
+	"time"
+
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/health"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// How often the health of dependencies is refreshed for the metrics
+const HEALTH_INTERVAL = time.Second * 15
+
+// Start: begin serving metrics on the configured monitoring listener
+//
+// This is kept apart from the public http server so that metrics are never exposed to clients
+func Start(gCtx global.Context) (<-chan struct{}, error) {
+	ln, err := net.Listen(gCtx.Config().Monitoring.Type, gCtx.Config().Monitoring.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := fasthttpadaptor.NewFastHTTPHandler(promhttp.HandlerFor(gCtx.Inst().Prometheus.Registry(), promhttp.HandlerOpts{
+		ErrorLog: logrus.StandardLogger(),
+	}))
+
+	srv := &fasthttp.Server{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			switch string(ctx.Path()) {
+			case "/metrics":
+				metrics(ctx)
+			default:
+				ctx.SetStatusCode(fasthttp.StatusNotFound)
+			}
+		},
+		ReadTimeout:  time.Second * 10,
+		WriteTimeout: time.Second * 10,
+	}
+
+	// Gracefully exit when the global context is canceled
+	go func() {
+		<-gCtx.Done()
+		_ = srv.Shutdown()
+	}()
+
+	// Keep the health of dependencies up to date, without scrapes waiting on it
+	go func() {
+		tick := time.NewTicker(HEALTH_INTERVAL)
+		defer tick.Stop()
+
+		for {
+			_ = health.Check(gCtx, gCtx, time.Second*1)
+
+			select {
+			case <-tick.C:
+			case <-gCtx.Done():
+				return
+			}
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := srv.Serve(ln); err != nil {
+			logrus.WithError(err).Error("monitoring, failed to serve metrics")
+		}
+	}()
+
+	return done, nil
+}
@@ -0,0 +1,51 @@
+package panics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/Common/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const CollectionName mongo.CollectionName = "errors"
+
+// Report: a record of a panic which occured while serving a request
+type Report struct {
+	// the error ID returned to the client
+	ID primitive.ObjectID `json:"id" bson:"_id"`
+	// the ID of the request which caused the panic
+	RequestID string `json:"request_id" bson:"request_id"`
+	// the node which served the request
+	NodeName string `json:"node_name" bson:"node_name"`
+	// the request method
+	Method string `json:"method" bson:"method"`
+	// the route that handled the request, as registered. Empty if the panic occured outside of a route
+	Route string `json:"route,omitempty" bson:"route,omitempty"`
+	// the requested path
+	Path string `json:"path" bson:"path"`
+	// the query string of the request
+	Query string `json:"query,omitempty" bson:"query,omitempty"`
+	// the user who made the request, if authenticated
+	ActorID primitive.ObjectID `json:"actor_id" bson:"actor_id,omitempty"`
+	// the address of the client
+	RemoteIP string `json:"remote_ip" bson:"remote_ip"`
+	// the value passed to panic()
+	Message string `json:"message" bson:"message"`
+	// the stack trace of the goroutine which panicked
+	Stack string `json:"stack" bson:"stack"`
+	// the time at which the panic occured
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
+}
+
+// Message: format a recovered value into a message
+func Message(v interface{}) string {
+	switch x := v.(type) {
+	case error:
+		return x.Error()
+	case string:
+		return x
+	default:
+		return fmt.Sprintf("%+v", x)
+	}
+}
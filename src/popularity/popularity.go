@@ -0,0 +1,131 @@
+package popularity
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongod "go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// The field of emotes holding their popularity; the amount of emote sets they are in
+	Field = "popularity"
+	// The field of emotes holding when their popularity was last counted
+	UpdatedField = "popularity_at"
+
+	// How often popularity is counted again
+	Interval = time.Minute * 10
+	// The key held by the node counting popularity, so that only one node does so per interval
+	RedisKey = "rest:emotes:popularity"
+
+	// How many emotes are updated per write
+	batchSize = 500
+)
+
+// Watch: keep the popularity of emotes up to date, until the context is done
+func Watch(gCtx global.Context) {
+	tick := time.NewTicker(Interval)
+	defer tick.Stop()
+
+	for {
+		if claim(gCtx) {
+			n, err := Update(gCtx, gCtx)
+			if err != nil {
+				logrus.WithError(err).Error("failed to update the popularity of emotes")
+			} else {
+				logrus.WithField("count", n).Debug("updated the popularity of emotes")
+			}
+		}
+
+		select {
+		case <-tick.C:
+		case <-gCtx.Done():
+			return
+		}
+	}
+}
+
+// claim: whether this node should count popularity for this interval. Always true without redis
+func claim(gCtx global.Context) bool {
+	if gCtx.Inst().Redis == nil {
+		return true
+	}
+
+	ok, err := gCtx.Inst().Redis.RawClient().SetNX(gCtx, RedisKey, gCtx.Config().NodeName, Interval-time.Second).Result()
+	if err != nil {
+		logrus.WithError(err).Error("redis, failed to claim the popularity update")
+		return false
+	}
+	return ok
+}
+
+// Update: count the emote sets each emote is in, and store it as the popularity of the emote.
+// Returns how many emotes are in at least one set
+//
+// Emotes no longer in any set, and those which were never counted, are set to 0
+func Update(ctx context.Context, gCtx global.Context) (int, error) {
+	start := time.Now()
+	emotes := gCtx.Inst().Mongo.Collection(mongo.CollectionNameEmotes)
+
+	cur, err := gCtx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$unwind", Value: "$emotes"}},
+		{{Key: "$group", Value: bson.M{"_id": "$emotes.id", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	n := 0
+	models := []mongod.WriteModel{}
+	write := func() error {
+		if len(models) == 0 {
+			return nil
+		}
+		_, err := emotes.BulkWrite(ctx, models)
+		models = models[:0]
+		return err
+	}
+	for cur.Next(ctx) {
+		c := struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Count int32              `bson:"count"`
+		}{}
+		if err = cur.Decode(&c); err != nil {
+			return n, err
+		}
+
+		models = append(models, mongod.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": c.ID}).
+			SetUpdate(bson.M{"$set": bson.M{Field: c.Count, UpdatedField: start}}),
+		)
+		n++
+		if len(models) == batchSize {
+			if err = write(); err != nil {
+				return n, err
+			}
+		}
+	}
+	if err = cur.Err(); err != nil {
+		return n, err
+	}
+	if err = write(); err != nil {
+		return n, err
+	}
+
+	if _, err = emotes.UpdateMany(ctx, bson.M{"$or": bson.A{
+		bson.M{UpdatedField: bson.M{"$exists": false}},
+		bson.M{UpdatedField: bson.M{"$lt": start}, Field: bson.M{"$gt": 0}},
+	}}, bson.M{
+		"$set": bson.M{Field: int32(0), UpdatedField: start},
+	}); err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
@@ -0,0 +1,99 @@
+package popularity
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/fakes"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUpdate(t *testing.T) {
+	gCtx, err := fakes.NewContext(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+
+	ids := map[string]primitive.ObjectID{}
+	for _, name := range []string{"twice", "once", "never counted", "removed"} {
+		ids[name] = primitive.NewObjectID()
+	}
+	_ = mgo.Seed(mongo.CollectionNameEmotes,
+		bson.M{"_id": ids["twice"], "name": "twice"},
+		bson.M{"_id": ids["once"], "name": "once", Field: int32(7)},
+		bson.M{"_id": ids["never counted"], "name": "never counted"},
+		bson.M{"_id": ids["removed"], "name": "removed", Field: int32(5)},
+	)
+	set := func(emotes ...string) *structures.EmoteSet {
+		s := &structures.EmoteSet{ID: primitive.NewObjectID(), Emotes: []*structures.ActiveEmote{}}
+		for _, e := range emotes {
+			s.Emotes = append(s.Emotes, &structures.ActiveEmote{ID: ids[e]})
+		}
+		return s
+	}
+	_ = mgo.Seed(mongo.CollectionNameEmoteSets, set("twice", "once"), set("twice"), set())
+
+	tests := []struct {
+		name string
+		// a change made before counting
+		change func() error
+		// the amount of emotes expected to be in a set, and the expected popularity of each
+		n    int
+		want map[string]int32
+	}{
+		{"counted", func() error { return nil }, 2, map[string]int32{"twice": 2, "once": 1, "never counted": 0, "removed": 0}},
+		{"removed from a set", func() error {
+			_, err := mgo.Collection(mongo.CollectionNameEmoteSets).UpdateMany(gCtx, bson.M{}, bson.M{
+				"$pull": bson.M{"emotes": bson.M{"id": ids["once"]}},
+			})
+			return err
+		}, 1, map[string]int32{"twice": 2, "once": 0, "never counted": 0, "removed": 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change(); err != nil {
+				t.Fatal(err)
+			}
+
+			n, err := Update(gCtx, gCtx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.n {
+				t.Errorf("expected %d emotes to be in a set, got %d", tt.n, n)
+			}
+
+			for name, want := range tt.want {
+				doc := bson.M{}
+				if err := mgo.Collection(mongo.CollectionNameEmotes).FindOne(gCtx, bson.M{"_id": ids[name]}).Decode(&doc); err != nil {
+					t.Fatal(err)
+				}
+				if doc[Field] != want {
+					t.Errorf("%s: expected a popularity of %d, got %v", name, want, doc[Field])
+				}
+				if _, ok := doc[UpdatedField]; !ok {
+					t.Errorf("%s: expected the time it was counted to be set", name)
+				}
+			}
+		})
+	}
+}
+
+func TestClaim(t *testing.T) {
+	gCtx, err := fakes.NewContext(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !claim(gCtx) {
+		t.Fatal("expected the first node to claim the update")
+	}
+	if claim(gCtx) {
+		t.Error("expected the update to be claimed once per interval")
+	}
+}
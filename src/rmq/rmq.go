@@ -79,13 +79,14 @@ func (r *RmqInstance) Subscribe(queue string) (<-chan amqp.Delivery, error) {
 	)
 }
 
-func (r *RmqInstance) Publish(queue string, contentType string, deliveryMode uint8, msg []byte) error {
+func (r *RmqInstance) Publish(queue string, contentType string, deliveryMode uint8, headers amqp.Table, msg []byte) error {
 	return r.chRmq.Publish(
 		"",    // exchange
 		queue, // queue name
 		false, // mandatory
 		false, // immediate
 		amqp.Publishing{
+			Headers:      headers,
 			ContentType:  contentType,
 			DeliveryMode: deliveryMode,
 			Timestamp:    time.Now(),
@@ -95,6 +96,15 @@ func (r *RmqInstance) Publish(queue string, contentType string, deliveryMode uin
 	)
 }
 
+// QueueDepth: get the amount of messages waiting in a queue
+func (r *RmqInstance) QueueDepth(queue string) (int, error) {
+	q, err := r.chRmq.QueueInspect(queue)
+	if err != nil {
+		return 0, err
+	}
+	return q.Messages, nil
+}
+
 func (r *RmqInstance) Shutdown() {
 	_ = r.rmq.Close()
 }
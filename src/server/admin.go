@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/SevenTV/REST/src/configure"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/admin"
+	"github.com/fasthttp/router"
+)
+
+// StartAdmin: set up the admin server and begin listening on the admin listener
+//
+// The operator endpoints are added to a router of their own, so none of them can be reached on the public listener
+func (s *HttpServer) StartAdmin(gCtx global.Context) (<-chan struct{}, error) {
+	cfg := gCtx.Config().Admin
+
+	var err error
+	switch cfg.Type {
+	case "unix":
+		s.listener, err = listenUnix(cfg.URI)
+	case "tcp":
+		if !configure.IsLoopback(cfg.URI) {
+			return nil, fmt.Errorf("admin listener %s is not a loopback address", cfg.URI)
+		}
+		s.listener, err = net.Listen(cfg.Type, cfg.URI)
+	default:
+		return nil, fmt.Errorf("unknown admin listener type %q", cfg.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.gCtx = gCtx
+	s.admin = true
+	s.router = router.New()
+	s.router.SaveMatchedRoutePath = true
+
+	s.SetupHandlers()
+	for _, r := range admin.Routes(gCtx) {
+		s.traverseRoutes(r, "")
+	}
+
+	return s.serve(), nil
+}
+
+// listenUnix: listen on a unix socket which only the user running the node may connect to
+//
+// The socket is created in a private directory and moved into place once its mode is set,
+// so that it can never be reached with the default permissions
+func listenUnix(path string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".admin-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "admin.sock")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	l.SetUnlinkOnClose(false)
+
+	// A node which did not shut down cleanly leaves its socket behind, which is replaced
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket == 0 {
+		_ = l.Close()
+		return nil, fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if err = os.Chmod(tmp, 0600); err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+
+	return &unixListener{UnixListener: l, path: path}, nil
+}
+
+// unixListener: a listener which removes its socket once closed, from the path it was moved to
+type unixListener struct {
+	*net.UnixListener
+	path string
+}
+
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	_ = os.Remove(l.path)
+	return err
+}
+
+func (l *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
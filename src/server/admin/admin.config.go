@@ -0,0 +1,35 @@
+package admin
+
+import (
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+)
+
+type reloadConfig struct {
+	Ctx global.Context
+}
+
+func (r *reloadConfig) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/config/reload",
+		Method: rest.POST,
+	}
+}
+
+// Reload Config: read the config file again and apply it on this node
+func (r *reloadConfig) Handler(ctx *rest.Ctx) rest.APIError {
+	restart, err := global.ReloadConfig(r.Ctx)
+	if err != nil {
+		return errors.ErrValidationRejected().SetDetail(err.Error())
+	}
+
+	return ctx.JSON(rest.OK, &reloadConfigResult{
+		RestartRequired: restart,
+	})
+}
+
+type reloadConfigResult struct {
+	// The changed settings which only take effect after a restart
+	RestartRequired []string `json:"restart_required"`
+}
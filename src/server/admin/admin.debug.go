@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"net/http/pprof"
+	rpprof "runtime/pprof"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+type goroutines struct {
+	Ctx global.Context
+}
+
+func (r *goroutines) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/debug/goroutines",
+		Method: rest.GET,
+	}
+}
+
+// Goroutines: dump the stacks of every goroutine, in the same format as an unrecovered panic
+func (r *goroutines) Handler(ctx *rest.Ctx) rest.APIError {
+	ctx.SetContentType("text/plain; charset=utf-8")
+	if err := rpprof.Lookup("goroutine").WriteTo(ctx, 2); err != nil {
+		ctx.Log().WithError(err).Error("failed to dump goroutines")
+		return errors.ErrInternalServerError()
+	}
+
+	return nil
+}
+
+type profiles struct {
+	Ctx global.Context
+}
+
+func (r *profiles) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/debug/pprof/{profile:*}",
+		Method: rest.GET,
+	}
+}
+
+var (
+	pprofIndex   = fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Index)
+	pprofHandler = map[string]func(ctx *fasthttp.RequestCtx){
+		"cmdline": fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Cmdline),
+		"profile": fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Profile),
+		"symbol":  fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Symbol),
+		"trace":   fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Trace),
+	}
+)
+
+// Profiles: the pprof endpoints, as served by net/http/pprof under /debug/pprof/
+func (r *profiles) Handler(ctx *rest.Ctx) rest.APIError {
+	h, ok := pprofHandler[ctx.Param("profile")]
+	if !ok {
+		// The index serves the named runtime profiles (heap, goroutine, etc.)
+		h = pprofIndex
+	}
+	h(ctx.RequestCtx)
+
+	return nil
+}
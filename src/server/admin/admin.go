@@ -0,0 +1,21 @@
+package admin
+
+import (
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+)
+
+// Routes: the operator endpoints, served only on the admin listener
+//
+// They carry no authentication of their own: access to the listener (i.e the permissions of its unix socket) is what guards them
+func Routes(gCtx global.Context) []rest.Route {
+	return []rest.Route{
+		&goroutines{gCtx},
+		&profiles{gCtx},
+		&requeue{gCtx},
+		&getMaintenance{gCtx},
+		&setMaintenance{gCtx},
+		&clearMaintenance{gCtx},
+		&reloadConfig{gCtx},
+	}
+}
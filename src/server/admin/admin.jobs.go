@@ -0,0 +1,117 @@
+package admin
+
+import (
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v3/routes/emotes"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The most jobs re-queued by a single request, when finding them by age
+const REQUEUE_MAX_JOBS = 1000
+
+type requeue struct {
+	Ctx global.Context
+}
+
+func (r *requeue) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/jobs/requeue",
+		Method: rest.POST,
+	}
+}
+
+// Re-queue Jobs: send emote versions to the processor again, by the IDs of the versions,
+// or else every version which has been pending or processing for longer than older_than seconds (default 30 minutes)
+func (r *requeue) Handler(ctx *rest.Ctx) rest.APIError {
+	if r.Ctx.Inst().Rmq == nil {
+		return errors.ErrMissingInternalDependency().SetDetail("Emote Processing Service Unavailable")
+	}
+
+	args := &requeueArgs{}
+	if err := ctx.Bind(args); err != nil {
+		return err
+	}
+
+	ids := args.IDs
+	if len(ids) == 0 {
+		olderThan := time.Minute * 30
+		if args.OlderThan > 0 {
+			olderThan = time.Duration(args.OlderThan) * time.Second
+		}
+
+		var err error
+		if ids, err = r.stuck(ctx, time.Now().Add(-olderThan)); err != nil {
+			ctx.Log().WithError(err).Error("mongo, failed to find unprocessed emotes")
+			return errors.ErrInternalServerError()
+		}
+	}
+
+	res := &requeueResponse{
+		Requeued: []primitive.ObjectID{},
+		Failed:   map[string]string{},
+	}
+	for _, id := range ids {
+		key, err := emotes.FindInternalFile(ctx, r.Ctx, id)
+		if err == nil {
+			err = emotes.PublishJob(ctx, r.Ctx, id, key, ctx.RequestID())
+		}
+		if err != nil {
+			res.Failed[id.Hex()] = err.Error()
+			continue
+		}
+		res.Requeued = append(res.Requeued, id)
+	}
+	ctx.Log().WithField("requeued", len(res.Requeued)).WithField("failed", len(res.Failed)).Info("re-queued emote processing jobs")
+
+	return ctx.JSON(rest.OK, res)
+}
+
+// stuck: find the IDs of emote versions which were created before a time and have not finished processing
+func (r *requeue) stuck(ctx *rest.Ctx, before time.Time) ([]primitive.ObjectID, error) {
+	lifecycles := bson.A{structures.EmoteLifecyclePending, structures.EmoteLifecycleProcessing}
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Find(ctx, bson.M{
+		"versions": bson.M{"$elemMatch": bson.M{
+			"state.lifecycle": bson.M{"$in": lifecycles},
+			"timestamp":       bson.M{"$lt": before},
+		}},
+	}, options.Find().SetProjection(bson.M{"versions": 1}).SetLimit(REQUEUE_MAX_JOBS))
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*structures.Emote{}
+	if err = cur.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	ids := []primitive.ObjectID{}
+	for _, e := range result {
+		for _, v := range e.Versions {
+			if len(ids) == REQUEUE_MAX_JOBS {
+				return ids, nil
+			}
+			if (v.State.Lifecycle == structures.EmoteLifecyclePending || v.State.Lifecycle == structures.EmoteLifecycleProcessing) && v.Timestamp.Before(before) {
+				ids = append(ids, v.ID)
+			}
+		}
+	}
+	return ids, nil
+}
+
+type requeueArgs struct {
+	IDs       []primitive.ObjectID `json:"ids" validate:"max=1000"`
+	OlderThan int                  `json:"older_than" validate:"min=0"`
+}
+
+type requeueResponse struct {
+	Requeued []primitive.ObjectID `json:"requeued"`
+	Failed   map[string]string    `json:"failed"`
+}
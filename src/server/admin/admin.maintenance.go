@@ -0,0 +1,86 @@
+package admin
+
+import (
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/maintenance"
+	"github.com/SevenTV/REST/src/server/rest"
+)
+
+type getMaintenance struct {
+	Ctx global.Context
+}
+
+func (r *getMaintenance) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/maintenance",
+		Method: rest.GET,
+	}
+}
+
+// Get Maintenance Mode: the current mode of the API
+func (r *getMaintenance) Handler(ctx *rest.Ctx) rest.APIError {
+	state := maintenance.Current(r.Ctx)
+	return ctx.JSON(rest.OK, &state)
+}
+
+type setMaintenance struct {
+	Ctx global.Context
+}
+
+func (r *setMaintenance) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/maintenance",
+		Method: rest.PUT,
+	}
+}
+
+// Set Maintenance Mode: change the mode of the API on every node, overriding the configured mode
+func (r *setMaintenance) Handler(ctx *rest.Ctx) rest.APIError {
+	args := &setMaintenanceArgs{}
+	if err := ctx.BindBody(args); err != nil {
+		return err
+	}
+
+	if err := maintenance.Set(ctx, r.Ctx, maintenance.State{
+		Mode:       maintenance.Mode(args.Mode),
+		Message:    args.Message,
+		RetryAfter: args.RetryAfter,
+	}); err != nil {
+		ctx.Log().WithError(err).Error("failed to set maintenance mode")
+		return errors.ErrInternalServerError()
+	}
+	ctx.Log().WithField("mode", args.Mode).Warn("maintenance mode set")
+
+	state := maintenance.Current(r.Ctx)
+	return ctx.JSON(rest.OK, &state)
+}
+
+type setMaintenanceArgs struct {
+	Mode       string `json:"mode" validate:"required,oneof=off|read-only|maintenance"`
+	Message    string `json:"message" validate:"max=500"`
+	RetryAfter int    `json:"retry_after" validate:"min=0"`
+}
+
+type clearMaintenance struct {
+	Ctx global.Context
+}
+
+func (r *clearMaintenance) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/maintenance",
+		Method: rest.DELETE,
+	}
+}
+
+// Clear Maintenance Mode: remove the mode set at runtime, returning every node to the configured mode
+func (r *clearMaintenance) Handler(ctx *rest.Ctx) rest.APIError {
+	if err := maintenance.Clear(ctx, r.Ctx); err != nil {
+		ctx.Log().WithError(err).Error("failed to clear maintenance mode")
+		return errors.ErrInternalServerError()
+	}
+	ctx.Log().Warn("maintenance mode cleared")
+
+	state := maintenance.Current(r.Ctx)
+	return ctx.JSON(rest.OK, &state)
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/valyala/fasthttp"
+)
+
+func TestStartAdmin(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  string
+		uri  string
+		// a file to leave at the socket path beforehand, as a socket or not
+		leftover string
+		ok       bool
+	}{
+		{"socket", "unix", "admin.sock", "", true},
+		{"stale socket is replaced", "unix", "admin.sock", "socket", true},
+		{"other file is kept", "unix", "admin.sock", "file", false},
+		{"loopback", "tcp", "127.0.0.1:0", "", true},
+		{"ipv6 loopback", "tcp", "[::1]:0", "", true},
+		{"localhost", "tcp", "localhost:0", "", true},
+		{"every interface", "tcp", ":0", "", false},
+		{"unspecified address", "tcp", "0.0.0.0:0", "", false},
+		{"unknown type", "udp", "127.0.0.1:0", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.uri == "[::1]:0" {
+				if l, err := net.Listen("tcp", tt.uri); err != nil {
+					t.Skip("ipv6 is not available")
+				} else {
+					l.Close()
+				}
+			}
+
+			config := fakes.NewConfig()
+			config.Admin.Enabled = true
+			config.Admin.Type = tt.typ
+			config.Admin.URI = tt.uri
+			if tt.typ == "unix" {
+				config.Admin.URI = filepath.Join(t.TempDir(), tt.uri)
+			}
+			switch tt.leftover {
+			case "socket":
+				l, err := net.ListenUnix("unix", &net.UnixAddr{Name: config.Admin.URI, Net: "unix"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				l.SetUnlinkOnClose(false)
+				l.Close()
+			case "file":
+				if err := os.WriteFile(config.Admin.URI, []byte("x"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			gCtx, err := fakes.NewContext(context.Background(), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := New()
+			_, err = srv.StartAdmin(gCtx)
+			if !tt.ok {
+				if err == nil {
+					_ = srv.Shutdown(context.Background())
+					t.Fatal("expected the admin server not to start")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := &fasthttp.Client{Dial: func(string) (net.Conn, error) {
+				return net.Dial(srv.listener.Addr().Network(), srv.listener.Addr().String())
+			}}
+			if tt.typ == "unix" {
+				fi, err := os.Stat(config.Admin.URI)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if fi.Mode()&os.ModeSocket == 0 || fi.Mode().Perm() != 0600 {
+					t.Errorf("expected a socket only its owner can use, got %s", fi.Mode())
+				}
+				if entries, _ := os.ReadDir(filepath.Dir(config.Admin.URI)); len(entries) != 1 {
+					t.Errorf("expected only the socket to be left in its directory, got %d entries", len(entries))
+				}
+			}
+
+			status, _, err := client.Get(nil, "http://admin/maintenance")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status != 200 {
+				t.Errorf("expected the admin endpoints to be served, got status %d", status)
+			}
+
+			if err := srv.Shutdown(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(config.Admin.URI); tt.typ == "unix" && !os.IsNotExist(err) {
+				t.Errorf("expected the socket to be removed on shutdown, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net"
+
+	"github.com/SevenTV/Common/auth"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/valyala/fasthttp"
+)
+
+// Harness: drives requests through the full handler chain (routing, middleware and completion hooks)
+// without listening on a socket. Pair it with the fakes package to exercise routes in-process
+type Harness struct {
+	srv *HttpServer
+}
+
+var harnessAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 50000}
+
+// NewHarness: set up the routes of every API version against a global context
+func NewHarness(gCtx global.Context) *Harness {
+	srv := &HttpServer{}
+	srv.setup(gCtx)
+
+	return &Harness{srv: srv}
+}
+
+// Do: handle a request and return the response
+func (h *Harness) Do(req *fasthttp.Request) *fasthttp.Response {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(req, harnessAddr, nil)
+
+	h.srv.handle(ctx)
+
+	res := &fasthttp.Response{}
+	ctx.Response.CopyTo(res)
+	return res
+}
+
+// Request: handle a request built from a method, URI, body and headers
+func (h *Harness) Request(method, uri string, body []byte, headers map[string]string) *fasthttp.Response {
+	req := &fasthttp.Request{}
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	req.Header.SetHost("localhost")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if body != nil {
+		req.SetBody(body)
+		if len(req.Header.ContentType()) == 0 {
+			req.Header.SetContentType("application/json")
+		}
+	}
+
+	return h.Do(req)
+}
+
+// Token: sign an access token for a user, for use as a Bearer token in the Authorization header
+func (h *Harness) Token(user *structures.User) (string, error) {
+	return auth.SignJWT(h.srv.gCtx.Config().Credentials.JWTSecret, &auth.JWTClaimUser{
+		UserID:       user.ID.Hex(),
+		TokenVersion: user.TokenVersion,
+	})
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/utils"
+	"github.com/SevenTV/REST/src/maintenance"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+// rejectMaintenance: respond with the maintenance notice if the current mode does not allow the request
+//
+// The health probes and the API root, which shows the mode, are always served
+func (s *HttpServer) rejectMaintenance(ctx *fasthttp.RequestCtx) bool {
+	path := utils.B2S(ctx.Path())
+	if strings.HasPrefix(path, "/health/") || (ctx.IsGet() && path == "/v3") {
+		return false
+	}
+
+	state := maintenance.Current(s.gCtx)
+
+	var err rest.APIError
+	switch state.Mode {
+	case maintenance.ModeReadOnly:
+		if ctx.IsGet() || ctx.IsHead() {
+			return false
+		}
+		err = rest.ErrReadOnly()
+	case maintenance.ModeMaintenance:
+		body, contentType, er := maintenance.Notice(s.gCtx)
+		if er != nil {
+			logrus.WithError(er).Error("maintenance, failed to read the notice")
+		}
+		if body != nil {
+			ctx.Response.Header.Set("Retry-After", strconv.Itoa(state.RetryAfter))
+			ctx.SetStatusCode(int(rest.ServiceUnavailable))
+			ctx.SetContentType(contentType)
+			ctx.SetBody(body)
+			return true
+		}
+		err = rest.ErrMaintenance()
+	default:
+		return false
+	}
+	if state.Message != "" {
+		err = err.SetDetail(state.Message)
+	}
+	err = err.SetFields(errors.Fields{"mode": state.Mode})
+
+	ctx.Response.Header.Set("Retry-After", strconv.Itoa(state.RetryAfter))
+	writeError(ctx, rest.ServiceUnavailable, err)
+	return true
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/server/rest"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	dir := t.TempDir()
+	notices := map[string]string{
+		"notice.html": "<h1>Down for maintenance</h1>",
+		"notice.json": `{"message":"down for maintenance"}`,
+	}
+	for name, body := range notices {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		mode   string
+		notice string
+		method string
+		uri    string
+		// the expected status, and the content type and body when a notice is served
+		status      int
+		contentType string
+		body        string
+		// the code of the error served instead of a notice
+		code int
+	}{
+		{"off", "off", "", "POST", "/v3/ping", 200, "", "", 0},
+		{"read-only allows reads", "read-only", "", "GET", "/v3/ping", 200, "", "", 0},
+		{"read-only rejects writes", "read-only", "", "POST", "/v3/ping", 503, "", "", rest.ErrReadOnly().Code()},
+		{"read-only does not serve the notice", "read-only", "notice.html", "POST", "/v3/ping", 503, "", "", rest.ErrReadOnly().Code()},
+		{"maintenance", "maintenance", "", "GET", "/v3/ping", 503, "", "", rest.ErrMaintenance().Code()},
+		{"maintenance with an html notice", "maintenance", "notice.html", "GET", "/v3/ping", 503, "text/html; charset=utf-8", notices["notice.html"], 0},
+		{"maintenance with a json notice", "maintenance", "notice.json", "POST", "/v3/ping", 503, "application/json", notices["notice.json"], 0},
+		{"missing notice", "maintenance", "missing.html", "GET", "/v3/ping", 503, "", "", rest.ErrMaintenance().Code()},
+		{"health probes are served", "maintenance", "notice.html", "GET", "/health/ping", 200, "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := fakes.NewConfig()
+			config.Maintenance.Mode = tt.mode
+			config.Maintenance.RetryAfter = 60
+			if tt.notice != "" {
+				config.Maintenance.Notice = filepath.Join(dir, tt.notice)
+			}
+			gCtx, err := fakes.NewContext(context.Background(), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pong := func(ctx *rest.Ctx) rest.APIError {
+				return ctx.JSON(rest.OK, "pong")
+			}
+			h := newTestHarness(gCtx,
+				&testRoute{config: rest.RouteConfig{URI: "/v3/ping", Method: rest.GET}, handler: pong},
+				&testRoute{config: rest.RouteConfig{URI: "/v3/ping", Method: rest.POST}, handler: pong},
+				&testRoute{config: rest.RouteConfig{URI: "/health/ping", Method: rest.GET}, handler: pong},
+			)
+
+			res := h.Request(tt.method, tt.uri, nil, nil)
+			if res.StatusCode() != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, res.StatusCode(), res.Body())
+			}
+			if tt.status != 503 {
+				return
+			}
+			if got := string(res.Header.Peek("Retry-After")); got != "60" {
+				t.Errorf("expected Retry-After 60, got %q", got)
+			}
+
+			if tt.body != "" {
+				if got := string(res.Header.ContentType()); got != tt.contentType {
+					t.Errorf("expected content type %q, got %q", tt.contentType, got)
+				}
+				if string(res.Body()) != tt.body {
+					t.Errorf("expected the notice, got %s", res.Body())
+				}
+				return
+			}
+
+			body := &rest.APIErrorResponse{}
+			if err := json.Unmarshal(res.Body(), body); err != nil {
+				t.Fatal(err)
+			}
+			if body.ErrorCode != tt.code {
+				t.Errorf("expected error %d, got %d", tt.code, body.ErrorCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"runtime/debug"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/utils"
+	"github.com/SevenTV/REST/src/panics"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// recoverPanic: report a recovered panic and get the error to send to the client in its place
+//
+// The client only receives an opaque error ID. The panic message, stack and request metadata are logged,
+// and stored in the errors collection when enabled, where they can be looked up with that ID
+func (s *HttpServer) recoverPanic(ctx *fasthttp.RequestCtx, v interface{}) rest.APIError {
+	report := &panics.Report{
+		ID:        primitive.NewObjectIDFromTimestamp(time.Now()),
+		RequestID: rest.RequestID(ctx),
+		NodeName:  s.gCtx.Config().NodeName,
+		Method:    string(ctx.Method()),
+		Path:      string(ctx.Path()),
+		Query:     string(ctx.QueryArgs().QueryString()),
+		RemoteIP:  ctx.RemoteIP().String(),
+		Message:   panics.Message(v),
+		Stack:     string(debug.Stack()),
+	}
+	report.Timestamp = report.ID.Timestamp()
+
+	rctx := &rest.Ctx{RequestCtx: ctx}
+	report.Route = rctx.Route()
+	if actor, ok := rctx.GetActor(); ok {
+		report.ActorID = actor.ID
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"error_id":   report.ID.Hex(),
+		"request_id": report.RequestID,
+		"method":     report.Method,
+		"route":      report.Route,
+		"path":       report.Path,
+		"actor_id":   utils.Ternary(report.ActorID.IsZero(), "", report.ActorID.Hex()),
+		"remote_ip":  report.RemoteIP,
+	}).Errorf("panic occured: %s\n%s", report.Message, report.Stack)
+
+	if s.gCtx.Config().Http.ErrorReports && s.gCtx.Inst().Mongo != nil {
+		lctx, cancel := context.WithTimeout(s.gCtx, time.Second*5)
+		defer cancel()
+
+		if _, err := s.gCtx.Inst().Mongo.Collection(panics.CollectionName).InsertOne(lctx, report); err != nil {
+			logrus.WithError(err).WithField("error_id", report.ID.Hex()).Error("mongo, failed to store error report")
+		}
+	}
+
+	return errors.ErrInternalServerError().SetFields(errors.Fields{
+		"error_id": report.ID.Hex(),
+	})
+}
+
+// writeError: format an error into the standard API error response
+func writeError(ctx *fasthttp.RequestCtx, status rest.HttpStatusCode, err rest.APIError) {
+	b, _ := json.Marshal(&rest.APIErrorResponse{
+		Status:     status.String(),
+		StatusCode: status,
+		Error:      err.Message(),
+		ErrorCode:  err.Code(),
+		Details:    err.GetFields(),
+		RequestID:  rest.RequestID(ctx),
+	})
+
+	ctx.SetStatusCode(int(status))
+	ctx.SetContentType("application/json")
+	ctx.SetBody(b)
+}
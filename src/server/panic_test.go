@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/panics"
+	"github.com/SevenTV/REST/src/server/rest"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPanics(t *testing.T) {
+	const secret = "mongodb://user:hunter2@db"
+
+	tests := []struct {
+		name string
+		// the value to panic with, and where
+		value      interface{}
+		middleware bool
+		reports    bool
+	}{
+		{"string in the handler", secret, false, true},
+		{"error in the handler", fmt.Errorf("dial %s: refused", secret), false, true},
+		{"value in a middleware", struct{ URI string }{secret}, true, true},
+		{"reports disabled", secret, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := fakes.NewConfig()
+			config.Http.ErrorReports = tt.reports
+			gCtx, err := fakes.NewContext(context.Background(), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			completed := make(chan rest.RequestResult, 1)
+			boom := func(ctx *rest.Ctx) rest.APIError {
+				panic(tt.value)
+			}
+			route := &testRoute{
+				config: rest.RouteConfig{
+					URI:    "/boom",
+					Method: rest.GET,
+					Middleware: []rest.Middleware{func(ctx *rest.Ctx) rest.APIError {
+						ctx.OnComplete(func(ctx *rest.Ctx, res rest.RequestResult) {
+							completed <- res
+						})
+						return nil
+					}},
+				},
+				handler: func(ctx *rest.Ctx) rest.APIError {
+					return ctx.JSON(rest.OK, "ok")
+				},
+			}
+			if tt.middleware {
+				route.config.Middleware = append(route.config.Middleware, boom)
+			} else {
+				route.handler = boom
+			}
+
+			h := newTestHarness(gCtx, route)
+			res := h.Request("GET", "/boom?q=1", nil, nil)
+			if res.StatusCode() != 500 {
+				t.Fatalf("expected status 500, got %d", res.StatusCode())
+			}
+			if strings.Contains(string(res.Body()), "hunter2") {
+				t.Fatalf("the panic message was sent to the client: %s", res.Body())
+			}
+
+			body := &rest.APIErrorResponse{}
+			if err := json.Unmarshal(res.Body(), body); err != nil {
+				t.Fatal(err)
+			}
+			errorID, _ := body.Details["error_id"].(string)
+			if len(body.Details) != 1 || !primitive.IsValidObjectID(errorID) {
+				t.Fatalf("expected only an error id in the details, got %v", body.Details)
+			}
+
+			// The request is still completed, with the error
+			select {
+			case r := <-completed:
+				if r.Status != rest.InternalServerError || r.Error == nil {
+					t.Errorf("expected the request to complete with a 500 error, got %+v", r)
+				}
+			default:
+				t.Error("completion hooks did not run")
+			}
+
+			// The details are kept in the report
+			docs := gCtx.Inst().Mongo.(*fakes.Mongo).Documents(panics.CollectionName)
+			if !tt.reports {
+				if len(docs) != 0 {
+					t.Errorf("expected no report to be stored, got %d", len(docs))
+				}
+				return
+			}
+			if len(docs) != 1 {
+				t.Fatalf("expected a report to be stored, got %d", len(docs))
+			}
+			report := &panics.Report{}
+			if err := bson.Unmarshal(docs[0], report); err != nil {
+				t.Fatal(err)
+			}
+			if report.ID.Hex() != errorID || report.Route != "/boom" || report.Query != "q=1" || report.Stack == "" {
+				t.Errorf("unexpected report %+v", report)
+			}
+			if !strings.Contains(report.Message, "hunter2") {
+				t.Errorf("expected the report to hold the panic message, got %q", report.Message)
+			}
+		})
+	}
+}
+
+func TestErrorReportLookup(t *testing.T) {
+	gCtx, err := fakes.NewContext(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+
+	admin := &structures.Role{ID: primitive.NewObjectID(), Allowed: structures.RolePermissionManageStack}
+	users := map[string]*structures.User{
+		"admin": {ID: primitive.NewObjectID(), RoleIDs: []primitive.ObjectID{admin.ID}},
+		"user":  {ID: primitive.NewObjectID(), RoleIDs: []primitive.ObjectID{}},
+	}
+	mgo.Seed(mongo.CollectionNameRoles, admin)
+	mgo.Seed(mongo.CollectionNameUsers, users["admin"], users["user"])
+
+	report := &panics.Report{ID: primitive.NewObjectID(), Message: "boom", Stack: "main.go:1"}
+	mgo.Seed(panics.CollectionName, report)
+
+	h := NewHarness(gCtx)
+	tests := []struct {
+		name   string
+		user   string
+		id     string
+		status int
+	}{
+		{"anonymous", "", report.ID.Hex(), 401},
+		{"without the permission", "user", report.ID.Hex(), 403},
+		{"found", "admin", report.ID.Hex(), 200},
+		{"unknown", "admin", primitive.NewObjectID().Hex(), 404},
+		{"malformed", "admin", "xyz", 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.user != "" {
+				tok, err := h.Token(users[tt.user])
+				if err != nil {
+					t.Fatal(err)
+				}
+				headers["Authorization"] = "Bearer " + tok
+			}
+
+			res := h.Request("GET", "/v3/errors/"+tt.id, nil, headers)
+			if res.StatusCode() != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, res.StatusCode(), res.Body())
+			}
+			if tt.status == 200 && !strings.Contains(string(res.Body()), "main.go:1") {
+				t.Errorf("expected the report, got %s", res.Body())
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package probes
+
+import (
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/health"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v3/middleware"
+)
+
+type details struct {
+	Ctx global.Context
+}
+
+func (r *details) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/health/details",
+		Method: rest.GET,
+		Middleware: []rest.Middleware{
+			middleware.Auth(r.Ctx),
+		},
+	}
+}
+
+// Health Details: the state of the node and each of its dependencies. Requires the manage stack permission
+func (r *details) Handler(ctx *rest.Ctx) rest.APIError {
+	actor, ok := ctx.GetActor()
+	if !ok {
+		return errors.ErrUnauthorized()
+	}
+	if !actor.HasPermission(structures.RolePermissionManageStack) {
+		return errors.ErrInsufficientPrivilege()
+	}
+	ctx.Response.Header.Set("Cache-Control", "no-store")
+
+	report := health.Check(ctx, r.Ctx, time.Second*1)
+	res := &detailsResponse{
+		Node:     r.Ctx.Config().NodeName,
+		Status:   report.Status(),
+		Ready:    health.Ready(),
+		Services: make(map[health.Service]serviceDetails, len(report)),
+		Queues:   map[string]int{},
+	}
+	if uptime, ok := r.Ctx.Value("uptime").(time.Time); ok {
+		res.Uptime = uptime.Format(time.RFC3339)
+	}
+
+	for svc, result := range report {
+		sd := serviceDetails{
+			Status:    result.Status,
+			Required:  svc.Required(),
+			LatencyMS: float64(result.Latency.Microseconds()) / 1000,
+		}
+		if result.Error != nil {
+			sd.Error = result.Error.Error()
+		}
+		if f := result.LastFailure; f != nil {
+			sd.LastError = f.Error
+			sd.LastErrorAt = f.At.Format(time.RFC3339)
+		}
+		res.Services[svc] = sd
+	}
+
+	// Queue depths, when the queues can be reached
+	if rmq := r.Ctx.Inst().Rmq; rmq != nil && report[health.ServiceRmq].Status == health.StatusOK {
+		cfg := r.Ctx.Config().Rmq
+		for _, q := range []string{cfg.JobQueueName, cfg.ResultQueueName, cfg.UpdateQueueName} {
+			n, err := rmq.QueueDepth(q)
+			if err != nil {
+				ctx.Log().WithError(err).WithField("queue", q).Warn("rmq, failed to inspect queue")
+				continue
+			}
+			res.Queues[q] = n
+		}
+	}
+
+	return ctx.JSON(rest.OK, res)
+}
+
+type detailsResponse struct {
+	Node     string                            `json:"node"`
+	Status   health.Status                     `json:"status"`
+	Ready    bool                              `json:"ready"`
+	Uptime   string                            `json:"uptime,omitempty"`
+	Services map[health.Service]serviceDetails `json:"services"`
+	Queues   map[string]int                    `json:"queues"`
+}
+
+type serviceDetails struct {
+	Status      health.Status `json:"status"`
+	Required    bool          `json:"required"`
+	LatencyMS   float64       `json:"latency_ms"`
+	Error       string        `json:"error,omitempty"`
+	LastError   string        `json:"last_error,omitempty"`
+	LastErrorAt string        `json:"last_error_at,omitempty"`
+}
@@ -0,0 +1,18 @@
+package probes
+
+import (
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+)
+
+// Routes: the health endpoints, served outside of the versioned API for load balancers and orchestrators to probe
+//
+// /health/live reports whether the process is up, /health/ready whether the node should be sent traffic,
+// and /health/details (which requires the manage stack permission) the state of every dependency
+func Routes(gCtx global.Context) []rest.Route {
+	return []rest.Route{
+		&live{gCtx},
+		&ready{gCtx},
+		&details{gCtx},
+	}
+}
@@ -0,0 +1,31 @@
+package probes
+
+import (
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/health"
+	"github.com/SevenTV/REST/src/server/rest"
+)
+
+type live struct {
+	Ctx global.Context
+}
+
+func (r *live) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/health/live",
+		Method: rest.GET,
+	}
+}
+
+// Liveness: the process is up and serving requests. Dependencies are not checked,
+// so that an outage of one of them does not get every node restarted
+func (r *live) Handler(ctx *rest.Ctx) rest.APIError {
+	ctx.Response.Header.Set("Cache-Control", "no-store")
+
+	return ctx.JSON(rest.OK, &statusResponse{Status: health.StatusOK})
+}
+
+type statusResponse struct {
+	Status health.Status `json:"status"`
+	Ready  *bool         `json:"ready,omitempty"`
+}
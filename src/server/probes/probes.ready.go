@@ -0,0 +1,39 @@
+package probes
+
+import (
+	"time"
+
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/health"
+	"github.com/SevenTV/REST/src/server/rest"
+)
+
+type ready struct {
+	Ctx global.Context
+}
+
+func (r *ready) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/health/ready",
+		Method: rest.GET,
+	}
+}
+
+// Readiness: the node has started, is not shutting down, and its required dependencies are reachable.
+// A node which is not ready responds with 503
+func (r *ready) Handler(ctx *rest.Ctx) rest.APIError {
+	ctx.Response.Header.Set("Cache-Control", "no-store")
+
+	status := health.StatusUnavailable
+	ok := health.Ready()
+	if ok {
+		status = health.Check(ctx, r.Ctx, time.Second*1).Status()
+		ok = status != health.StatusUnavailable
+	}
+
+	code := rest.OK
+	if !ok {
+		code = rest.ServiceUnavailable
+	}
+	return ctx.JSON(code, &statusResponse{Status: status, Ready: &ok})
+}
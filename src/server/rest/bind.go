@@ -0,0 +1,520 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Bind: decode the request's JSON body, query args and headers into dst, then validate it
+//
+// Body fields are mapped with the "json" tag, query args with the "query" tag and headers with the "header" tag.
+// The body is only decoded when the request has a JSON content type. See Validate for the rules that can be declared.
+func (c *Ctx) Bind(dst interface{}) APIError {
+	if err := c.decodeBody(dst); err != nil {
+		return err
+	}
+
+	fields := errors.Fields{}
+	decodeTagged(reflect.ValueOf(dst), "query", func(key string) []string {
+		a := c.QueryArgs().PeekMulti(key)
+		s := make([]string, len(a))
+		for i, b := range a {
+			s[i] = string(b)
+		}
+		return s
+	}, fields)
+	decodeTagged(reflect.ValueOf(dst), "header", func(key string) []string {
+		v := c.Request.Header.Peek(key)
+		if len(v) == 0 {
+			return nil
+		}
+		return []string{string(v)}
+	}, fields)
+
+	// Report fields which failed to decode along with those which failed validation
+	validateStruct(reflect.ValueOf(dst), "", fields)
+	if len(fields) > 0 {
+		return validationError(fields)
+	}
+	return nil
+}
+
+// BindBody: decode the request's JSON body into dst, then validate it
+func (c *Ctx) BindBody(dst interface{}) APIError {
+	if err := c.decodeBody(dst); err != nil {
+		return err
+	}
+
+	return Validate(dst)
+}
+
+// BindJSONHeader: decode a header whose value is a JSON document into dst, then validate it
+func (c *Ctx) BindJSONHeader(key string, dst interface{}) APIError {
+	v := c.Request.Header.Peek(key)
+	if len(v) == 0 {
+		return errors.ErrMissingRequiredField().SetFields(errors.Fields{"header": key})
+	}
+
+	if err := json.Unmarshal(v, dst); err != nil {
+		return errors.ErrInvalidRequest().SetDetail(fmt.Sprintf("Bad %s Header: %s", key, err.Error()))
+	}
+
+	return Validate(dst)
+}
+
+func (c *Ctx) decodeBody(dst interface{}) APIError {
+	if !strings.HasPrefix(utils.B2S(c.Request.Header.ContentType()), "application/json") {
+		return nil
+	}
+
+	b := c.Request.Body()
+	if len(b) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(b, dst); err != nil {
+		return errors.ErrInvalidRequest().SetDetail(fmt.Sprintf("Bad JSON Body: %s", err.Error()))
+	}
+
+	return nil
+}
+
+// decodeTagged: set the fields of v which carry the given tag from the values returned by peek
+func decodeTagged(v reflect.Value, tag string, peek func(key string) []string, fields errors.Fields) {
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := v.Field(i)
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+
+		key, ok := f.Tag.Lookup(tag)
+		if !ok {
+			if f.Type.Kind() == reflect.Struct && f.Type != timeType {
+				decodeTagged(fv, tag, peek, fields)
+			}
+			continue
+		}
+
+		values := peek(key)
+		if len(values) == 0 {
+			continue
+		}
+
+		if err := setFromStrings(fv, values); err != nil {
+			fields[key] = err.Error()
+		}
+	}
+}
+
+func setFromStrings(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Slice && v.Type() != objectIDType {
+		// A single value may hold a comma-separated list
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, val := range values {
+			if err := setFromString(s.Index(i), strings.TrimSpace(val)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+
+	return setFromString(v, strings.TrimSpace(values[0]))
+}
+
+func setFromString(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		ptr := reflect.New(v.Type().Elem())
+		if err := setFromString(ptr.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+
+	switch v.Type() {
+	case objectIDType:
+		id, err := primitive.ObjectIDFromHex(s)
+		if err != nil {
+			return fmt.Errorf("must be a valid object id")
+		}
+		v.Set(reflect.ValueOf(id))
+		return nil
+	case timeType:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("must be an RFC3339 timestamp")
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a positive integer")
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// Validate: check a struct against the rules declared in its "validate" tags
+//
+// Rules are separated by commas:
+//
+// required - the value must not be empty
+//
+// min=N, max=N - length of a string, value of a number, or count of a slice's non-empty items
+//
+// match=NAME - the string (or every item of a string slice) must match a pattern added with RegisterPattern
+//
+// oneof=A|B|C - the value (or every item of a slice) must be one of the listed values
+//
+// mask=N - a bitfield may only have bits set which are also set in N; either a number or a mask added with RegisterMask
+//
+// objectid - the string (or every item of a string slice) must be a valid hex object id
+//
+// All failing fields are returned together in a single ErrInvalidRequest
+func Validate(v interface{}) APIError {
+	fields := errors.Fields{}
+	validateStruct(reflect.ValueOf(v), "", fields)
+
+	if len(fields) > 0 {
+		return validationError(fields)
+	}
+	return nil
+}
+
+// RegisterPattern: make a regular expression available to the "match" validation rule
+func RegisterPattern(name string, re *regexp.Regexp) {
+	patternMx.Lock()
+	defer patternMx.Unlock()
+
+	patterns[name] = re
+}
+
+// RegisterMask: make a bitfield mask available to the "mask" validation rule by name
+func RegisterMask(name string, mask int64) {
+	patternMx.Lock()
+	defer patternMx.Unlock()
+
+	masks[name] = mask
+}
+
+func validationError(fields errors.Fields) APIError {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return errors.ErrInvalidRequest().
+		SetDetail(fmt.Sprintf("Bad Fields (%s)", strings.Join(keys, ", "))).
+		SetFields(fields)
+}
+
+func validateStruct(v reflect.Value, prefix string, fields errors.Fields) {
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		fv := v.Field(i)
+		name := prefix + fieldName(f)
+
+		if _, failed := fields[name]; failed {
+			continue
+		}
+
+		if tag, ok := f.Tag.Lookup("validate"); ok && tag != "" {
+			if msg := validateValue(fv, tag); msg != "" {
+				fields[name] = msg
+				continue
+			}
+		}
+
+		// Validate nested objects
+		nested := fv
+		if nested.Kind() == reflect.Ptr {
+			if nested.IsNil() {
+				continue
+			}
+			nested = nested.Elem()
+		}
+		if nested.Kind() == reflect.Struct && nested.Type() != timeType {
+			validateStruct(nested, name+".", fields)
+		}
+	}
+}
+
+func validateValue(v reflect.Value, tag string) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if hasRule(tag, "required") {
+				return "is required"
+			}
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+
+		var msg string
+		switch name {
+		case "required":
+			if isEmpty(v) {
+				msg = "is required"
+			}
+		case "min", "max":
+			msg = checkBound(v, name, arg)
+		case "match":
+			patternMx.RLock()
+			re, ok := patterns[arg]
+			patternMx.RUnlock()
+			if !ok {
+				panic(fmt.Sprintf("rest: unknown validation pattern %q", arg))
+			}
+			msg = eachString(v, func(s string) string {
+				if !re.MatchString(s) {
+					return fmt.Sprintf("'%s' does not match the expected format", s)
+				}
+				return ""
+			})
+		case "oneof":
+			allowed := strings.Split(arg, "|")
+			msg = eachScalar(v, func(s string) string {
+				if !utils.Contains(allowed, s) {
+					return fmt.Sprintf("'%s' must be one of %s", s, strings.Join(allowed, ", "))
+				}
+				return ""
+			})
+		case "mask":
+			patternMx.RLock()
+			mask, ok := masks[arg]
+			patternMx.RUnlock()
+			if !ok {
+				var err error
+				if mask, err = strconv.ParseInt(arg, 0, 64); err != nil {
+					panic(fmt.Sprintf("rest: unknown validation mask %q", arg))
+				}
+			}
+			switch v.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				if v.Int()&^mask != 0 {
+					msg = fmt.Sprintf("has unknown bits set (allowed: %d)", mask)
+				}
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				if int64(v.Uint())&^mask != 0 {
+					msg = fmt.Sprintf("has unknown bits set (allowed: %d)", mask)
+				}
+			}
+		case "objectid":
+			msg = eachString(v, func(s string) string {
+				if !primitive.IsValidObjectID(s) {
+					return fmt.Sprintf("'%s' is not a valid object id", s)
+				}
+				return ""
+			})
+		default:
+			panic(fmt.Sprintf("rest: unknown validation rule %q", name))
+		}
+
+		if msg != "" {
+			return msg
+		}
+	}
+
+	return ""
+}
+
+func checkBound(v reflect.Value, rule string, arg string) string {
+	n, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		panic(fmt.Sprintf("rest: bad validation bound %q", arg))
+	}
+
+	var (
+		got  float64
+		unit string
+	)
+	switch v.Kind() {
+	case reflect.String:
+		got, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Array:
+		if v.Type() == objectIDType {
+			return ""
+		}
+		got, unit = float64(countItems(v)), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		got = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		got = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		got = v.Float()
+	default:
+		return ""
+	}
+
+	if rule == "min" && got < n {
+		return fmt.Sprintf("must be at least %s%s", arg, unit)
+	}
+	if rule == "max" && got > n {
+		return fmt.Sprintf("must be at most %s%s", arg, unit)
+	}
+	return ""
+}
+
+// eachString: run fn against a string, or every non-empty item of a string slice or array
+func eachString(v reflect.Value, fn func(s string) string) string {
+	switch v.Kind() {
+	case reflect.String:
+		if v.Len() == 0 {
+			return ""
+		}
+		return fn(v.String())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i)
+			if item.Kind() != reflect.String || item.Len() == 0 {
+				continue
+			}
+			if msg := fn(item.String()); msg != "" {
+				return msg
+			}
+		}
+	}
+	return ""
+}
+
+// eachScalar: run fn against the string form of a value, or of every non-empty item of a slice or array
+func eachScalar(v reflect.Value, fn func(s string) string) string {
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type() != objectIDType {
+		for i := 0; i < v.Len(); i++ {
+			if isEmpty(v.Index(i)) {
+				continue
+			}
+			if msg := fn(fmt.Sprint(v.Index(i).Interface())); msg != "" {
+				return msg
+			}
+		}
+		return ""
+	}
+
+	if isEmpty(v) {
+		return ""
+	}
+	return fn(fmt.Sprint(v.Interface()))
+}
+
+// countItems: the amount of non-empty items of a slice or array
+func countItems(v reflect.Value) int {
+	n := 0
+	for i := 0; i < v.Len(); i++ {
+		if !isEmpty(v.Index(i)) {
+			n++
+		}
+	}
+	return n
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Array:
+		if v.Type() == objectIDType {
+			return v.Interface().(primitive.ObjectID).IsZero()
+		}
+		return countItems(v) == 0
+	}
+	return v.IsZero()
+}
+
+func hasRule(tag string, name string) bool {
+	for _, rule := range strings.Split(tag, ",") {
+		if rule == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldName: get the name a field is exposed as to clients
+func fieldName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "query", "header"} {
+		if v, ok := f.Tag.Lookup(tag); ok {
+			if name := strings.Split(v, ",")[0]; name != "" && name != "-" {
+				return name
+			}
+		}
+	}
+	return f.Name
+}
+
+var (
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+	timeType     = reflect.TypeOf(time.Time{})
+
+	patterns  = map[string]*regexp.Regexp{}
+	masks     = map[string]int64{}
+	patternMx sync.RWMutex
+)
@@ -0,0 +1,326 @@
+package rest
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/valyala/fasthttp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func init() {
+	RegisterPattern("test_name", regexp.MustCompile(`^[a-z]{2,8}$`))
+	RegisterMask("test_flags", 3)
+}
+
+func TestValidate(t *testing.T) {
+	type nested struct {
+		ID string `json:"id" validate:"required,objectid"`
+	}
+
+	tests := []struct {
+		name string
+		v    interface{}
+		// the fields expected to fail, with their message
+		fields errors.Fields
+	}{
+		{"required string set", &struct {
+			A string `json:"a" validate:"required"`
+		}{"x"}, nil},
+		{"required string empty", &struct {
+			A string `json:"a" validate:"required"`
+		}{}, errors.Fields{"a": "is required"}},
+		{"required nil pointer", &struct {
+			A *string `json:"a" validate:"required"`
+		}{}, errors.Fields{"a": "is required"}},
+		{"optional nil pointer skips rules", &struct {
+			A *string `json:"a" validate:"min=2"`
+		}{}, nil},
+		{"pointer is dereferenced", &struct {
+			A *string `json:"a" validate:"min=2"`
+		}{strPtr("x")}, errors.Fields{"a": "must be at least 2 characters"}},
+		{"string max", &struct {
+			A string `json:"a" validate:"max=3"`
+		}{"abcd"}, errors.Fields{"a": "must be at most 3 characters"}},
+		{"string length counts runes", &struct {
+			A string `json:"a" validate:"max=3"`
+		}{"äöü"}, nil},
+		{"number bounds", &struct {
+			A int     `json:"a" validate:"min=1,max=10"`
+			B float64 `json:"b" validate:"max=0.5"`
+			C uint    `json:"c" validate:"min=2"`
+		}{11, 0.75, 1}, errors.Fields{
+			"a": "must be at most 10",
+			"b": "must be at most 0.5",
+			"c": "must be at least 2",
+		}},
+		{"slice max counts non-empty items", &struct {
+			A []string `json:"a" validate:"max=2"`
+		}{[]string{"a", "", "b", ""}}, nil},
+		{"slice max", &struct {
+			A []string `json:"a" validate:"max=2"`
+		}{[]string{"a", "b", "c"}}, errors.Fields{"a": "must be at most 2 items"}},
+		{"array max counts non-empty items", &struct {
+			A [4]string `json:"a" validate:"max=1"`
+		}{[4]string{"a", "b"}}, errors.Fields{"a": "must be at most 1 items"}},
+		{"required slice of empty items", &struct {
+			A [2]string `json:"a" validate:"required"`
+		}{}, errors.Fields{"a": "is required"}},
+		{"match string", &struct {
+			A string `json:"a" validate:"match=test_name"`
+		}{"Nope"}, errors.Fields{"a": "'Nope' does not match the expected format"}},
+		{"match skips empty string", &struct {
+			A string `json:"a" validate:"match=test_name"`
+		}{}, nil},
+		{"match every item", &struct {
+			A []string `json:"a" validate:"match=test_name"`
+		}{[]string{"ok", "", "n0"}}, errors.Fields{"a": "'n0' does not match the expected format"}},
+		{"oneof", &struct {
+			A string `json:"a" validate:"oneof=x|y"`
+		}{"z"}, errors.Fields{"a": "'z' must be one of x, y"}},
+		{"oneof every item", &struct {
+			A []int `json:"a" validate:"oneof=1|2"`
+		}{[]int{1, 2, 3}}, errors.Fields{"a": "'3' must be one of 1, 2"}},
+		{"mask allows set bits", &struct {
+			A int32 `json:"a" validate:"mask=257"`
+		}{257}, nil},
+		{"mask rejects other bits", &struct {
+			A int32 `json:"a" validate:"mask=257"`
+		}{258}, errors.Fields{"a": "has unknown bits set (allowed: 257)"}},
+		{"mask accepts hex", &struct {
+			A uint8 `json:"a" validate:"mask=0x0f"`
+		}{0x10}, errors.Fields{"a": "has unknown bits set (allowed: 15)"}},
+		{"named mask", &struct {
+			A int32 `json:"a" validate:"mask=test_flags"`
+		}{4}, errors.Fields{"a": "has unknown bits set (allowed: 3)"}},
+		{"objectid", &struct {
+			A string   `json:"a" validate:"objectid"`
+			B []string `json:"b" validate:"objectid"`
+		}{"nope", []string{primitive.NewObjectID().Hex(), "123"}}, errors.Fields{
+			"a": "'nope' is not a valid object id",
+			"b": "'123' is not a valid object id",
+		}},
+		{"nested struct fields are prefixed", &struct {
+			N nested `json:"n"`
+		}{}, errors.Fields{"n.id": "is required"}},
+		{"nested pointer", &struct {
+			N *nested `json:"n"`
+		}{&nested{"x"}}, errors.Fields{"n.id": "'x' is not a valid object id"}},
+		{"nil nested pointer is skipped", &struct {
+			N *nested `json:"n"`
+		}{}, nil},
+		{"field name falls back to query, then header", &struct {
+			A string `query:"q" validate:"required"`
+			B string `header:"X-B" validate:"required"`
+			C string `validate:"required"`
+		}{}, errors.Fields{"q": "is required", "X-B": "is required", "C": "is required"}},
+		{"first failing rule is reported", &struct {
+			A string `json:"a" validate:"required,min=3"`
+		}{}, errors.Fields{"a": "is required"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertFields(t, Validate(tt.v), tt.fields)
+		})
+	}
+}
+
+func TestValidatePanicsOnUnknownRule(t *testing.T) {
+	for _, tag := range []string{"nope", "match=unregistered", "mask=unregistered", "max=x"} {
+		t.Run(tag, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic for %q", tag)
+				}
+			}()
+			validateValue(reflect.ValueOf("abc"), tag)
+		})
+	}
+}
+
+func TestBind(t *testing.T) {
+	type args struct {
+		Name   string             `json:"name" validate:"required,match=test_name"`
+		Limit  int                `query:"limit" validate:"max=10"`
+		Tags   []string           `query:"tags"`
+		Owner  primitive.ObjectID `query:"owner"`
+		Since  time.Time          `query:"since"`
+		Strict *bool              `query:"strict"`
+		Token  string             `header:"X-Token" validate:"required"`
+	}
+	owner := primitive.NewObjectID()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		uri         string
+		headers     map[string]string
+		fields      errors.Fields
+		check       func(t *testing.T, a *args)
+	}{
+		{
+			name:        "decodes body, query and headers",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"name":"alice"}`,
+			uri:         "/?limit=5&tags=a,b&tags=c&owner=" + owner.Hex() + "&since=2022-01-02T03:04:05Z&strict=true",
+			headers:     map[string]string{"X-Token": "t"},
+			check: func(t *testing.T, a *args) {
+				if a.Name != "alice" || a.Limit != 5 || a.Owner != owner || a.Token != "t" {
+					t.Errorf("bad decode: %+v", a)
+				}
+				if len(a.Tags) != 2 || a.Tags[0] != "a,b" || a.Tags[1] != "c" {
+					t.Errorf("repeated query args should be kept as items, got %q", a.Tags)
+				}
+				if !a.Since.Equal(time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)) {
+					t.Errorf("bad time %s", a.Since)
+				}
+				if a.Strict == nil || !*a.Strict {
+					t.Errorf("pointer was not set")
+				}
+			},
+		},
+		{
+			name:        "a single query arg is split on commas",
+			contentType: "application/json",
+			body:        `{"name":"bob"}`,
+			uri:         "/?tags=a,%20b",
+			headers:     map[string]string{"X-Token": "t"},
+			check: func(t *testing.T, a *args) {
+				if len(a.Tags) != 2 || a.Tags[1] != "b" {
+					t.Errorf("got %q", a.Tags)
+				}
+			},
+		},
+		{
+			name:        "body is ignored without a json content type",
+			contentType: "text/plain",
+			body:        `{"name":"alice"}`,
+			uri:         "/",
+			headers:     map[string]string{"X-Token": "t"},
+			fields:      errors.Fields{"name": "is required"},
+		},
+		{
+			name:        "decode and validation failures are reported together",
+			contentType: "application/json",
+			body:        `{"name":"Al"}`,
+			uri:         "/?limit=x&owner=nope&since=yesterday&strict=maybe",
+			fields: errors.Fields{
+				"name":    "'Al' does not match the expected format",
+				"limit":   "must be an integer",
+				"owner":   "must be a valid object id",
+				"since":   "must be an RFC3339 timestamp",
+				"strict":  "must be a boolean",
+				"X-Token": "is required",
+			},
+		},
+		{
+			name:        "limit over the maximum",
+			contentType: "application/json",
+			body:        `{"name":"alice"}`,
+			uri:         "/?limit=11",
+			headers:     map[string]string{"X-Token": "t"},
+			fields:      errors.Fields{"limit": "must be at most 10"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newTestCtx(tt.uri, tt.contentType, tt.body, tt.headers)
+			a := &args{}
+			assertFields(t, ctx.Bind(a), tt.fields)
+			if tt.check != nil {
+				tt.check(t, a)
+			}
+		})
+	}
+
+	t.Run("malformed body", func(t *testing.T) {
+		ctx := newTestCtx("/", "application/json", `{"name":`, nil)
+		err := ctx.Bind(&args{})
+		if err == nil || err.Code() != errors.ErrInvalidRequest().Code() {
+			t.Fatalf("expected an invalid request error, got %v", err)
+		}
+	})
+}
+
+func TestBindJSONHeader(t *testing.T) {
+	type data struct {
+		Name string `json:"name" validate:"required"`
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		code   int
+	}{
+		{"missing", "", errors.ErrMissingRequiredField().Code()},
+		{"malformed", "{", errors.ErrInvalidRequest().Code()},
+		{"invalid", `{"name":""}`, errors.ErrInvalidRequest().Code()},
+		{"valid", `{"name":"x"}`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.header != "" {
+				headers["X-Data"] = tt.header
+			}
+			err := newTestCtx("/", "", "", headers).BindJSONHeader("X-Data", &data{})
+			code := 0
+			if err != nil {
+				code = err.Code()
+			}
+			if code != tt.code {
+				t.Errorf("expected code %d, got %d (%v)", tt.code, code, err)
+			}
+		})
+	}
+}
+
+func newTestCtx(uri, contentType, body string, headers map[string]string) *Ctx {
+	req := &fasthttp.Request{}
+	req.SetRequestURI(uri)
+	if contentType != "" {
+		req.Header.SetContentType(contentType)
+	}
+	req.SetBodyString(body)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	rctx := &fasthttp.RequestCtx{}
+	rctx.Init(req, nil, nil)
+	return &Ctx{RequestCtx: rctx}
+}
+
+func assertFields(t *testing.T, err APIError, want errors.Fields) {
+	t.Helper()
+
+	if len(want) == 0 {
+		if err != nil {
+			t.Fatalf("expected no error, got %v (%v)", err, err.GetFields())
+		}
+		return
+	}
+	if err == nil {
+		t.Fatalf("expected failing fields %v, got no error", want)
+	}
+
+	got := err.GetFields()
+	if len(got) != len(want) {
+		t.Errorf("expected fields %v, got %v", want, got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("field %s: expected %q, got %q", k, v, got[k])
+		}
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -1,16 +1,23 @@
 package rest
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
 
 	"github.com/SevenTV/Common/errors"
 	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/audit"
+	"github.com/fasthttp/router"
+	"github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Ctx struct {
-	Lifecycle *Lifecycle
 	*fasthttp.RequestCtx
+	hooks  hooks
+	halted bool
 }
 
 type APIError = errors.APIError
@@ -45,6 +52,97 @@ func (c *Ctx) SetActor(u *structures.User) {
 
 // Get the current authenticated user
 func (c *Ctx) GetActor() (*structures.User, bool) {
-	v := c.UserValue(string(AuthUserKey)).(*structures.User)
+	v, _ := c.UserValue(string(AuthUserKey)).(*structures.User)
 	return v, v != nil
 }
+
+// Get the route which is handling the request, as it was registered
+func (c *Ctx) Route() string {
+	v, _ := c.UserValue(router.MatchedRoutePathParam).(string)
+	return v
+}
+
+// Get the ID of the request
+func (c *Ctx) RequestID() string {
+	return RequestID(c.RequestCtx)
+}
+
+// Get a log entry for the request, tagged with its ID
+func (c *Ctx) Log() *logrus.Entry {
+	return logrus.WithField("request_id", c.RequestID())
+}
+
+// Get the value of a path parameter
+func (c *Ctx) Param(name string) string {
+	v, _ := c.UserValue(name).(string)
+	return v
+}
+
+// Get the value of a path parameter as an ObjectID. A missing or malformed value returns ErrBadObjectID
+func (c *Ctx) ObjectIDParam(name string) (primitive.ObjectID, APIError) {
+	v := c.Param(name)
+	id, err := primitive.ObjectIDFromHex(v)
+	if err != nil {
+		return primitive.NilObjectID, errors.ErrBadObjectID().SetFields(errors.Fields{
+			"param": name,
+			"value": v,
+		})
+	}
+
+	return id, nil
+}
+
+// Stop the request before any further middleware or the route handler runs, keeping the response as it is
+func (c *Ctx) Halt() {
+	c.halted = true
+}
+
+// Whether or not the request was halted by a middleware
+func (c *Ctx) Halted() bool {
+	return c.halted
+}
+
+// Get the request body as a stream, read from the connection as it arrives when the server streams request bodies.
+// Routes which handle large bodies should read them from here rather than Body(), so that wrappers added by middleware see the data
+func (c *Ctx) BodyStream() io.Reader {
+	if v, ok := c.UserValue(string(BodyStreamKey)).(io.Reader); ok {
+		return v
+	}
+
+	var r io.Reader = bytes.NewReader(c.Request.Body())
+	if s := c.RequestBodyStream(); s != nil {
+		r = s
+	}
+	c.SetUserValue(string(BodyStreamKey), r)
+	return r
+}
+
+// Wrap the stream returned by BodyStream, i.e to inspect the body as the route handler reads it
+func (c *Ctx) WrapBodyStream(fn func(r io.Reader) io.Reader) {
+	c.SetUserValue(string(BodyStreamKey), fn(c.BodyStream()))
+}
+
+// Add tags to the response, used to invalidate it when cached
+func (c *Ctx) AddCacheTags(tags ...string) {
+	v, _ := c.UserValue(string(CacheTagsKey)).([]string)
+	c.SetUserValue(string(CacheTagsKey), append(v, tags...))
+}
+
+// Get the tags added to the response
+func (c *Ctx) CacheTags() []string {
+	v, _ := c.UserValue(string(CacheTagsKey)).([]string)
+	return v
+}
+
+// Get the audit log of the request, to which targets and changes can be added
+func (c *Ctx) AuditLog() *audit.Log {
+	v, ok := c.UserValue(string(AuditLogKey)).(*audit.Log)
+	if !ok {
+		v = &audit.Log{
+			Targets: []audit.Target{},
+			Changes: []audit.Change{},
+		}
+		c.SetUserValue(string(AuditLogKey), v)
+	}
+	return v
+}
@@ -0,0 +1,13 @@
+package rest
+
+import "github.com/SevenTV/Common/errors"
+
+// Errors specific to this service, in addition to those defined in Common
+var (
+	ErrTooManyRequests     = errors.DefineError(70471, "Too Many Requests", int(TooManyRequests))    // client has exceeded a rate limit
+	ErrUnknownErrorReport  = errors.DefineError(70472, "Unknown Error Report", int(NotFound))        // can't find error report object
+	ErrPayloadTooLarge     = errors.DefineError(70473, "Payload Too Large", int(PayloadTooLarge))    // request body exceeds the size limit
+	ErrIdempotencyConflict = errors.DefineError(70474, "Idempotency Conflict", int(Conflict))        // idempotency key is in use or was used for another request
+	ErrReadOnly            = errors.DefineError(70475, "Read Only", int(ServiceUnavailable))         // the api is in read-only mode and only serves GET requests
+	ErrMaintenance         = errors.DefineError(70476, "Under Maintenance", int(ServiceUnavailable)) // the api is in maintenance mode
+)
@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/SevenTV/Common/structures/v3"
+)
+
+type (
+	// StartHook: called once all middleware has passed, right before the route handler runs
+	StartHook = func(ctx *Ctx)
+	// ErrorHook: called when a middleware or the route handler returns an error
+	ErrorHook = func(ctx *Ctx, err APIError)
+	// CompleteHook: called once the response is final, whether or not the request succeeded
+	CompleteHook = func(ctx *Ctx, res RequestResult)
+)
+
+// RequestResult: the outcome of a request
+type RequestResult struct {
+	// The final status code of the response
+	Status HttpStatusCode
+	// The time elapsed since the request was received
+	Duration time.Duration
+	// The authenticated user, or nil if the request was anonymous
+	Actor *structures.User
+	// The error returned by a middleware or the handler, if any
+	Error APIError
+}
+
+// hooks are run synchronously, in the order they were registered, on the goroutine serving the request
+type hooks struct {
+	mx       sync.Mutex
+	start    []StartHook
+	errors   []ErrorHook
+	complete []CompleteHook
+	phase    hookPhase
+}
+
+type hookPhase uint8
+
+const (
+	hookPhaseNone hookPhase = iota
+	hookPhaseStarted
+	hookPhaseCompleted
+)
+
+// OnStart: register a function to call once all middleware has passed
+func (c *Ctx) OnStart(fn StartHook) {
+	c.hooks.mx.Lock()
+	defer c.hooks.mx.Unlock()
+
+	c.hooks.start = append(c.hooks.start, fn)
+}
+
+// OnError: register a function to call if the request fails with an error
+func (c *Ctx) OnError(fn ErrorHook) {
+	c.hooks.mx.Lock()
+	defer c.hooks.mx.Unlock()
+
+	c.hooks.errors = append(c.hooks.errors, fn)
+}
+
+// OnComplete: register a function to call once the response is final
+func (c *Ctx) OnComplete(fn CompleteHook) {
+	c.hooks.mx.Lock()
+	defer c.hooks.mx.Unlock()
+
+	c.hooks.complete = append(c.hooks.complete, fn)
+}
+
+// Start: run the start hooks. This is called by the server and has no effect after the first call
+func (c *Ctx) Start() {
+	c.hooks.mx.Lock()
+	if c.hooks.phase != hookPhaseNone {
+		c.hooks.mx.Unlock()
+		return
+	}
+	c.hooks.phase = hookPhaseStarted
+	fns := c.hooks.start
+	c.hooks.mx.Unlock()
+
+	for _, fn := range fns {
+		fn(c)
+	}
+}
+
+// Complete: run the error hooks (if err is not nil) followed by the completion hooks.
+// This is called by the server once the response is final and has no effect after the first call
+func (c *Ctx) Complete(err APIError) {
+	c.hooks.mx.Lock()
+	if c.hooks.phase == hookPhaseCompleted {
+		c.hooks.mx.Unlock()
+		return
+	}
+	c.hooks.phase = hookPhaseCompleted
+	errFns := c.hooks.errors
+	completeFns := c.hooks.complete
+	c.hooks.mx.Unlock()
+
+	if err != nil {
+		for _, fn := range errFns {
+			fn(c, err)
+		}
+	}
+
+	actor, _ := c.GetActor()
+	res := RequestResult{
+		Status:   c.StatusCode(),
+		Duration: time.Since(c.Time()),
+		Actor:    actor,
+		Error:    err,
+	}
+	for _, fn := range completeFns {
+		fn(c, res)
+	}
+}
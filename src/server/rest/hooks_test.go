@@ -0,0 +1,120 @@
+package rest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/structures/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestHooks(t *testing.T) {
+	actor := &structures.User{ID: primitive.NewObjectID()}
+
+	tests := []struct {
+		name string
+		// the calls the server makes, in order: "start", "complete" or "fail"
+		calls []string
+		actor *structures.User
+		want  []string
+	}{
+		{
+			name:  "hooks run in registration order",
+			calls: []string{"start", "complete"},
+			want:  []string{"start:1", "start:2", "complete:1:200:", "complete:2:200:"},
+		},
+		{
+			name:  "error hooks run before completion hooks",
+			calls: []string{"start", "fail"},
+			want:  []string{"start:1", "start:2", "error:1:70410", "error:2:70410", "complete:1:400:70410", "complete:2:400:70410"},
+		},
+		{
+			name:  "a middleware failing skips the start hooks",
+			calls: []string{"fail"},
+			want:  []string{"error:1:70410", "error:2:70410", "complete:1:400:70410", "complete:2:400:70410"},
+		},
+		{
+			name:  "each phase runs once",
+			calls: []string{"start", "start", "complete", "fail", "complete"},
+			want:  []string{"start:1", "start:2", "complete:1:200:", "complete:2:200:"},
+		},
+		{
+			name:  "starting after completion does nothing",
+			calls: []string{"complete", "start"},
+			want:  []string{"complete:1:200:", "complete:2:200:"},
+		},
+		{
+			name:  "the actor is passed to completion hooks",
+			calls: []string{"complete"},
+			actor: actor,
+			want:  []string{"complete:1:200:" + actor.ID.Hex(), "complete:2:200:" + actor.ID.Hex()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newTestCtx("/", "", "", nil)
+			if tt.actor != nil {
+				ctx.SetActor(tt.actor)
+			}
+
+			got := []string{}
+			for i := 1; i <= 2; i++ {
+				i := i
+				ctx.OnStart(func(ctx *Ctx) {
+					got = append(got, fmt.Sprintf("start:%d", i))
+				})
+				ctx.OnError(func(ctx *Ctx, err APIError) {
+					got = append(got, fmt.Sprintf("error:%d:%d", i, err.Code()))
+				})
+				ctx.OnComplete(func(ctx *Ctx, res RequestResult) {
+					s := fmt.Sprintf("complete:%d:%d:", i, res.Status)
+					if res.Error != nil {
+						s += fmt.Sprint(res.Error.Code())
+					}
+					if res.Actor != nil {
+						s += res.Actor.ID.Hex()
+					}
+					got = append(got, s)
+				})
+			}
+
+			for _, call := range tt.calls {
+				switch call {
+				case "start":
+					ctx.Start()
+				case "complete":
+					ctx.SetStatusCode(OK)
+					ctx.Complete(nil)
+				case "fail":
+					ctx.SetStatusCode(BadRequest)
+					ctx.Complete(errors.ErrInvalidRequest())
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected\n\t%s\ngot\n\t%s", strings.Join(tt.want, "\n\t"), strings.Join(got, "\n\t"))
+			}
+		})
+	}
+}
+
+func TestHooksRegisteredDuringStart(t *testing.T) {
+	ctx := newTestCtx("/", "", "", nil)
+
+	completed := false
+	ctx.OnStart(func(ctx *Ctx) {
+		ctx.OnComplete(func(ctx *Ctx, res RequestResult) {
+			completed = true
+		})
+	})
+	ctx.Start()
+	ctx.Complete(nil)
+
+	if !completed {
+		t.Error("a completion hook registered by a start hook did not run")
+	}
+}
@@ -3,5 +3,9 @@ package rest
 type Key string
 
 const (
-	AuthUserKey Key = "CURRENT_USER"
+	AuthUserKey   Key = "CURRENT_USER"
+	AuditLogKey   Key = "AUDIT_LOG"
+	RequestIDKey  Key = "REQUEST_ID"
+	CacheTagsKey  Key = "CACHE_TAGS"
+	BodyStreamKey Key = "BODY_STREAM"
 )
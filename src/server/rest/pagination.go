@@ -0,0 +1,289 @@
+package rest
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/valyala/fasthttp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	PageLimitQuery = "limit"
+	PageAfterQuery = "after"
+)
+
+// Page: a window of a list sorted by one or more keys, parsed from the "limit" and "after" query args
+//
+// The position in the list is held by an opaque cursor encoding the sort key values of the last item sent to the client,
+// so pages stay consistent as items are added or removed, and no items are skipped over by the database to reach them.
+type Page struct {
+	// The maximum amount of items in the page
+	Limit int
+	// The keys the list is sorted by. _id is always the last, to break ties
+	Sort bson.D
+	// The sort key values of the item this page starts after, or nil for the first page
+	After bson.D
+
+	next string
+}
+
+// The types of value a cursor may hold. Documents, arrays and patterns are not allowed,
+// as they would be read as operators or regular expressions once the cursor is made into a filter
+var cursorTypes = map[bsontype.Type]bool{
+	bsontype.Null:       true,
+	bsontype.Boolean:    true,
+	bsontype.String:     true,
+	bsontype.ObjectID:   true,
+	bsontype.DateTime:   true,
+	bsontype.Timestamp:  true,
+	bsontype.Int32:      true,
+	bsontype.Int64:      true,
+	bsontype.Double:     true,
+	bsontype.Decimal128: true,
+}
+
+// Paginate: parse the page requested by the client, for a list sorted by the given keys.
+// The cursor values of the keys listed in types must be of that type or null, and _id must be an ObjectID.
+// The limit defaults to def and may not exceed max
+func (c *Ctx) Paginate(sort bson.D, types map[string]bsontype.Type, def int, max int) (*Page, APIError) {
+	p := &Page{Limit: def}
+
+	// Always end the sort on a unique key, so that every item has a distinct position
+	for _, e := range sort {
+		if e.Key == "_id" {
+			break
+		}
+		p.Sort = append(p.Sort, e)
+	}
+	dir := 1
+	if len(sort) > 0 {
+		dir = sortDirection(sort[len(sort)-1].Value)
+	}
+	p.Sort = append(p.Sort, bson.E{Key: "_id", Value: dir})
+
+	fields := errors.Fields{}
+	if v := c.QueryArgs().Peek(PageLimitQuery); len(v) > 0 {
+		n, err := strconv.Atoi(string(v))
+		if err != nil {
+			fields[PageLimitQuery] = "must be an integer"
+		} else if n < 1 || n > max {
+			fields[PageLimitQuery] = fmt.Sprintf("must be between 1 and %d", max)
+		} else {
+			p.Limit = n
+		}
+	}
+	if v := c.QueryArgs().Peek(PageAfterQuery); len(v) > 0 {
+		after, err := decodeCursor(string(v), p.Sort, types)
+		if err != nil {
+			fields[PageAfterQuery] = "must be a cursor returned by this list"
+		} else {
+			p.After = after
+		}
+	}
+	if len(fields) > 0 {
+		return nil, validationError(fields)
+	}
+
+	return p, nil
+}
+
+// Filter: combine a filter with the condition selecting the items which come after the cursor
+func (p *Page) Filter(filter bson.M) bson.M {
+	if p.After == nil {
+		return filter
+	}
+
+	// For a sort of (a, b, _id) this is: a > A or (a = A and b > B) or (a = A and b = B and _id > ID)
+	or := make(bson.A, len(p.Sort))
+	for i, e := range p.Sort {
+		cond := bson.M{}
+		for _, prev := range p.After[:i] {
+			cond[prev.Key] = prev.Value
+		}
+
+		op := "$gt"
+		if sortDirection(e.Value) < 0 {
+			op = "$lt"
+		}
+		cond[e.Key] = bson.M{op: p.After[i].Value}
+		or[i] = cond
+	}
+
+	if len(filter) == 0 {
+		return bson.M{"$or": or}
+	}
+	return bson.M{"$and": bson.A{filter, bson.M{"$or": or}}}
+}
+
+// FindOptions: the sort and limit to query the page with.
+// One more item than the limit is requested, to find out whether there is a next page
+func (p *Page) FindOptions() *options.FindOptions {
+	return options.Find().SetSort(p.Sort).SetLimit(int64(p.Limit) + 1)
+}
+
+// Stages: the stages selecting the page, to end an aggregation with. They are the equivalent of Filter and FindOptions,
+// for lists sorted by fields which are computed in the pipeline
+func (p *Page) Stages() mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: p.Filter(bson.M{})}},
+		{{Key: "$sort", Value: p.Sort}},
+		{{Key: "$limit", Value: p.Limit + 1}},
+	}
+}
+
+// Decode: read the items of the page from a cursor opened with FindOptions or Stages into dst, which must be a pointer to a slice
+func (p *Page) Decode(ctx context.Context, cur *mongo.Cursor, dst interface{}) error {
+	defer cur.Close(ctx)
+
+	docs := []bson.Raw{}
+	for cur.Next(ctx) {
+		docs = append(docs, append(bson.Raw{}, cur.Current...))
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+
+	p.next = ""
+	if len(docs) > p.Limit {
+		docs = docs[:p.Limit]
+
+		next, err := encodeCursor(docs[len(docs)-1], p.Sort)
+		if err != nil {
+			return err
+		}
+		p.next = next
+	}
+
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("rest: page destination must be a pointer to a slice, got %T", dst)
+	}
+	s := reflect.MakeSlice(v.Elem().Type(), len(docs), len(docs))
+	for i, doc := range docs {
+		if err := bson.Unmarshal(doc, s.Index(i).Addr().Interface()); err != nil {
+			return err
+		}
+	}
+	v.Elem().Set(s)
+
+	return nil
+}
+
+// Next: the cursor of the next page, or an empty string if this is the last page
+func (p *Page) Next() string {
+	return p.next
+}
+
+// SetPageHeaders: link to the next page of a list, and set its total count unless it is negative
+func (c *Ctx) SetPageHeaders(p *Page, total int64) {
+	if total >= 0 {
+		c.Response.Header.Set("X-Total-Count", strconv.FormatInt(total, 10))
+	}
+	if p.next == "" {
+		return
+	}
+
+	args := fasthttp.AcquireArgs()
+	defer fasthttp.ReleaseArgs(args)
+
+	c.QueryArgs().CopyTo(args)
+	args.Set(PageLimitQuery, strconv.Itoa(p.Limit))
+	args.Set(PageAfterQuery, p.next)
+	c.Response.Header.Set("Link", fmt.Sprintf("<%s?%s>; rel=\"next\"", c.Path(), args.QueryString()))
+}
+
+// encodeCursor: encode the sort key values of a document
+func encodeCursor(doc bson.Raw, sort bson.D) (string, error) {
+	values := make(bson.D, len(sort))
+	for i, e := range sort {
+		values[i].Key = e.Key
+		if v, err := doc.LookupErr(strings.Split(e.Key, ".")...); err == nil {
+			values[i].Value = v
+		}
+	}
+
+	b, err := bson.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor: decode the sort key values of a cursor, which must have been made for the same sort
+func decodeCursor(s string, sort bson.D, types map[string]bsontype.Type) (bson.D, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := bson.Raw(b)
+	elems, err := raw.Elements()
+	if err != nil {
+		return nil, err
+	}
+	if len(elems) != len(sort) {
+		return nil, fmt.Errorf("cursor has %d keys, expected %d", len(elems), len(sort))
+	}
+	for i, e := range sort {
+		if elems[i].Key() != e.Key {
+			return nil, fmt.Errorf("cursor key %q does not match sort key %q", elems[i].Key(), e.Key)
+		}
+
+		t := elems[i].Value().Type
+		if !cursorTypes[t] {
+			return nil, fmt.Errorf("cursor key %q holds a value of type %s", e.Key, t)
+		}
+		if e.Key == "_id" && t != bsontype.ObjectID {
+			return nil, fmt.Errorf("cursor key %q holds a value of type %s, expected %s", e.Key, t, bsontype.ObjectID)
+		}
+		if want, ok := types[e.Key]; ok && !cursorTypeMatches(t, want) {
+			return nil, fmt.Errorf("cursor key %q holds a value of type %s, expected %s", e.Key, t, want)
+		}
+	}
+
+	values := bson.D{}
+	if err = bson.Unmarshal(raw, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// cursorTypeMatches: whether a cursor value of type t can be compared with values of type want.
+// Numbers of any type are compared by value
+func cursorTypeMatches(t, want bsontype.Type) bool {
+	if t == want || t == bsontype.Null {
+		return true
+	}
+	return isNumberType(t) && isNumberType(want)
+}
+
+func isNumberType(t bsontype.Type) bool {
+	switch t {
+	case bsontype.Int32, bsontype.Int64, bsontype.Double, bsontype.Decimal128:
+		return true
+	}
+	return false
+}
+
+func sortDirection(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 1
+}
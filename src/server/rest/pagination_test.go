@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPaginate(t *testing.T) {
+	id := primitive.NewObjectID()
+	cursor := func(d bson.D) string {
+		b, err := bson.Marshal(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	sort := bson.D{{Key: "popularity", Value: -1}}
+	types := map[string]bsontype.Type{"popularity": bsontype.Int32}
+
+	tests := []struct {
+		name  string
+		query string
+		// the expected limit and cursor values, or the fields which fail
+		limit  int
+		after  bson.D
+		fields []string
+	}{
+		{"defaults", "", 50, nil, nil},
+		{"limit", "limit=10", 10, nil, nil},
+		{"limit too high", "limit=151", 0, nil, []string{"limit"}},
+		{"limit not a number", "limit=x", 0, nil, []string{"limit"}},
+		{"cursor", "after=" + cursor(bson.D{{Key: "popularity", Value: int32(3)}, {Key: "_id", Value: id}}), 50,
+			bson.D{{Key: "popularity", Value: int32(3)}, {Key: "_id", Value: id}}, nil},
+		{"numbers of another type", "after=" + cursor(bson.D{{Key: "popularity", Value: int64(3)}, {Key: "_id", Value: id}}), 50,
+			bson.D{{Key: "popularity", Value: int64(3)}, {Key: "_id", Value: id}}, nil},
+		{"null for a missing field", "after=" + cursor(bson.D{{Key: "popularity", Value: nil}, {Key: "_id", Value: id}}), 50,
+			bson.D{{Key: "popularity", Value: nil}, {Key: "_id", Value: id}}, nil},
+		{"not base64", "after=!!", 0, nil, []string{"after"}},
+		{"not bson", "after=" + base64.RawURLEncoding.EncodeToString([]byte("abc")), 0, nil, []string{"after"}},
+		{"missing key", "after=" + cursor(bson.D{{Key: "_id", Value: id}}), 0, nil, []string{"after"}},
+		{"keys out of order", "after=" + cursor(bson.D{{Key: "_id", Value: id}, {Key: "popularity", Value: int32(3)}}), 0, nil, []string{"after"}},
+		{"document value", "after=" + cursor(bson.D{{Key: "popularity", Value: bson.D{{Key: "$ne", Value: 1}}}, {Key: "_id", Value: id}}), 0, nil, []string{"after"}},
+		{"array value", "after=" + cursor(bson.D{{Key: "popularity", Value: bson.A{1}}, {Key: "_id", Value: id}}), 0, nil, []string{"after"}},
+		{"pattern value", "after=" + cursor(bson.D{{Key: "popularity", Value: primitive.Regex{Pattern: ".*"}}, {Key: "_id", Value: id}}), 0, nil, []string{"after"}},
+		{"type mismatch", "after=" + cursor(bson.D{{Key: "popularity", Value: "3"}, {Key: "_id", Value: id}}), 0, nil, []string{"after"}},
+		{"id not an object id", "after=" + cursor(bson.D{{Key: "popularity", Value: int32(3)}, {Key: "_id", Value: id.Hex()}}), 0, nil, []string{"after"}},
+		{"null id", "after=" + cursor(bson.D{{Key: "popularity", Value: int32(3)}, {Key: "_id", Value: nil}}), 0, nil, []string{"after"}},
+		{"both fail", "limit=0&after=x", 0, nil, []string{"limit", "after"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &fasthttp.Request{}
+			req.SetRequestURI("/emotes?" + tt.query)
+			rctx := &fasthttp.RequestCtx{}
+			rctx.Init(req, nil, nil)
+			ctx := &Ctx{RequestCtx: rctx}
+
+			p, err := ctx.Paginate(sort, types, 50, 150)
+			if tt.fields != nil {
+				if err == nil {
+					t.Fatalf("expected %v to fail", tt.fields)
+				}
+				details := err.GetFields()
+				for _, f := range tt.fields {
+					if _, ok := details[f]; !ok {
+						t.Errorf("expected %s to fail, got %v", f, details)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if p.Limit != tt.limit {
+				t.Errorf("expected limit %d, got %d", tt.limit, p.Limit)
+			}
+			if !reflect.DeepEqual(p.After, tt.after) {
+				t.Errorf("expected cursor %v, got %v", tt.after, p.After)
+			}
+			if want := (bson.D{{Key: "popularity", Value: -1}, {Key: "_id", Value: -1}}); !reflect.DeepEqual(p.Sort, want) {
+				t.Errorf("expected sort %v, got %v", want, p.Sort)
+			}
+		})
+	}
+}
+
+func TestPageFilter(t *testing.T) {
+	id := primitive.NewObjectID()
+	p := &Page{
+		Sort:  bson.D{{Key: "popularity", Value: -1}, {Key: "name", Value: 1}, {Key: "_id", Value: -1}},
+		After: bson.D{{Key: "popularity", Value: int32(3)}, {Key: "name", Value: "a"}, {Key: "_id", Value: id}},
+	}
+
+	want := bson.A{
+		bson.M{"popularity": bson.M{"$lt": int32(3)}},
+		bson.M{"popularity": int32(3), "name": bson.M{"$gt": "a"}},
+		bson.M{"popularity": int32(3), "name": "a", "_id": bson.M{"$lt": id}},
+	}
+	if got := p.Filter(bson.M{}); !reflect.DeepEqual(got, bson.M{"$or": want}) {
+		t.Errorf("unexpected filter %v", got)
+	}
+	if got := p.Filter(bson.M{"x": 1}); !reflect.DeepEqual(got, bson.M{"$and": bson.A{bson.M{"x": 1}, bson.M{"$or": want}}}) {
+		t.Errorf("unexpected filter %v", got)
+	}
+	if got := (&Page{}).Filter(bson.M{"x": 1}); !reflect.DeepEqual(got, bson.M{"x": 1}) {
+		t.Errorf("expected the first page to be unfiltered, got %v", got)
+	}
+}
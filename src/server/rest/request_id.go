@@ -0,0 +1,35 @@
+package rest
+
+import (
+	"regexp"
+
+	"github.com/SevenTV/Common/utils"
+	"github.com/valyala/fasthttp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The header used to receive and return the ID of a request
+const RequestIDHeader = "X-Request-ID"
+
+var requestIDRegex = regexp.MustCompile(`^[\w\-.:]{1,128}$`)
+
+// RequestID: get the ID of a request, assigning one if it does not have one yet
+//
+// The ID is taken from the X-Request-ID header when the client provides a valid one, otherwise a new ID is generated.
+// It is also set on the response so that clients can report it back
+func RequestID(ctx *fasthttp.RequestCtx) string {
+	if v, ok := ctx.UserValue(string(RequestIDKey)).(string); ok {
+		return v
+	}
+
+	id := utils.B2S(ctx.Request.Header.Peek(RequestIDHeader))
+	if !requestIDRegex.MatchString(id) {
+		id = primitive.NewObjectID().Hex()
+	} else {
+		id = string([]byte(id)) // copy, as the header buffer is reused
+	}
+
+	ctx.SetUserValue(string(RequestIDKey), id)
+	ctx.Response.Header.Set(RequestIDHeader, id)
+	return id
+}
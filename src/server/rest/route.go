@@ -12,6 +12,8 @@ type Route interface {
 type Router = router.Router
 
 type RouteConfig struct {
+	// The path of the route, relative to its parent. It may contain parameters, i.e "/{id}",
+	// which can be read with ctx.Param() or ctx.ObjectIDParam()
 	URI        string
 	Method     RouteMethod
 	Children   []Route
@@ -37,6 +39,7 @@ type APIErrorResponse struct {
 	Error      string                 `json:"error"`
 	ErrorCode  int                    `json:"error_code"`
 	Details    map[string]interface{} `json:"details,omitempty"`
+	RequestID  string                 `json:"request_id,omitempty"`
 }
 
 type HttpStatusCode int
@@ -1,20 +1,26 @@
 package server
 
 import (
+	"context"
 	"net"
 	"time"
 
 	"github.com/SevenTV/Common/utils"
 	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v3/routes/emotes"
 	"github.com/fasthttp/router"
 	"github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
 )
 
 type HttpServer struct {
+	gCtx     global.Context
 	listener net.Listener
 	server   *fasthttp.Server
 	router   *router.Router
+	// Whether this is the admin server, which is never affected by the maintenance mode
+	admin bool
 }
 
 // Start: set up the http server and begin listening on the configured port
@@ -24,67 +30,104 @@ func (s *HttpServer) Start(gCtx global.Context) (<-chan struct{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	s.router = router.New()
+	s.setup(gCtx)
 
-	// Add versions
-	s.SetupHandlers()
-	s.V3(gCtx)
+	return s.serve(), nil
+}
 
+// serve: begin serving requests on the listener
+func (s *HttpServer) serve() <-chan struct{} {
 	s.server = &fasthttp.Server{
-		Handler: func(ctx *fasthttp.RequestCtx) {
-			start := time.Now()
-			defer func() {
-				l := logrus.WithFields(logrus.Fields{
-					"status":   ctx.Response.StatusCode(),
-					"duration": time.Since(start) / time.Millisecond,
-					"method":   utils.B2S(ctx.Method()),
-					"path":     utils.B2S(ctx.Path()),
-				})
-				if err := recover(); err != nil {
-					l.Error("panic in handler: ", err)
-				} else {
-					l.Info()
-				}
-			}()
-
-			// CORS
-			ctx.Response.Header.Set("Access-Control-Allow-Credentials", "true")
-			ctx.Response.Header.Set("Access-Control-Allow-Headers", "*")
-			ctx.Response.Header.Set("Access-Control-Allow-Methods", "*")
-			ctx.Response.Header.Set("Access-Control-Allow-Origin", "*")
-			if ctx.IsOptions() {
-				return
-			}
-
-			// Routing
-			ctx.Response.Header.Set("Content-Type", "application/json") // default to JSON
-			s.router.Handler(ctx)
-		},
+		Handler:                      s.handle,
 		ReadTimeout:                  time.Second * 600,
 		IdleTimeout:                  time.Second * 10,
-		MaxRequestBodySize:           2e16,
+		MaxRequestBodySize:           int(emotes.MaxUploadSize(s.gCtx.Config())),
 		DisablePreParseMultipartForm: true,
 		LogAllErrors:                 true,
 		StreamRequestBody:            true,
 		CloseOnShutdown:              true,
 	}
 
-	// Gracefully exit when the global context is canceled
+	// Begin listening
 	done := make(chan struct{})
 	go func() {
-		<-gCtx.Done()
-		_ = s.server.Shutdown()
+		defer close(done)
+		if err := s.server.Serve(s.listener); err != nil {
+			logrus.WithError(err).Fatal("failed to start http server")
+		}
 	}()
 
-	// Begin listening
+	return done
+}
+
+// Shutdown: stop accepting connections and wait for in-flight requests to complete,
+// or for the context to be done, in which case its error is returned and the requests are left to the process exiting
+func (s *HttpServer) Shutdown(ctx context.Context) error {
+	done := make(chan error, 1)
 	go func() {
-		defer close(done)
-		if err = s.server.Serve(s.listener); err != nil {
-			logrus.WithError(err).Fatal("failed to start http server")
+		done <- s.server.Shutdown()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// setup: create the router and add every version of the API
+func (s *HttpServer) setup(gCtx global.Context) {
+	s.gCtx = gCtx
+	s.router = router.New()
+	s.router.SaveMatchedRoutePath = true
+
+	// Add versions
+	s.SetupHandlers()
+	s.Probes(gCtx)
+	s.V3(gCtx)
+	if gCtx.Config().Legacy.Enabled {
+		s.V2(gCtx)
+	}
+}
+
+// handle: the root request handler, which logs the request and applies CORS before routing
+func (s *HttpServer) handle(ctx *fasthttp.RequestCtx) {
+	start := time.Now()
+	requestID := rest.RequestID(ctx)
+	defer func() {
+		l := logrus.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"status":     ctx.Response.StatusCode(),
+			"duration":   time.Since(start) / time.Millisecond,
+			"method":     utils.B2S(ctx.Method()),
+			"path":       utils.B2S(ctx.Path()),
+		})
+		if v := recover(); v != nil {
+			writeError(ctx, rest.InternalServerError, s.recoverPanic(ctx, v))
+			l.WithField("status", ctx.Response.StatusCode()).Error("panic in handler")
+		} else {
+			l.Info()
 		}
 	}()
 
-	return done, err
+	// CORS
+	ctx.Response.Header.Set("Access-Control-Allow-Credentials", "true")
+	ctx.Response.Header.Set("Access-Control-Allow-Headers", "*")
+	ctx.Response.Header.Set("Access-Control-Allow-Methods", "*")
+	ctx.Response.Header.Set("Access-Control-Allow-Origin", "*")
+	if ctx.IsOptions() {
+		return
+	}
+
+	// Maintenance
+	if !s.admin && s.rejectMaintenance(ctx) {
+		return
+	}
+
+	// Routing
+	ctx.Response.Header.Set("Content-Type", "application/json") // default to JSON
+	s.router.Handler(ctx)
 }
 
 func New() HttpServer {
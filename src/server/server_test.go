@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestShutdown(t *testing.T) {
+	tests := []struct {
+		name string
+		// how long the request in flight takes, if there is one
+		request time.Duration
+		timeout time.Duration
+		err     error
+	}{
+		{"idle", 0, time.Second, nil},
+		{"waits for requests in flight", time.Millisecond * 100, time.Second, nil},
+		{"stops waiting at the deadline", time.Second * 5, time.Millisecond * 100, context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gCtx, err := fakes.NewContext(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			started := make(chan struct{})
+			h := newTestHarness(gCtx, &testRoute{
+				config: rest.RouteConfig{URI: "/slow", Method: rest.GET},
+				handler: func(ctx *rest.Ctx) rest.APIError {
+					close(started)
+					time.Sleep(tt.request)
+					return ctx.JSON(rest.OK, "ok")
+				},
+			})
+			srv := h.srv
+			if srv.listener, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+				t.Fatal(err)
+			}
+			srv.serve()
+
+			responded := make(chan int, 1)
+			if tt.request > 0 {
+				go func() {
+					status, _, _ := fasthttp.Get(nil, "http://"+srv.listener.Addr().String()+"/slow")
+					responded <- status
+				}()
+				<-started
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			start := time.Now()
+			if err := srv.Shutdown(ctx); err != tt.err {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if time.Since(start) > tt.timeout+time.Millisecond*200 {
+				t.Errorf("shutdown took %s, past its deadline", time.Since(start))
+			}
+
+			if tt.request > 0 && tt.err == nil {
+				if status := <-responded; status != 200 {
+					t.Errorf("expected the request in flight to complete, got status %d", status)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/sirupsen/logrus"
+)
+
+// Deprecated: mark the response as coming from a deprecated API,
+// pointing to its successor and announcing the date it will be removed, if one is configured
+func Deprecated(gCtx global.Context) rest.Middleware {
+	sunset := ""
+	if s := gCtx.Config().Legacy.Sunset; s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			logrus.WithError(err).Warn("legacy, bad sunset date")
+		} else {
+			sunset = t.UTC().Format(http.TimeFormat)
+		}
+	}
+
+	return func(ctx *rest.Ctx) rest.APIError {
+		ctx.Response.Header.Set("Deprecation", "true")
+		ctx.Response.Header.Set("Link", "</v3>; rel=\"successor-version\"")
+		if sunset != "" {
+			ctx.Response.Header.Set("Sunset", sunset)
+		}
+
+		return nil
+	}
+}
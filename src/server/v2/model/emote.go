@@ -0,0 +1,122 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SevenTV/Common/structures/v3"
+)
+
+// Emote: an emote, in the v2 format
+//
+// In v2 each version of an emote is an emote of its own, identified by the version's ID
+type Emote struct {
+	ID               string     `json:"id"`
+	Name             string     `json:"name"`
+	Owner            *User      `json:"owner"`
+	Visibility       int32      `json:"visibility"`
+	VisibilitySimple []string   `json:"visibility_simple"`
+	Mime             string     `json:"mime"`
+	Status           int32      `json:"status"`
+	Tags             []string   `json:"tags"`
+	Width            [4]int32   `json:"width"`
+	Height           [4]int32   `json:"height"`
+	URLs             [][]string `json:"urls"`
+}
+
+// v2 emote visibility flags
+const (
+	EmoteVisibilityPrivate   int32 = 1 << 0
+	EmoteVisibilityUnlisted  int32 = 1 << 2
+	EmoteVisibilityZeroWidth int32 = 1 << 7
+)
+
+// v2 emote statuses
+const (
+	EmoteStatusDeleted    int32 = -1
+	EmoteStatusProcessing int32 = 0
+	EmoteStatusPending    int32 = 1
+	EmoteStatusDisabled   int32 = 2
+	EmoteStatusLive       int32 = 3
+)
+
+// NewEmote: translate a version of an emote into the v2 format
+func NewEmote(e *structures.Emote, ver *structures.EmoteVersion, cdnURL string) *Emote {
+	name := e.Name
+	if ver.Name != "" {
+		name = ver.Name
+	}
+
+	visibility := int32(0)
+	simple := []string{}
+	if e.Flags&structures.EmoteFlagsPrivate != 0 {
+		visibility |= EmoteVisibilityPrivate
+		simple = append(simple, "PRIVATE")
+	}
+	if e.Flags&structures.EmoteFlagsListed == 0 {
+		visibility |= EmoteVisibilityUnlisted
+		simple = append(simple, "UNLISTED")
+	}
+	if e.Flags&structures.EmoteFlagsZeroWidth != 0 {
+		visibility |= EmoteVisibilityZeroWidth
+		simple = append(simple, "ZERO_WIDTH")
+	}
+
+	status := EmoteStatusDisabled
+	switch ver.State.Lifecycle {
+	case structures.EmoteLifecycleDeleted:
+		status = EmoteStatusDeleted
+	case structures.EmoteLifecyclePending:
+		status = EmoteStatusPending
+	case structures.EmoteLifecycleProcessing:
+		status = EmoteStatusProcessing
+	case structures.EmoteLifecycleLive:
+		status = EmoteStatusLive
+	}
+
+	tags := e.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	result := &Emote{
+		ID:               ver.ID.Hex(),
+		Name:             name,
+		Owner:            NewUser(e.Owner),
+		Visibility:       visibility,
+		VisibilitySimple: simple,
+		Mime:             string(structures.EmoteFormatNameWEBP),
+		Status:           status,
+		Tags:             tags,
+		URLs:             [][]string{},
+	}
+
+	// v2 serves webp only, in up to four sizes
+	for _, format := range ver.Formats {
+		if format.Name != structures.EmoteFormatNameWEBP {
+			continue
+		}
+
+		files := make([]structures.EmoteFile, len(format.Files))
+		copy(files, format.Files)
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].Width < files[j].Width
+		})
+
+		for i, f := range files {
+			if i >= len(result.Width) {
+				break
+			}
+
+			result.Width[i] = f.Width
+			result.Height[i] = f.Height
+			result.URLs = append(result.URLs, []string{
+				fmt.Sprint(i + 1),
+				fmt.Sprintf("%s/emote/%s/%s", strings.TrimSuffix(cdnURL, "/"), ver.ID.Hex(), f.Name),
+			})
+		}
+	}
+
+	return result
+}
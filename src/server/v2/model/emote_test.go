@@ -0,0 +1,147 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/SevenTV/Common/structures/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNewEmote(t *testing.T) {
+	ver := func(lifecycle structures.EmoteLifecycle, files ...structures.EmoteFile) *structures.EmoteVersion {
+		return &structures.EmoteVersion{
+			ID:    primitive.NewObjectID(),
+			State: structures.EmoteState{Lifecycle: lifecycle},
+			Formats: []structures.EmoteFormat{
+				{Name: structures.EmoteFormatNameAVIF, Files: []structures.EmoteFile{{Name: "1x.avif", Width: 32, Height: 32}}},
+				{Name: structures.EmoteFormatNameWEBP, Files: files},
+			},
+		}
+	}
+	files := []structures.EmoteFile{
+		{Name: "3x.webp", Width: 96, Height: 84},
+		{Name: "1x.webp", Width: 32, Height: 28},
+		{Name: "2x.webp", Width: 64, Height: 56},
+	}
+
+	tests := []struct {
+		name       string
+		flags      structures.EmoteFlag
+		version    *structures.EmoteVersion
+		visibility int32
+		simple     []string
+		status     int32
+		sizes      int
+	}{
+		{"listed and live", structures.EmoteFlagsListed, ver(structures.EmoteLifecycleLive, files...), 0, []string{}, EmoteStatusLive, 3},
+		{"unlisted", 0, ver(structures.EmoteLifecycleLive, files...), EmoteVisibilityUnlisted, []string{"UNLISTED"}, EmoteStatusLive, 3},
+		{
+			"private zero width", structures.EmoteFlagsListed | structures.EmoteFlagsPrivate | structures.EmoteFlagsZeroWidth,
+			ver(structures.EmoteLifecycleLive, files...),
+			EmoteVisibilityPrivate | EmoteVisibilityZeroWidth, []string{"PRIVATE", "ZERO_WIDTH"}, EmoteStatusLive, 3,
+		},
+		{"processing", structures.EmoteFlagsListed, ver(structures.EmoteLifecycleProcessing), 0, []string{}, EmoteStatusProcessing, 0},
+		{"deleted", structures.EmoteFlagsListed, ver(structures.EmoteLifecycleDeleted, files...), 0, []string{}, EmoteStatusDeleted, 3},
+		{"disabled", structures.EmoteFlagsListed, ver(structures.EmoteLifecycleDisabled, files...), 0, []string{}, EmoteStatusDisabled, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &structures.Emote{ID: primitive.NewObjectID(), Name: "peepoHappy", Flags: tt.flags}
+			m := NewEmote(e, tt.version, "https://cdn.example.com/")
+
+			if m.ID != tt.version.ID.Hex() {
+				t.Errorf("expected the version's id, got %s", m.ID)
+			}
+			if m.Visibility != tt.visibility {
+				t.Errorf("expected visibility %d, got %d", tt.visibility, m.Visibility)
+			}
+			if len(m.VisibilitySimple) != len(tt.simple) {
+				t.Fatalf("expected %v, got %v", tt.simple, m.VisibilitySimple)
+			}
+			for i, v := range tt.simple {
+				if m.VisibilitySimple[i] != v {
+					t.Errorf("expected %v, got %v", tt.simple, m.VisibilitySimple)
+				}
+			}
+			if m.Status != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, m.Status)
+			}
+			if m.Owner != nil || m.Tags == nil {
+				t.Errorf("expected no owner and empty tags, got %+v and %v", m.Owner, m.Tags)
+			}
+
+			// Only webp is served, smallest first
+			if len(m.URLs) != tt.sizes {
+				t.Fatalf("expected %d urls, got %v", tt.sizes, m.URLs)
+			}
+			if tt.sizes > 0 {
+				want := "https://cdn.example.com/emote/" + tt.version.ID.Hex() + "/1x.webp"
+				if m.URLs[0][0] != "1" || m.URLs[0][1] != want {
+					t.Errorf("expected %s first, got %v", want, m.URLs[0])
+				}
+				if m.Width != [4]int32{32, 64, 96, 0} || m.Height != [4]int32{28, 56, 84, 0} {
+					t.Errorf("unexpected sizes %v x %v", m.Width, m.Height)
+				}
+			}
+		})
+	}
+}
+
+func TestNewEmoteName(t *testing.T) {
+	e := &structures.Emote{Name: "emote"}
+	tests := []struct {
+		name    string
+		version string
+		want    string
+	}{
+		{"the emote's name", "", "emote"},
+		{"the version's name", "version", "version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewEmote(e, &structures.EmoteVersion{Name: tt.version}, "")
+			if m.Name != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, m.Name)
+			}
+		})
+	}
+}
+
+func TestNewUser(t *testing.T) {
+	low := &structures.Role{ID: primitive.NewObjectID(), Name: "Subscriber", Position: 1}
+	high := &structures.Role{ID: primitive.NewObjectID(), Name: "Moderator", Position: 5, Allowed: structures.RolePermissionEditAnyEmote}
+
+	tests := []struct {
+		name     string
+		user     *structures.User
+		twitchID string
+		role     string
+	}{
+		{"no roles or connections", &structures.User{}, "", ""},
+		{"highest role", &structures.User{Roles: []*structures.Role{low, high, nil}}, "", "Moderator"},
+		{
+			"twitch connection", &structures.User{Connections: []*structures.UserConnection{
+				{ID: "yt", Platform: structures.UserConnectionPlatformYouTube},
+				{ID: "123", Platform: structures.UserConnectionPlatformTwitch},
+			}}, "123", "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := NewUser(tt.user)
+			if u.TwitchID != tt.twitchID {
+				t.Errorf("expected twitch id %q, got %q", tt.twitchID, u.TwitchID)
+			}
+			if u.Role.Name != tt.role {
+				t.Errorf("expected role %q, got %q", tt.role, u.Role.Name)
+			}
+		})
+	}
+
+	if NewUser(nil) != nil {
+		t.Error("expected no user")
+	}
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"github.com/SevenTV/Common/structures/v3"
+)
+
+// User: a user, in the v2 format
+type User struct {
+	ID          string `json:"id"`
+	TwitchID    string `json:"twitch_id"`
+	Login       string `json:"login"`
+	DisplayName string `json:"display_name"`
+	Role        Role   `json:"role"`
+}
+
+// Role: a role, in the v2 format
+type Role struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Position int32  `json:"position"`
+	Color    int32  `json:"color"`
+	Allowed  int64  `json:"allowed"`
+	Denied   int64  `json:"denied"`
+}
+
+// NewUser: translate a user into the v2 format
+//
+// v2 users have a single role, so the user's highest role is used
+func NewUser(u *structures.User) *User {
+	if u == nil {
+		return nil
+	}
+
+	twitchID := ""
+	for _, c := range u.Connections {
+		if c.Platform == structures.UserConnectionPlatformTwitch {
+			twitchID = c.ID
+			break
+		}
+	}
+
+	role := Role{}
+	var top *structures.Role
+	for _, r := range u.Roles {
+		if r != nil && (top == nil || r.Position > top.Position) {
+			top = r
+		}
+	}
+	if top != nil {
+		role = Role{
+			ID:       top.ID.Hex(),
+			Name:     top.Name,
+			Position: top.Position,
+			Color:    top.Color,
+			Allowed:  int64(top.Allowed),
+			Denied:   int64(top.Denied),
+		}
+	}
+
+	return &User{
+		ID:          u.ID.Hex(),
+		TwitchID:    twitchID,
+		Login:       u.Username,
+		DisplayName: u.DisplayName,
+		Role:        role,
+	}
+}
@@ -0,0 +1,42 @@
+package emotes
+
+import (
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v2/middleware"
+	"github.com/SevenTV/REST/src/server/v2/model"
+)
+
+type globalEmotes struct {
+	Ctx global.Context
+}
+
+func NewGlobal(gCtx global.Context) rest.Route {
+	return &globalEmotes{gCtx}
+}
+
+func (r *globalEmotes) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/emotes/global",
+		Method: rest.GET,
+		Middleware: []rest.Middleware{
+			middleware.Deprecated(r.Ctx),
+		},
+	}
+}
+
+// Get Global Emotes (v2)
+// List the emotes of the system's emote set, which are enabled in every channel, in the v2 format
+func (r *globalEmotes) Handler(ctx *rest.Ctx) rest.APIError {
+	sys := r.Ctx.Inst().Mongo.System(ctx)
+	if sys.EmoteSetID.IsZero() {
+		return ctx.JSON(rest.OK, []*model.Emote{})
+	}
+
+	result, err := SetEmotes(ctx, r.Ctx, sys.EmoteSetID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(rest.OK, result)
+}
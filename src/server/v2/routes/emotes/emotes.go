@@ -0,0 +1,72 @@
+package emotes
+
+import (
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v2/middleware"
+	"github.com/SevenTV/REST/src/server/v2/model"
+	v3middleware "github.com/SevenTV/REST/src/server/v3/middleware"
+	"github.com/SevenTV/REST/src/visibility"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type Route struct {
+	Ctx global.Context
+}
+
+func New(gCtx global.Context) rest.Route {
+	return &Route{gCtx}
+}
+
+func (r *Route) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/emotes/{emote}",
+		Method: rest.GET,
+		Middleware: []rest.Middleware{
+			middleware.Deprecated(r.Ctx),
+			v3middleware.OptionalAuth(r.Ctx),
+		},
+	}
+}
+
+// Get Emote (v2)
+// Get a single emote by the ID of one of its versions, in the v2 format. Emotes hidden from the actor are unknown
+func (r *Route) Handler(ctx *rest.Ctx) rest.APIError {
+	id, err := ctx.ObjectIDParam("emote")
+	if err != nil {
+		return err
+	}
+
+	actor, _ := ctx.GetActor()
+	visible, er := visibility.Filter(ctx, r.Ctx, actor, false)
+	if er != nil {
+		ctx.Log().WithError(er).Error("mongo, failed to find the emotes visible to the actor")
+		return errors.ErrInternalServerError()
+	}
+
+	emote := &structures.Emote{}
+	if er = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).FindOne(ctx, bson.M{
+		"$and": bson.A{bson.M{"versions.id": id}, visible},
+	}).Decode(emote); er != nil {
+		if er == mongo.ErrNoDocuments {
+			return errors.ErrUnknownEmote()
+		}
+		ctx.Log().WithError(er).Error("mongo, failed to find emote")
+		return errors.ErrInternalServerError()
+	}
+
+	ver, _ := structures.NewEmoteBuilder(emote).GetVersion(id)
+	if ver == nil || ver.State.Lifecycle == structures.EmoteLifecycleDeleted {
+		return errors.ErrUnknownEmote()
+	}
+
+	if er = loadOwners(ctx, r.Ctx, []*structures.Emote{emote}); er != nil {
+		ctx.Log().WithError(er).Error("mongo, failed to find the owner of emote")
+		return errors.ErrInternalServerError()
+	}
+
+	return ctx.JSON(rest.OK, model.NewEmote(emote, ver, r.Ctx.Config().CdnURL))
+}
@@ -0,0 +1,133 @@
+package emotes
+
+import (
+	"context"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/structures/v3/aggregations"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v2/model"
+	"github.com/SevenTV/REST/src/visibility"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SetEmotes: get the emotes of an emote set which the actor may see, in the v2 format
+//
+// Each emote is served as its latest live version, under the name it was given in the set.
+// Emotes without a live version are left out
+func SetEmotes(ctx *rest.Ctx, gCtx global.Context, setID primitive.ObjectID) ([]*model.Emote, rest.APIError) {
+	set := &structures.EmoteSet{}
+	if err := gCtx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).FindOne(ctx, bson.M{
+		"_id": setID,
+	}).Decode(set); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrUnknownEmoteSet()
+		}
+		ctx.Log().WithError(err).Error("mongo, failed to find emote set")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(set.Emotes))
+	for _, ae := range set.Emotes {
+		if ae != nil {
+			ids = append(ids, ae.ID)
+		}
+	}
+
+	actor, _ := ctx.GetActor()
+	visible, err := visibility.Filter(ctx, gCtx, actor, false)
+	if err != nil {
+		ctx.Log().WithError(err).Error("mongo, failed to find the emotes visible to the actor")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	cur, err := gCtx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Find(ctx, bson.M{
+		"$and": bson.A{bson.M{"_id": bson.M{"$in": ids}}, visible},
+	})
+	if err != nil {
+		ctx.Log().WithError(err).Error("mongo, failed to find the emotes of emote set")
+		return nil, errors.ErrInternalServerError()
+	}
+	emotes := []*structures.Emote{}
+	if err = cur.All(ctx, &emotes); err != nil {
+		ctx.Log().WithError(err).Error("mongo, failed to decode the emotes of emote set")
+		return nil, errors.ErrInternalServerError()
+	}
+	if err = loadOwners(ctx, gCtx, emotes); err != nil {
+		ctx.Log().WithError(err).Error("mongo, failed to find the owners of emotes")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	byID := make(map[primitive.ObjectID]*structures.Emote, len(emotes))
+	for _, e := range emotes {
+		byID[e.ID] = e
+	}
+
+	result := []*model.Emote{}
+	for _, ae := range set.Emotes {
+		if ae == nil || byID[ae.ID] == nil {
+			continue
+		}
+
+		e := byID[ae.ID]
+		ver := liveVersion(e)
+		if ver == nil {
+			continue
+		}
+
+		m := model.NewEmote(e, ver, gCtx.Config().CdnURL)
+		if ae.Name != "" {
+			m.Name = ae.Name
+		}
+		result = append(result, m)
+	}
+
+	return result, nil
+}
+
+// liveVersion: the most recent version of an emote which is live, or nil if there is none
+func liveVersion(e *structures.Emote) *structures.EmoteVersion {
+	for i := len(e.Versions) - 1; i >= 0; i-- {
+		if v := e.Versions[i]; v != nil && v.State.Lifecycle == structures.EmoteLifecycleLive {
+			return v
+		}
+	}
+	return nil
+}
+
+// loadOwners: populate the owners of emotes, with their roles
+func loadOwners(ctx context.Context, gCtx global.Context, emotes []*structures.Emote) error {
+	if len(emotes) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, len(emotes))
+	for i, e := range emotes {
+		ids[i] = e.OwnerID
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"_id": bson.M{"$in": ids}}}}}
+	pipeline = append(pipeline, aggregations.UserRelationRoles...)
+	cur, err := gCtx.Inst().Mongo.Collection(mongo.CollectionNameUsers).Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+
+	users := []*structures.User{}
+	if err = cur.All(ctx, &users); err != nil {
+		return err
+	}
+
+	byID := make(map[primitive.ObjectID]*structures.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+	for _, e := range emotes {
+		e.Owner = byID[e.OwnerID]
+	}
+	return nil
+}
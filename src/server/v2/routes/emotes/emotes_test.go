@@ -0,0 +1,191 @@
+package emotes_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server"
+	"github.com/SevenTV/REST/src/server/v2/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newEmote: an emote owned by a user, with versions in the given lifecycles
+func newEmote(owner *structures.User, name string, flags structures.EmoteFlag, lifecycles ...structures.EmoteLifecycle) *structures.Emote {
+	e := &structures.Emote{ID: primitive.NewObjectID(), OwnerID: owner.ID, Name: name, Flags: flags, Tags: []string{}}
+	for _, l := range lifecycles {
+		e.Versions = append(e.Versions, &structures.EmoteVersion{
+			ID:    primitive.NewObjectID(),
+			State: structures.EmoteState{Lifecycle: l},
+		})
+	}
+	return e
+}
+
+// newContext: a global context serving the legacy API
+func newContext(t *testing.T) global.Context {
+	config := fakes.NewConfig()
+	config.Legacy.Enabled = true
+	gCtx, err := fakes.NewContext(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gCtx
+}
+
+func TestGetEmote(t *testing.T) {
+	gCtx := newContext(t)
+	mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+
+	mod := &structures.Role{ID: primitive.NewObjectID(), Name: "Mod", Position: 2, Allowed: structures.RolePermissionEditAnyEmote}
+	users := map[string]*structures.User{
+		"owner":  {ID: primitive.NewObjectID(), Username: "owner", RoleIDs: []primitive.ObjectID{mod.ID}},
+		"editor": {ID: primitive.NewObjectID(), Username: "editor", RoleIDs: []primitive.ObjectID{}},
+		"mod":    {ID: primitive.NewObjectID(), Username: "mod", RoleIDs: []primitive.ObjectID{mod.ID}},
+		"user":   {ID: primitive.NewObjectID(), Username: "user", RoleIDs: []primitive.ObjectID{}},
+	}
+	users["owner"].Editors = []*structures.UserEditor{
+		{ID: users["editor"].ID, Permissions: structures.UserEditorPermissionManageOwnedEmotes},
+	}
+	mgo.Seed(mongo.CollectionNameRoles, mod)
+	for _, u := range users {
+		mgo.Seed(mongo.CollectionNameUsers, u)
+	}
+
+	public := newEmote(users["owner"], "public", structures.EmoteFlagsListed, structures.EmoteLifecycleLive, structures.EmoteLifecycleDeleted)
+	private := newEmote(users["owner"], "private", structures.EmoteFlagsListed|structures.EmoteFlagsPrivate, structures.EmoteLifecycleLive)
+	unlisted := newEmote(users["owner"], "unlisted", 0, structures.EmoteLifecycleLive)
+	mgo.Seed(mongo.CollectionNameEmotes, public, private, unlisted)
+
+	h := server.NewHarness(gCtx)
+	tests := []struct {
+		name    string
+		user    string
+		version primitive.ObjectID
+		status  int
+	}{
+		{"public", "", public.Versions[0].ID, 200},
+		{"unlisted emotes can be looked up", "", unlisted.Versions[0].ID, 200},
+		{"deleted version", "", public.Versions[1].ID, 404},
+		{"unknown version", "", primitive.NewObjectID(), 404},
+		{"private, anonymous", "", private.Versions[0].ID, 404},
+		{"private, other user", "user", private.Versions[0].ID, 404},
+		{"private, owner", "owner", private.Versions[0].ID, 200},
+		{"private, editor", "editor", private.Versions[0].ID, 200},
+		{"private, edit any emote", "mod", private.Versions[0].ID, 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.user != "" {
+				tok, err := h.Token(users[tt.user])
+				if err != nil {
+					t.Fatal(err)
+				}
+				headers["Authorization"] = "Bearer " + tok
+			}
+
+			res := h.Request("GET", "/v2/emotes/"+tt.version.Hex(), nil, headers)
+			if res.StatusCode() != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, res.StatusCode(), res.Body())
+			}
+			if tt.status != 200 {
+				return
+			}
+
+			m := &model.Emote{}
+			if err := json.Unmarshal(res.Body(), m); err != nil {
+				t.Fatal(err)
+			}
+			if m.ID != tt.version.Hex() {
+				t.Errorf("expected version %s, got %s", tt.version.Hex(), m.ID)
+			}
+			if m.Owner == nil || m.Owner.Login != "owner" || m.Owner.Role.Name != "Mod" {
+				t.Errorf("expected the owner with their role, got %+v", m.Owner)
+			}
+		})
+	}
+}
+
+func TestGlobalEmotes(t *testing.T) {
+	owner := &structures.User{ID: primitive.NewObjectID(), Username: "owner", RoleIDs: []primitive.ObjectID{}}
+	live := newEmote(owner, "live", structures.EmoteFlagsListed, structures.EmoteLifecycleLive, structures.EmoteLifecycleLive, structures.EmoteLifecycleProcessing)
+	aliased := newEmote(owner, "aliased", 0, structures.EmoteLifecycleLive)
+	private := newEmote(owner, "private", structures.EmoteFlagsPrivate, structures.EmoteLifecycleLive)
+	processing := newEmote(owner, "processing", structures.EmoteFlagsListed, structures.EmoteLifecycleProcessing)
+
+	set := &structures.EmoteSet{ID: primitive.NewObjectID(), Emotes: []*structures.ActiveEmote{
+		{ID: live.ID},
+		{ID: aliased.ID, Name: "alias"},
+		{ID: private.ID},
+		{ID: processing.ID},
+		{ID: primitive.NewObjectID()},
+	}}
+
+	tests := []struct {
+		name   string
+		system *structures.System
+		status int
+		// the names of the emotes served, and the version of the first
+		want    []string
+		version primitive.ObjectID
+	}{
+		{"no global set", nil, 200, []string{}, primitive.NilObjectID},
+		{"global set", &structures.System{ID: primitive.NewObjectID(), EmoteSetID: set.ID}, 200, []string{"live", "alias"}, live.Versions[1].ID},
+		{"missing set", &structures.System{ID: primitive.NewObjectID(), EmoteSetID: primitive.NewObjectID()}, 404, nil, primitive.NilObjectID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gCtx := newContext(t)
+			mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+			mgo.Seed(mongo.CollectionNameUsers, owner)
+			mgo.Seed(mongo.CollectionNameEmotes, live, aliased, private, processing)
+			mgo.Seed(mongo.CollectionNameEmoteSets, set)
+			if tt.system != nil {
+				mgo.Seed(mongo.CollectionNameSystem, tt.system)
+			}
+
+			res := server.NewHarness(gCtx).Request("GET", "/v2/emotes/global", nil, nil)
+			if res.StatusCode() != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, res.StatusCode(), res.Body())
+			}
+			if tt.status != 200 {
+				return
+			}
+
+			result := []*model.Emote{}
+			if err := json.Unmarshal(res.Body(), &result); err != nil {
+				t.Fatal(err)
+			}
+			if len(result) != len(tt.want) {
+				t.Fatalf("expected %v, got %s", tt.want, res.Body())
+			}
+			for i, name := range tt.want {
+				if result[i].Name != name {
+					t.Errorf("%d: expected %s, got %s", i, name, result[i].Name)
+				}
+			}
+			if len(result) > 0 && result[0].ID != tt.version.Hex() {
+				t.Errorf("expected the latest live version %s, got %s", tt.version.Hex(), result[0].ID)
+			}
+		})
+	}
+}
+
+func TestGlobalEmotesRoute(t *testing.T) {
+	// The static path must not be taken for an emote id
+	gCtx := newContext(t)
+	gCtx.Inst().Mongo.(*fakes.Mongo).Seed(mongo.CollectionNameSystem, bson.M{"_id": primitive.NewObjectID()})
+
+	res := server.NewHarness(gCtx).Request("GET", "/v2/emotes/global", nil, nil)
+	if res.StatusCode() != 200 || string(res.Body()) != "[]" {
+		t.Errorf("expected an empty list, got %d: %s", res.StatusCode(), res.Body())
+	}
+}
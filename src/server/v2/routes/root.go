@@ -0,0 +1,46 @@
+package routes
+
+import (
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v2/middleware"
+	"github.com/SevenTV/REST/src/server/v2/routes/emotes"
+	"github.com/SevenTV/REST/src/server/v2/routes/users"
+)
+
+type Route struct {
+	Ctx global.Context
+}
+
+func New(gCtx global.Context) rest.Route {
+	return &Route{gCtx}
+}
+
+func (r *Route) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/v2",
+		Method: rest.GET,
+		Children: []rest.Route{
+			emotes.New(r.Ctx),
+			emotes.NewGlobal(r.Ctx),
+			users.New(r.Ctx),
+		},
+		Middleware: []rest.Middleware{
+			middleware.Deprecated(r.Ctx),
+		},
+	}
+}
+
+func (r *Route) Handler(ctx *rest.Ctx) rest.APIError {
+	return ctx.JSON(rest.OK, &Response{
+		Online:     true,
+		Deprecated: true,
+		Sunset:     r.Ctx.Config().Legacy.Sunset,
+	})
+}
+
+type Response struct {
+	Online     bool   `json:"online"`
+	Deprecated bool   `json:"deprecated"`
+	Sunset     string `json:"sunset,omitempty"`
+}
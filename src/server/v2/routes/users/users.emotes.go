@@ -0,0 +1,61 @@
+package users
+
+import (
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v2/middleware"
+	"github.com/SevenTV/REST/src/server/v2/model"
+	"github.com/SevenTV/REST/src/server/v2/routes/emotes"
+	v3middleware "github.com/SevenTV/REST/src/server/v3/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type userEmotes struct {
+	Ctx global.Context
+}
+
+func newEmotes(gCtx global.Context) rest.Route {
+	return &userEmotes{gCtx}
+}
+
+func (r *userEmotes) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/emotes",
+		Method: rest.GET,
+		Middleware: []rest.Middleware{
+			middleware.Deprecated(r.Ctx),
+			v3middleware.OptionalAuth(r.Ctx),
+		},
+	}
+}
+
+// Get Channel Emotes (v2)
+// List the emotes enabled in a user's channel, in the v2 format
+func (r *userEmotes) Handler(ctx *rest.Ctx) rest.APIError {
+	key := ctx.Param("user")
+	user, err := findUser(ctx, r.Ctx, key)
+	if err != nil {
+		return err
+	}
+
+	// Prefer the channel the user was looked up by, then the first with an emote set
+	setID := primitive.NilObjectID
+	for _, conn := range user.Connections {
+		if conn.EmoteSetID.IsZero() {
+			continue
+		}
+		if setID.IsZero() || conn.ID == key {
+			setID = conn.EmoteSetID
+		}
+	}
+	if setID.IsZero() {
+		return ctx.JSON(rest.OK, []*model.Emote{})
+	}
+
+	result, err := emotes.SetEmotes(ctx, r.Ctx, setID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(rest.OK, result)
+}
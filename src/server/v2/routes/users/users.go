@@ -0,0 +1,82 @@
+package users
+
+import (
+	"strings"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/structures/v3/aggregations"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v2/middleware"
+	"github.com/SevenTV/REST/src/server/v2/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Route struct {
+	Ctx global.Context
+}
+
+func New(gCtx global.Context) rest.Route {
+	return &Route{gCtx}
+}
+
+func (r *Route) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/users/{user}",
+		Method: rest.GET,
+		Middleware: []rest.Middleware{
+			middleware.Deprecated(r.Ctx),
+		},
+		Children: []rest.Route{
+			newEmotes(r.Ctx),
+		},
+	}
+}
+
+// Get User (v2)
+// Get a single user by their ID, login or twitch ID, in the v2 format
+func (r *Route) Handler(ctx *rest.Ctx) rest.APIError {
+	user, err := findUser(ctx, r.Ctx, ctx.Param("user"))
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(rest.OK, model.NewUser(user))
+}
+
+// findUser: find a user with their roles, by any of the identifiers v2 accepts in place of the user's ID
+func findUser(ctx *rest.Ctx, gCtx global.Context, key string) (*structures.User, rest.APIError) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"username": strings.ToLower(key)},
+		bson.M{"connections.id": key},
+	}}
+	if id, err := primitive.ObjectIDFromHex(key); err == nil {
+		filter = bson.M{"_id": id}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$limit", Value: 1}},
+	}
+	pipeline = append(pipeline, aggregations.UserRelationRoles...)
+
+	cur, err := gCtx.Inst().Mongo.Collection(mongo.CollectionNameUsers).Aggregate(ctx, pipeline)
+	if err != nil {
+		ctx.Log().WithError(err).Error("mongo, failed to find user")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	users := []*structures.User{}
+	if err = cur.All(ctx, &users); err != nil {
+		ctx.Log().WithError(err).Error("mongo, failed to decode user")
+		return nil, errors.ErrInternalServerError()
+	}
+	if len(users) == 0 {
+		return nil, errors.ErrUnknownUser()
+	}
+
+	return users[0], nil
+}
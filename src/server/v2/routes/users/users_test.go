@@ -0,0 +1,130 @@
+package users_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/server"
+	"github.com/SevenTV/REST/src/server/v2/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUsers(t *testing.T) {
+	config := fakes.NewConfig()
+	config.Legacy.Enabled = true
+	gCtx, err := fakes.NewContext(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+
+	// Connections must carry their platform's data, even if empty
+	data, _ := bson.Marshal(bson.M{})
+	twitchSet := &structures.EmoteSet{ID: primitive.NewObjectID()}
+	youtubeSet := &structures.EmoteSet{ID: primitive.NewObjectID()}
+	streamer := &structures.User{
+		ID:       primitive.NewObjectID(),
+		Username: "streamer",
+		RoleIDs:  []primitive.ObjectID{},
+		Connections: []*structures.UserConnection{
+			{ID: "123", Platform: structures.UserConnectionPlatformTwitch, EmoteSetID: twitchSet.ID, Data: data},
+			{ID: "yt", Platform: structures.UserConnectionPlatformYouTube, EmoteSetID: youtubeSet.ID, Data: data},
+		},
+	}
+	viewer := &structures.User{ID: primitive.NewObjectID(), Username: "viewer", RoleIDs: []primitive.ObjectID{}}
+	if err := mgo.Seed(mongo.CollectionNameUsers, streamer, viewer); err != nil {
+		t.Fatal(err)
+	}
+
+	emote := func(name string, flags structures.EmoteFlag) *structures.Emote {
+		return &structures.Emote{
+			ID: primitive.NewObjectID(), OwnerID: viewer.ID, Name: name, Flags: flags,
+			Versions: []*structures.EmoteVersion{{ID: primitive.NewObjectID(), State: structures.EmoteState{Lifecycle: structures.EmoteLifecycleLive}}},
+		}
+	}
+	kappa, private, pog := emote("Kappa", structures.EmoteFlagsListed), emote("private", structures.EmoteFlagsPrivate), emote("Pog", 0)
+	mgo.Seed(mongo.CollectionNameEmotes, kappa, private, pog)
+	twitchSet.Emotes = []*structures.ActiveEmote{{ID: kappa.ID}, {ID: private.ID}}
+	youtubeSet.Emotes = []*structures.ActiveEmote{{ID: pog.ID}}
+	mgo.Seed(mongo.CollectionNameEmoteSets, twitchSet, youtubeSet)
+
+	h := server.NewHarness(gCtx)
+	t.Run("lookup", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			key    string
+			status int
+		}{
+			{"by id", streamer.ID.Hex(), 200},
+			{"by login", "Streamer", 200},
+			{"by connection", "123", 200},
+			{"unknown", "nobody", 404},
+			{"unknown id", primitive.NewObjectID().Hex(), 404},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				res := h.Request("GET", "/v2/users/"+tt.key, nil, nil)
+				if res.StatusCode() != tt.status {
+					t.Fatalf("expected status %d, got %d: %s", tt.status, res.StatusCode(), res.Body())
+				}
+				if tt.status != 200 {
+					return
+				}
+
+				u := &model.User{}
+				if err := json.Unmarshal(res.Body(), u); err != nil {
+					t.Fatal(err)
+				}
+				if u.ID != streamer.ID.Hex() || u.TwitchID != "123" {
+					t.Errorf("expected the streamer, got %+v", u)
+				}
+			})
+		}
+	})
+
+	t.Run("channel emotes", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			key    string
+			status int
+			want   []string
+		}{
+			{"by id", streamer.ID.Hex(), 200, []string{"Kappa"}},
+			{"by twitch connection", "123", 200, []string{"Kappa"}},
+			{"by youtube connection", "yt", 200, []string{"Pog"}},
+			{"no emote set", "viewer", 200, []string{}},
+			{"unknown user", "nobody", 404, nil},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				res := h.Request("GET", "/v2/users/"+tt.key+"/emotes", nil, nil)
+				if res.StatusCode() != tt.status {
+					t.Fatalf("expected status %d, got %d: %s", tt.status, res.StatusCode(), res.Body())
+				}
+				if tt.status != 200 {
+					return
+				}
+
+				result := []*model.Emote{}
+				if err := json.Unmarshal(res.Body(), &result); err != nil {
+					t.Fatal(err)
+				}
+				if len(result) != len(tt.want) {
+					t.Fatalf("expected %v, got %s", tt.want, res.Body())
+				}
+				for i, name := range tt.want {
+					if result[i].Name != name || result[i].Owner == nil || result[i].Owner.Login != "viewer" {
+						t.Errorf("%d: expected %s with its owner, got %+v", i, name, result[i])
+					}
+				}
+			})
+		}
+	})
+}
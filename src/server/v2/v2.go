@@ -0,0 +1,16 @@
+package v2
+
+import (
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v2/routes"
+)
+
+// API: the legacy v2 API, kept for clients which have not yet moved to v3
+//
+// Responses are translated from the v3 structures into the v2 formats, and carry deprecation headers.
+// Only the lookups used by the browser extensions are served: single emotes and users, global emotes
+// and a user's channel emotes. Listing, searching and writing are left to v3
+func API(gCtx global.Context, router *rest.Router) rest.Route {
+	return routes.New(gCtx)
+}
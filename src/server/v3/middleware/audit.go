@@ -1,21 +1,55 @@
 package middleware
 
 import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/Common/utils"
+	"github.com/SevenTV/REST/src/audit"
 	"github.com/SevenTV/REST/src/global"
 	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Audit: persist an audit log once the request completes.
+// Logs are written for every mutating request, and for any other request whose handler recorded changes
 func Audit(gCtx global.Context) rest.Middleware {
 	return func(ctx *rest.Ctx) rest.APIError {
-		go func() {
-			for ev := range ctx.Lifecycle.Listen(ctx) {
-				if ev.Event == rest.LifecyclePhaseCompleted {
-					// TODO: Write Audit Logs here
-					{
-					}
-				}
+		ctx.OnComplete(func(ctx *rest.Ctx, res rest.RequestResult) {
+			log := ctx.AuditLog()
+			method := utils.B2S(ctx.Method())
+			if (method == "GET" || method == "HEAD" || method == "OPTIONS") && !log.HasChanges() {
+				return
+			}
+			if gCtx.Inst().Mongo == nil {
+				return
+			}
+
+			log.ID = primitive.NewObjectIDFromTimestamp(time.Now())
+			log.Method = method
+			log.Route = ctx.Route()
+			log.Path = string(ctx.Path())
+			log.Status = int(res.Status)
+			log.Timestamp = log.ID.Timestamp()
+			if res.Actor != nil {
+				log.ActorID = res.Actor.ID
+			}
+			if res.Error != nil {
+				log.ErrorCode = res.Error.Code()
+			}
+
+			// The request may already be canceled at this point, so a separate context is used
+			lctx, cancel := context.WithTimeout(gCtx, time.Second*5)
+			defer cancel()
+
+			if _, err := gCtx.Inst().Mongo.Collection(audit.CollectionName).InsertOne(lctx, log); err != nil {
+				ctx.Log().WithError(err).WithFields(logrus.Fields{
+					"route":  log.Route,
+					"method": log.Method,
+				}).Error("mongo, failed to write audit log")
 			}
-		}()
+		})
 		return nil
 	}
 }
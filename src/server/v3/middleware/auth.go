@@ -12,7 +12,6 @@ import (
 	"github.com/SevenTV/Common/utils"
 	"github.com/SevenTV/REST/src/global"
 	"github.com/SevenTV/REST/src/server/rest"
-	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -55,12 +54,12 @@ func Auth(gCtx global.Context) rest.Middleware {
 				return errors.ErrUnauthorized().SetFields(errors.Fields{"message": "Token has Unknown Bound User"})
 			}
 
-			logrus.WithError(err).Error("mongo")
+			ctx.Log().WithError(err).Error("mongo")
 			return errors.ErrInternalServerError()
 		}
 		cur.Next(ctx)
 		if err := cur.Decode(user); err != nil {
-			logrus.WithError(err).Error("mongo")
+			ctx.Log().WithError(err).Error("mongo")
 			return errors.ErrInternalServerError()
 		}
 
@@ -94,3 +93,14 @@ func Auth(gCtx global.Context) rest.Middleware {
 		return nil
 	}
 }
+
+// OptionalAuth: authenticate the request if it carries credentials, for routes which are also open to anonymous clients
+func OptionalAuth(gCtx global.Context) rest.Middleware {
+	mw := Auth(gCtx)
+	return func(ctx *rest.Ctx) rest.APIError {
+		if len(ctx.Request.Header.Peek("Authorization")) == 0 {
+			return nil
+		}
+		return mw(ctx)
+	}
+}
@@ -1,12 +1,21 @@
 package middleware
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/SevenTV/Common/utils"
 	"github.com/SevenTV/REST/src/global"
 	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/sirupsen/logrus"
 )
 
 func SetCacheControl(gCtx global.Context, maxAge int, args []string) rest.Middleware {
@@ -21,3 +30,197 @@ func SetCacheControl(gCtx global.Context, maxAge int, args []string) rest.Middle
 		return nil
 	}
 }
+
+const (
+	cacheKeyPrefix    = "rest:cache:"
+	cacheTagKeyPrefix = "rest:cache-tag:"
+)
+
+var cacheInvalidation sync.Once
+
+// Cache: serve GET requests from a response cache in redis, keyed by path, query and whether the request is authenticated
+//
+// Entries are tagged with the given tags and any added by the handler with ctx.AddCacheTags(),
+// and are dropped early when a tag is purged. Responses with a private or no-store Cache-Control are never stored,
+// so routes whose output varies by actor should set one of those
+//
+// This should be placed after the Auth middleware, if any, so that the actor is known
+func Cache(gCtx global.Context, ttl time.Duration, tags ...string) rest.Middleware {
+	cacheInvalidation.Do(func() {
+		go listenCacheInvalidation(gCtx)
+	})
+
+	return func(ctx *rest.Ctx) rest.APIError {
+		if gCtx.Inst().Redis == nil || !ctx.IsGet() {
+			return nil
+		}
+
+		key := cacheKey(ctx)
+
+		// Serve the stored response, if there is one
+		if b, err := gCtx.Inst().Redis.RawClient().Get(ctx, key).Bytes(); err == nil {
+			entry := cacheEntry{}
+			if err = json.Unmarshal(b, &entry); err == nil {
+				ctx.SetStatusCode(entry.Status)
+				ctx.SetContentType(entry.ContentType)
+				ctx.SetBody(entry.Body)
+				for k, v := range entry.Headers {
+					ctx.Response.Header.Set(k, v)
+				}
+				ctx.Response.Header.Set("X-Cache", "HIT")
+				ctx.Halt()
+				return nil
+			}
+		}
+		ctx.Response.Header.Set("X-Cache", "MISS")
+
+		// Store the response once the handler is done
+		ctx.OnComplete(func(ctx *rest.Ctx, res rest.RequestResult) {
+			if res.Error != nil || res.Status != rest.OK {
+				return
+			}
+			cc := utils.B2S(ctx.Response.Header.Peek("Cache-Control"))
+			if strings.Contains(cc, "private") || strings.Contains(cc, "no-store") {
+				return
+			}
+
+			headers := map[string]string{}
+			for _, k := range cachedHeaders {
+				if v := ctx.Response.Header.Peek(k); len(v) > 0 {
+					headers[k] = string(v)
+				}
+			}
+			b, _ := json.Marshal(&cacheEntry{
+				Status:      res.Status,
+				ContentType: string(ctx.Response.Header.ContentType()),
+				Body:        ctx.Response.Body(),
+				Headers:     headers,
+			})
+
+			lctx, cancel := context.WithTimeout(gCtx, time.Second*5)
+			defer cancel()
+
+			pipe := gCtx.Inst().Redis.RawClient().TxPipeline()
+			pipe.Set(lctx, key, b, ttl)
+			for _, tag := range append(append([]string{}, tags...), ctx.CacheTags()...) {
+				pipe.SAdd(lctx, cacheTagKeyPrefix+tag, key)
+				pipe.Expire(lctx, cacheTagKeyPrefix+tag, ttl)
+			}
+			if _, err := pipe.Exec(lctx); err != nil {
+				ctx.Log().WithError(err).Error("redis, failed to store cached response")
+			}
+		})
+
+		return nil
+	}
+}
+
+// PurgeCache: drop all cached responses with any of the given tags, or every cached response if no tag is given
+//
+// Returns the amount of responses removed
+func PurgeCache(ctx context.Context, gCtx global.Context, tags ...string) (int64, error) {
+	cl := gCtx.Inst().Redis.RawClient()
+
+	keys := []string{}
+	if len(tags) == 0 {
+		for _, pattern := range []string{cacheKeyPrefix + "*", cacheTagKeyPrefix + "*"} {
+			iter := cl.Scan(ctx, 0, pattern, 500).Iterator()
+			for iter.Next(ctx) {
+				keys = append(keys, iter.Val())
+			}
+			if err := iter.Err(); err != nil {
+				return 0, err
+			}
+		}
+	} else {
+		for _, tag := range tags {
+			members, err := cl.SMembers(ctx, cacheTagKeyPrefix+tag).Result()
+			if err != nil {
+				return 0, err
+			}
+			keys = append(keys, members...)
+			keys = append(keys, cacheTagKeyPrefix+tag)
+		}
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	// Count only the responses, not the tag sets
+	n := int64(0)
+	for _, k := range keys {
+		if strings.HasPrefix(k, cacheKeyPrefix) {
+			n++
+		}
+	}
+	if err := cl.Del(ctx, keys...).Err(); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// listenCacheInvalidation: purge cached responses affected by object events, for as long as the global context lives
+//
+// Only responses tagged with the changed object are dropped. Lists it may now appear in are left to expire
+func listenCacheInvalidation(gCtx global.Context) {
+	if gCtx.Inst().Redis == nil {
+		return
+	}
+
+	sub := gCtx.Inst().Redis.RawClient().PSubscribe(gCtx, "7tv-events:sub:emotes:*")
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			id := strings.TrimPrefix(msg.Channel, "7tv-events:sub:emotes:")
+			if _, err := PurgeCache(gCtx, gCtx, "emotes:"+id); err != nil {
+				logrus.WithError(err).WithField("emote_id", id).Error("redis, failed to purge cached responses")
+			}
+		case <-gCtx.Done():
+			return
+		}
+	}
+}
+
+func cacheKey(ctx *rest.Ctx) string {
+	// Normalize the query so that argument order does not matter
+	args := [][2]string{}
+	ctx.QueryArgs().VisitAll(func(key, value []byte) {
+		args = append(args, [2]string{string(key), string(value)})
+	})
+	sort.Slice(args, func(i, j int) bool {
+		if args[i][0] == args[j][0] {
+			return args[i][1] < args[j][1]
+		}
+		return args[i][0] < args[j][0]
+	})
+	q := url.Values{}
+	for _, a := range args {
+		q.Add(a[0], a[1])
+	}
+
+	_, authed := ctx.GetActor()
+	if len(ctx.Request.Header.Peek("Authorization")) > 0 {
+		authed = true
+	}
+
+	h := sha256.Sum256([]byte(q.Encode()))
+	return fmt.Sprintf("%s%s:%s:%s", cacheKeyPrefix, string(ctx.Path()), utils.Ternary(authed, "auth", "anon").(string), hex.EncodeToString(h[:]))
+}
+
+type cacheEntry struct {
+	Status      rest.HttpStatusCode `json:"status"`
+	ContentType string              `json:"content_type"`
+	Body        []byte              `json:"body"`
+	Headers     map[string]string   `json:"headers,omitempty"`
+}
+
+// The response headers which are stored along with the body, as they describe it (i.e the page of a list)
+var cachedHeaders = []string{"Link", "X-Total-Count"}
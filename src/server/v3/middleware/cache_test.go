@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/server/rest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCache(t *testing.T) {
+	lctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gCtx, err := fakes.NewContext(lctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The invalidation listener is started once, for the context of the first cache
+	cacheInvalidation = sync.Once{}
+	actor := &structures.User{ID: primitive.NewObjectID()}
+
+	type step struct {
+		name   string
+		method string
+		uri    string
+		actor  *structures.User
+		// the response of the handler, if it runs
+		body    string
+		private bool
+		tags    []string
+		// an emote change event to publish before the request
+		publish string
+		// the expected X-Cache header, and body served
+		cache string
+		want  string
+	}
+
+	steps := []step{
+		{name: "first request is stored", uri: "/emotes?a=1&b=2", body: "one", tags: []string{"emotes:a"}, cache: "MISS", want: "one"},
+		{name: "same request is served from the cache", uri: "/emotes?a=1&b=2", body: "two", cache: "HIT", want: "one"},
+		{name: "query order does not matter", uri: "/emotes?b=2&a=1", body: "two", cache: "HIT", want: "one"},
+		{name: "other query", uri: "/emotes?a=2", body: "three", tags: []string{"emotes:b"}, cache: "MISS", want: "three"},
+		{name: "authenticated requests are kept apart", uri: "/emotes?a=1&b=2", actor: actor, body: "mine", private: true, cache: "MISS", want: "mine"},
+		{name: "private responses are not stored", uri: "/emotes?a=1&b=2", actor: actor, body: "mine again", private: true, cache: "MISS", want: "mine again"},
+		{name: "other methods bypass the cache", method: "POST", uri: "/emotes?a=1&b=2", body: "created", cache: "", want: "created"},
+		{name: "a change purges the entries tagged with it", publish: "a", uri: "/emotes?a=1&b=2", body: "four", cache: "MISS", want: "four"},
+		{name: "entries not tagged with the change are kept", uri: "/emotes?a=2", body: "five", cache: "HIT", want: "three"},
+	}
+
+	mw := Cache(gCtx, time.Minute, "emotes")
+	for _, s := range steps {
+		if s.publish != "" {
+			// The purge happens on the listener's goroutine, which may not have subscribed yet
+			deadline := time.Now().Add(time.Second)
+			for len(gCtx.Inst().Redis.(*fakes.Redis).Keys(cacheTagKeyPrefix+"emotes:"+s.publish)) > 0 && time.Now().Before(deadline) {
+				if err := gCtx.Inst().Redis.RawClient().Publish(gCtx, "7tv-events:sub:emotes:"+s.publish, "1").Err(); err != nil {
+					t.Fatal(err)
+				}
+				time.Sleep(time.Millisecond * 10)
+			}
+		}
+
+		method := s.method
+		if method == "" {
+			method = "GET"
+		}
+		ctx := newTestCtx(method, s.uri, "10.0.0.1")
+		if s.actor != nil {
+			ctx.Request.Header.Set("Authorization", "Bearer x")
+			ctx.SetActor(s.actor)
+		}
+
+		if err := mw(ctx); err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if !ctx.Halted() {
+			ctx.AddCacheTags(s.tags...)
+			if s.private {
+				ctx.Response.Header.Set("Cache-Control", "private")
+			}
+			ctx.SetStatusCode(rest.OK)
+			ctx.SetBodyString(s.body)
+		}
+		ctx.Complete(nil)
+
+		if got := string(ctx.Response.Header.Peek("X-Cache")); got != s.cache {
+			t.Errorf("%s: expected X-Cache %q, got %q", s.name, s.cache, got)
+		}
+		if got := string(ctx.Response.Body()); got != s.want {
+			t.Errorf("%s: expected body %q, got %q", s.name, s.want, got)
+		}
+	}
+}
+
+func TestPurgeCache(t *testing.T) {
+	tests := []struct {
+		name   string
+		tags   []string
+		purged int64
+		// the entries left afterwards
+		left int
+	}{
+		{"by tag", []string{"emotes:a"}, 1, 2},
+		{"by several tags", []string{"emotes:a", "emotes:b"}, 2, 1},
+		{"shared tag", []string{"emotes"}, 3, 0},
+		{"unknown tag", []string{"emotes:x"}, 0, 3},
+		{"everything", nil, 3, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gCtx, err := fakes.NewContext(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cl := gCtx.Inst().Redis.RawClient()
+			for k, tags := range map[string][]string{
+				"one":   {"emotes", "emotes:a"},
+				"two":   {"emotes", "emotes:b"},
+				"three": {"emotes"},
+			} {
+				cl.Set(gCtx, cacheKeyPrefix+k, "{}", time.Minute)
+				for _, tag := range tags {
+					cl.SAdd(gCtx, cacheTagKeyPrefix+tag, cacheKeyPrefix+k)
+				}
+			}
+
+			n, err := PurgeCache(gCtx, gCtx, tt.tags...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.purged {
+				t.Errorf("expected %d purged, got %d", tt.purged, n)
+			}
+			if left := len(gCtx.Inst().Redis.(*fakes.Redis).Keys(cacheKeyPrefix + "*")); left != tt.left {
+				t.Errorf("expected %d entries left, got %d", tt.left, left)
+			}
+		})
+	}
+}
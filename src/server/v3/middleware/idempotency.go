@@ -0,0 +1,225 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/utils"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	idempotencyKeyPrefix = "rest:idempotency:"
+	// How long a request holds its key while being processed, after which a retry may run it again
+	idempotencyLockTTL = time.Minute * 5
+	// Responses are kept this long unless configured otherwise
+	idempotencyDefaultWindow = time.Hour * 24
+)
+
+var idempotencyKeyRegex = regexp.MustCompile(`^[\w\-.:]{1,255}$`)
+
+// The request headers which are part of the fingerprint, as they change what a request does
+var idempotencyHeaders = []string{"Content-Type", "X-Emote-Data"}
+
+// Idempotency: replay the stored response to a request made again with the same Idempotency-Key header,
+// so that a client may safely retry a mutating request which timed out
+//
+// Keys are scoped to the route and the actor (or client IP, when anonymous). Reusing a key with a different path, query,
+// Content-Type or X-Emote-Data header or request body is a 409 conflict, as is retrying while the first request is still being processed.
+// Responses are only stored once the request body has been read in full, and never for server errors, so those may be retried.
+//
+// This should be placed after the Auth middleware and before any middleware with side effects
+func Idempotency(gCtx global.Context) rest.Middleware {
+	return func(ctx *rest.Ctx) rest.APIError {
+		key := utils.B2S(ctx.Request.Header.Peek(IdempotencyKeyHeader))
+		if key == "" || gCtx.Inst().Redis == nil {
+			return nil
+		}
+		if !idempotencyKeyRegex.MatchString(key) {
+			return errors.ErrInvalidRequest().SetDetail("Bad Idempotency-Key Header")
+		}
+
+		identity := ctx.RemoteIP().String()
+		if actor, ok := ctx.GetActor(); ok {
+			identity = actor.ID.Hex()
+		}
+		rkey := fmt.Sprintf("%s%s:%s:%s:%s", idempotencyKeyPrefix, utils.B2S(ctx.Method()), ctx.Route(), identity, key)
+		cl := gCtx.Inst().Redis.RawClient()
+
+		window := idempotencyDefaultWindow
+		if w := gCtx.Config().Http.IdempotencyWindow; w > 0 {
+			window = time.Duration(w) * time.Second
+		}
+
+		// Claim the key, or find the request which already did
+		locked, err := cl.SetNX(ctx, rkey, "{}", idempotencyLockTTL).Result()
+		if err != nil {
+			ctx.Log().WithError(err).Error("redis, failed to claim idempotency key")
+			return nil
+		}
+		if !locked {
+			entry := idempotencyEntry{}
+			b, err := cl.Get(ctx, rkey).Bytes()
+			if err == redis.Nil {
+				return rest.ErrIdempotencyConflict().SetDetail("Request Expired While Retrying")
+			} else if err != nil {
+				ctx.Log().WithError(err).Error("redis, failed to get idempotent response")
+				return errors.ErrInternalServerError()
+			}
+			if err = json.Unmarshal(b, &entry); err != nil || entry.Fingerprint == "" {
+				return rest.ErrIdempotencyConflict().SetDetail("A Request With This Key Is Still Being Processed")
+			}
+
+			// The request must be the same as the first time
+			fp := newFingerprint(ctx)
+			if err = fp.read(ctx, entry.Size+1); err != nil {
+				ctx.Log().WithError(err).Error("failed to read request body")
+				return errors.ErrInternalServerError()
+			}
+			if fp.sum() != entry.Fingerprint {
+				return rest.ErrIdempotencyConflict().SetDetail("Idempotency-Key Reused With A Different Request")
+			}
+
+			ctx.SetStatusCode(entry.Status)
+			ctx.SetContentType(entry.ContentType)
+			ctx.SetBody(entry.Body)
+			ctx.Response.Header.Set("Idempotent-Replayed", "true")
+			ctx.Halt()
+			return nil
+		}
+
+		// Fingerprint the body as the handler reads it
+		fp := newFingerprint(ctx)
+		streamed := ctx.RequestBodyStream() != nil
+		if streamed {
+			ctx.WrapBodyStream(func(r io.Reader) io.Reader {
+				fp.r = r
+				return fp
+			})
+		} else {
+			fp.consume(ctx.Request.Body())
+		}
+
+		ctx.OnComplete(func(ctx *rest.Ctx, res rest.RequestResult) {
+			if streamed && ctx.RequestBodyStream() == nil {
+				// The handler read the body into memory rather than through the stream
+				fp = newFingerprint(ctx)
+				fp.consume(ctx.Request.Body())
+			}
+			complete := fp.complete()
+
+			// The request may already be canceled at this point, so a separate context is used
+			lctx, cancel := context.WithTimeout(gCtx, time.Second*5)
+			defer cancel()
+
+			if res.Status >= rest.InternalServerError || !complete {
+				cl.Del(lctx, rkey)
+				return
+			}
+
+			b, _ := json.Marshal(&idempotencyEntry{
+				Fingerprint: fp.sum(),
+				Size:        fp.size,
+				Status:      res.Status,
+				ContentType: string(ctx.Response.Header.ContentType()),
+				Body:        ctx.Response.Body(),
+			})
+			if err := cl.Set(lctx, rkey, b, window).Err(); err != nil {
+				ctx.Log().WithError(err).Error("redis, failed to store idempotent response")
+			}
+		})
+
+		return nil
+	}
+}
+
+type idempotencyEntry struct {
+	Fingerprint string              `json:"fingerprint"`
+	Size        int64               `json:"size"`
+	Status      rest.HttpStatusCode `json:"status"`
+	ContentType string              `json:"content_type"`
+	Body        []byte              `json:"body"`
+}
+
+// fingerprint: a hash of the path, query, fingerprinted headers and body of a request.
+// It can wrap a streamed body, adding the data to the hash as it is read
+type fingerprint struct {
+	h    hash.Hash
+	r    io.Reader
+	size int64
+	eof  bool
+}
+
+func newFingerprint(ctx *rest.Ctx) *fingerprint {
+	fp := &fingerprint{h: sha256.New()}
+	_, _ = fp.h.Write(ctx.Path())
+	_, _ = fp.h.Write([]byte{0})
+	_, _ = fp.h.Write(ctx.URI().QueryString())
+	_, _ = fp.h.Write([]byte{0})
+	for _, k := range idempotencyHeaders {
+		_, _ = fp.h.Write(ctx.Request.Header.Peek(k))
+		_, _ = fp.h.Write([]byte{0})
+	}
+	return fp
+}
+
+// Write: add body data to the fingerprint
+func (fp *fingerprint) Write(p []byte) (int, error) {
+	fp.size += int64(len(p))
+	return fp.h.Write(p)
+}
+
+// Read: read from the wrapped body stream, adding what is read to the fingerprint
+func (fp *fingerprint) Read(p []byte) (int, error) {
+	n, err := fp.r.Read(p)
+	_, _ = fp.Write(p[:n])
+	if err == io.EOF {
+		fp.eof = true
+	}
+	return n, err
+}
+
+// consume: add a body which is already in memory
+func (fp *fingerprint) consume(body []byte) {
+	_, _ = fp.Write(body)
+	fp.eof = true
+}
+
+// read: add the body of a request, reading at most limit bytes of it
+func (fp *fingerprint) read(ctx *rest.Ctx, limit int64) error {
+	s := ctx.RequestBodyStream()
+	if s == nil {
+		fp.consume(ctx.Request.Body())
+		return nil
+	}
+
+	_, err := io.Copy(fp, io.LimitReader(s, limit))
+	return err
+}
+
+// complete: whether the whole body was added
+func (fp *fingerprint) complete() bool {
+	if fp.eof {
+		return true
+	}
+
+	// The reader may have stopped right at the end without seeing EOF
+	n, err := fp.Read(make([]byte, 1))
+	return n == 0 && err == io.EOF
+}
+
+func (fp *fingerprint) sum() string {
+	return hex.EncodeToString(fp.h.Sum(nil))
+}
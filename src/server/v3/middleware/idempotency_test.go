@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/fasthttp/router"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestIdempotency(t *testing.T) {
+	alice := &structures.User{ID: primitive.NewObjectID()}
+	bob := &structures.User{ID: primitive.NewObjectID()}
+
+	type step struct {
+		name   string
+		actor  *structures.User
+		ip     string
+		key    string
+		path   string
+		body   string
+		header map[string]string
+		// the status the handler responds with, if it runs
+		status rest.HttpStatusCode
+		// leave the request in progress rather than completing it
+		hold bool
+		// the expected status and body, and whether the response was replayed
+		want     int
+		wantBody string
+		replayed bool
+	}
+
+	emote := "/emotes/" + primitive.NewObjectID().Hex()
+	data := map[string]string{"X-Emote-Data": `{"name":"a"}`, "Content-Type": "image/png"}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "retries are replayed",
+			steps: []step{
+				{name: "first", key: "a", body: "x", header: data, status: rest.Created, want: 201, wantBody: "1"},
+				{name: "retry", key: "a", body: "x", header: data, status: rest.Created, want: 201, wantBody: "1", replayed: true},
+				{name: "other key", key: "b", body: "x", header: data, status: rest.Created, want: 201, wantBody: "3"},
+				{name: "no key", body: "x", header: data, status: rest.Created, want: 201, wantBody: "4"},
+			},
+		},
+		{
+			name: "reusing a key for another request is a conflict",
+			steps: []step{
+				{name: "first", key: "a", body: "x", header: data, status: rest.OK, want: 200, wantBody: "1"},
+				{name: "other body", key: "a", body: "y", header: data, status: rest.OK, want: 409},
+				{name: "other emote data", key: "a", body: "x", header: map[string]string{"X-Emote-Data": `{"name":"b"}`, "Content-Type": "image/png"}, status: rest.OK, want: 409},
+				{name: "other content type", key: "a", body: "x", header: map[string]string{"X-Emote-Data": `{"name":"a"}`, "Content-Type": "image/gif"}, status: rest.OK, want: 409},
+				{name: "other path", key: "a", path: "/emotes/" + primitive.NewObjectID().Hex(), body: "x", header: data, status: rest.OK, want: 409},
+				{name: "other query", key: "a", path: emote + "?q=1", body: "x", header: data, status: rest.OK, want: 409},
+				{name: "same request", key: "a", body: "x", header: data, status: rest.OK, want: 200, wantBody: "1", replayed: true},
+			},
+		},
+		{
+			name: "keys are scoped to the actor",
+			steps: []step{
+				{name: "alice", actor: alice, key: "a", body: "x", status: rest.OK, want: 200, wantBody: "1"},
+				{name: "bob", actor: bob, key: "a", body: "y", status: rest.OK, want: 200, wantBody: "2"},
+				{name: "anonymous", key: "a", body: "z", status: rest.OK, want: 200, wantBody: "3"},
+				{name: "anonymous, other ip", ip: "10.0.0.2", key: "a", body: "w", status: rest.OK, want: 200, wantBody: "4"},
+				{name: "alice again", actor: alice, ip: "10.0.0.2", key: "a", body: "x", status: rest.OK, want: 200, wantBody: "1", replayed: true},
+			},
+		},
+		{
+			name: "retrying while in progress is a conflict",
+			steps: []step{
+				{name: "first", key: "a", body: "x", hold: true, status: rest.OK},
+				{name: "retry", key: "a", body: "x", status: rest.OK, want: 409},
+			},
+		},
+		{
+			name: "server errors are not stored",
+			steps: []step{
+				{name: "first", key: "a", body: "x", status: rest.InternalServerError, want: 500, wantBody: "1"},
+				{name: "retry", key: "a", body: "x", status: rest.OK, want: 200, wantBody: "2"},
+				{name: "replay", key: "a", body: "x", status: rest.OK, want: 200, wantBody: "2", replayed: true},
+			},
+		},
+		{
+			name: "client errors are stored",
+			steps: []step{
+				{name: "first", key: "a", body: "x", status: rest.BadRequest, want: 400, wantBody: "1"},
+				{name: "retry", key: "a", body: "x", status: rest.OK, want: 400, wantBody: "1", replayed: true},
+			},
+		},
+		{
+			name: "malformed keys are rejected",
+			steps: []step{
+				{name: "spaces", key: "a b", body: "x", status: rest.OK, want: 400},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gCtx, err := fakes.NewContext(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mw := Idempotency(gCtx)
+			for i, s := range tt.steps {
+				path, ip := s.path, s.ip
+				if path == "" {
+					path = emote
+				}
+				if ip == "" {
+					ip = "10.0.0.1"
+				}
+
+				ctx := newTestCtx("PATCH", path, ip)
+				ctx.SetUserValue(router.MatchedRoutePathParam, "/emotes/{emote}")
+				ctx.Request.SetBodyString(s.body)
+				for k, v := range s.header {
+					ctx.Request.Header.Set(k, v)
+				}
+				if s.key != "" {
+					ctx.Request.Header.Set(IdempotencyKeyHeader, s.key)
+				}
+				if s.actor != nil {
+					ctx.SetActor(s.actor)
+				}
+
+				status := 0
+				if err := mw(ctx); err != nil {
+					status = err.ExpectedHTTPStatus()
+				} else if ctx.Halted() {
+					status = ctx.Response.StatusCode()
+				} else {
+					// The handler runs, responding with the number of the step
+					ctx.SetStatusCode(s.status)
+					ctx.SetBodyString(fmt.Sprint(i + 1))
+					if s.hold {
+						continue
+					}
+					ctx.Complete(nil)
+					status = int(s.status)
+				}
+
+				if status != s.want {
+					t.Fatalf("%s: expected status %d, got %d", s.name, s.want, status)
+				}
+				if s.wantBody != "" && string(ctx.Response.Body()) != s.wantBody {
+					t.Errorf("%s: expected body %q, got %q", s.name, s.wantBody, ctx.Response.Body())
+				}
+				if replayed := string(ctx.Response.Header.Peek("Idempotent-Replayed")) == "true"; replayed != s.replayed {
+					t.Errorf("%s: expected replayed=%t, got %t", s.name, s.replayed, replayed)
+				}
+			}
+		})
+	}
+}
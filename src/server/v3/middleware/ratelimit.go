@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/go-redis/redis/v8"
+)
+
+// RateLimit: limit the amount of requests an actor (or client IP, when anonymous) may make to a bucket
+// within a sliding window. The default budget can be overridden per bucket and per role in the config
+//
+// This should be placed after the Auth middleware, if any, so that the actor is known
+func RateLimit(gCtx global.Context, bucket string, limit int64, window time.Duration) rest.Middleware {
+	return func(ctx *rest.Ctx) rest.APIError {
+		if gCtx.Inst().Redis == nil {
+			return nil // rate limiting is unavailable without redis
+		}
+
+		// Resolve the budget for this request
+		limit, window := limit, window
+		identity := ctx.RemoteIP().String()
+		actor, ok := ctx.GetActor()
+		if ok {
+			identity = actor.ID.Hex()
+		}
+		if b, ok := gCtx.Config().Limits.Buckets[bucket]; ok {
+			if b.Limit > 0 {
+				limit = b.Limit
+			}
+			if b.Window > 0 {
+				window = time.Duration(b.Window) * time.Second
+			}
+			if actor != nil && len(b.Roles) > 0 {
+				// The highest limit among the actor's roles replaces the default, even if it is lower
+				roleLimit := int64(-1)
+				for _, role := range actor.Roles {
+					if l, ok := b.Roles[role.ID.Hex()]; ok && l > roleLimit {
+						roleLimit = l
+					}
+				}
+				if roleLimit >= 0 {
+					limit = roleLimit
+				}
+			}
+		}
+
+		// Record the request in the window
+		now := time.Now()
+		key := fmt.Sprintf("rest:ratelimit:%s:%s", bucket, identity)
+		member := fmt.Sprintf("%d:%d", now.UnixNano(), ctx.ID())
+
+		pipe := gCtx.Inst().Redis.RawClient().TxPipeline()
+		pipe.ZRemRangeByScore(ctx, key, "0", strconv.FormatInt(now.Add(-window).UnixNano(), 10))
+		pipe.ZAdd(ctx, key, &redis.Z{Score: float64(now.UnixNano()), Member: member})
+		count := pipe.ZCard(ctx, key)
+		oldest := pipe.ZRangeWithScores(ctx, key, 0, 0)
+		pipe.PExpire(ctx, key, window)
+		if _, err := pipe.Exec(ctx); err != nil {
+			ctx.Log().WithError(err).WithField("bucket", bucket).Error("redis, failed to update rate limit")
+			return nil
+		}
+
+		// The window resets once the oldest request within it expires
+		reset := now.Add(window)
+		if z := oldest.Val(); len(z) > 0 {
+			reset = time.Unix(0, int64(z[0].Score)).Add(window)
+		}
+		remaining := limit - count.Val()
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		ctx.Response.Header.Set("X-RateLimit-Limit", strconv.FormatInt(limit, 10))
+		ctx.Response.Header.Set("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+		ctx.Response.Header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+		if count.Val() > limit {
+			// Rejected requests do not count against the budget
+			gCtx.Inst().Redis.RawClient().ZRem(ctx, key, member)
+
+			retryAfter := int64(time.Until(reset).Seconds()) + 1
+			ctx.Response.Header.Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+			return rest.ErrTooManyRequests().SetFields(errors.Fields{
+				"bucket":      bucket,
+				"limit":       limit,
+				"retry_after": retryAfter,
+			})
+		}
+
+		return nil
+	}
+}
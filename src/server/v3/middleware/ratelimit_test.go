@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/configure"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/valyala/fasthttp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRateLimit(t *testing.T) {
+	role := &structures.Role{ID: primitive.NewObjectID()}
+	alice := &structures.User{ID: primitive.NewObjectID()}
+	bob := &structures.User{ID: primitive.NewObjectID(), Roles: []*structures.Role{role}}
+	other := &structures.Role{ID: primitive.NewObjectID()}
+	carol := &structures.User{ID: primitive.NewObjectID(), Roles: []*structures.Role{role, other}}
+
+	type step struct {
+		actor *structures.User
+		ip    string
+		// time to wait before the request
+		wait time.Duration
+		// whether the request should be allowed
+		allowed   bool
+		remaining string
+	}
+
+	tests := []struct {
+		name    string
+		limit   int64
+		window  time.Duration
+		buckets map[string]configure.LimitBucket
+		steps   []step
+	}{
+		{
+			name:   "requests over the limit are rejected",
+			limit:  2,
+			window: time.Minute,
+			steps: []step{
+				{ip: "10.0.0.1", allowed: true, remaining: "1"},
+				{ip: "10.0.0.1", allowed: true, remaining: "0"},
+				{ip: "10.0.0.1", allowed: false, remaining: "0"},
+				{ip: "10.0.0.1", allowed: false, remaining: "0"},
+			},
+		},
+		{
+			name:   "each client ip has its own budget",
+			limit:  1,
+			window: time.Minute,
+			steps: []step{
+				{ip: "10.0.0.1", allowed: true},
+				{ip: "10.0.0.2", allowed: true},
+				{ip: "10.0.0.1", allowed: false},
+			},
+		},
+		{
+			name:   "actors are limited by id rather than ip",
+			limit:  1,
+			window: time.Minute,
+			steps: []step{
+				{actor: alice, ip: "10.0.0.1", allowed: true},
+				{actor: alice, ip: "10.0.0.2", allowed: false},
+				{actor: bob, ip: "10.0.0.1", allowed: true},
+				{ip: "10.0.0.1", allowed: true},
+			},
+		},
+		{
+			name:   "the window slides",
+			limit:  2,
+			window: time.Millisecond * 300,
+			steps: []step{
+				{ip: "10.0.0.1", allowed: true},
+				{ip: "10.0.0.1", wait: time.Millisecond * 200, allowed: true},
+				{ip: "10.0.0.1", allowed: false},
+				// only the first request has left the window
+				{ip: "10.0.0.1", wait: time.Millisecond * 150, allowed: true, remaining: "0"},
+				{ip: "10.0.0.1", allowed: false},
+			},
+		},
+		{
+			name:   "rejected requests do not count against the budget",
+			limit:  1,
+			window: time.Millisecond * 300,
+			steps: []step{
+				{ip: "10.0.0.1", allowed: true},
+				{ip: "10.0.0.1", wait: time.Millisecond * 200, allowed: false},
+				{ip: "10.0.0.1", wait: time.Millisecond * 150, allowed: true},
+			},
+		},
+		{
+			name:   "the config overrides the default budget",
+			limit:  1,
+			window: time.Minute,
+			buckets: map[string]configure.LimitBucket{
+				"test": {Limit: 2},
+			},
+			steps: []step{
+				{ip: "10.0.0.1", allowed: true},
+				{ip: "10.0.0.1", allowed: true},
+				{ip: "10.0.0.1", allowed: false},
+			},
+		},
+		{
+			name:   "roles raise the budget",
+			limit:  1,
+			window: time.Minute,
+			buckets: map[string]configure.LimitBucket{
+				"test": {Roles: map[string]int64{role.ID.Hex(): 3}},
+			},
+			steps: []step{
+				{actor: bob, allowed: true, remaining: "2"},
+				{actor: bob, allowed: true},
+				{actor: bob, allowed: true},
+				{actor: bob, allowed: false},
+				{actor: alice, allowed: true, remaining: "0"},
+				{actor: alice, allowed: false},
+			},
+		},
+		{
+			name:   "roles lower the budget",
+			limit:  3,
+			window: time.Minute,
+			buckets: map[string]configure.LimitBucket{
+				"test": {Limit: 2, Roles: map[string]int64{role.ID.Hex(): 1}},
+			},
+			steps: []step{
+				{actor: bob, allowed: true, remaining: "0"},
+				{actor: bob, allowed: false},
+				{actor: alice, allowed: true, remaining: "1"},
+				{actor: alice, allowed: true},
+				{actor: alice, allowed: false},
+			},
+		},
+		{
+			name:   "the highest limit among the roles applies",
+			limit:  3,
+			window: time.Minute,
+			buckets: map[string]configure.LimitBucket{
+				"test": {Roles: map[string]int64{role.ID.Hex(): 1, other.ID.Hex(): 2}},
+			},
+			steps: []step{
+				{actor: carol, allowed: true, remaining: "1"},
+				{actor: carol, allowed: true},
+				{actor: carol, allowed: false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := fakes.NewConfig()
+			config.Limits.Buckets = tt.buckets
+			gCtx, err := fakes.NewContext(context.Background(), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mw := RateLimit(gCtx, "test", tt.limit, tt.window)
+			for i, s := range tt.steps {
+				time.Sleep(s.wait)
+
+				ip := s.ip
+				if ip == "" {
+					ip = "10.0.0.1"
+				}
+				ctx := newTestCtx("GET", "/", ip)
+				if s.actor != nil {
+					ctx.SetActor(s.actor)
+				}
+
+				err := mw(ctx)
+				if s.allowed != (err == nil) {
+					t.Fatalf("step %d: expected allowed=%t, got error %v", i, s.allowed, err)
+				}
+				if err != nil {
+					if err.ExpectedHTTPStatus() != int(rest.TooManyRequests) {
+						t.Errorf("step %d: expected status 429, got %d", i, err.ExpectedHTTPStatus())
+					}
+					if len(ctx.Response.Header.Peek("Retry-After")) == 0 {
+						t.Errorf("step %d: missing Retry-After header", i)
+					}
+				}
+				if s.remaining != "" {
+					if got := string(ctx.Response.Header.Peek("X-RateLimit-Remaining")); got != s.remaining {
+						t.Errorf("step %d: expected %s remaining, got %s", i, s.remaining, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func newTestCtx(method, uri string, ip string) *rest.Ctx {
+	req := &fasthttp.Request{}
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+
+	rctx := &fasthttp.RequestCtx{}
+	rctx.Init(req, &net.TCPAddr{IP: net.ParseIP(ip), Port: 50000}, nil)
+
+	return &rest.Ctx{RequestCtx: rctx}
+}
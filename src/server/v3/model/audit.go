@@ -0,0 +1,104 @@
+package model
+
+import (
+	"time"
+
+	"github.com/SevenTV/REST/src/audit"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// An Audit Log
+// @Description A record of a mutating request
+type AuditLog struct {
+	// The audit log's ID
+	ID string `json:"id" swaggertype:"string"`
+	// The ID of the user who made the request, if authenticated
+	ActorID string `json:"actor_id,omitempty" swaggertype:"string"`
+	// The request method
+	Method string `json:"method" swaggertype:"string"`
+	// The route which handled the request
+	Route string `json:"route" swaggertype:"string"`
+	// The requested path
+	Path string `json:"path" swaggertype:"string"`
+	// The final status code of the response
+	Status int `json:"status" swaggertype:"integer"`
+	// The error code, if the request failed
+	ErrorCode int `json:"error_code,omitempty" swaggertype:"integer"`
+	// The objects affected by the request
+	Targets []AuditLogTarget `json:"targets"`
+	// The changes made to the targets
+	Changes []AuditLogChange `json:"changes"`
+	// The time at which the request completed
+	Timestamp string `json:"timestamp" swaggertype:"string"`
+}
+
+type AuditLogTarget struct {
+	Kind string `json:"kind" swaggertype:"string" enums:"EMOTE,USER"`
+	ID   string `json:"id" swaggertype:"string"`
+}
+
+type AuditLogChange struct {
+	TargetID string      `json:"target_id" swaggertype:"string"`
+	Key      string      `json:"key" swaggertype:"string"`
+	Old      interface{} `json:"old,omitempty" swaggertype:"object"`
+	New      interface{} `json:"new,omitempty" swaggertype:"object"`
+}
+
+func NewAuditLog(l *audit.Log) AuditLog {
+	targets := make([]AuditLogTarget, len(l.Targets))
+	for i, t := range l.Targets {
+		targets[i] = AuditLogTarget{
+			Kind: string(t.Kind),
+			ID:   t.ID.Hex(),
+		}
+	}
+
+	changes := make([]AuditLogChange, len(l.Changes))
+	for i, c := range l.Changes {
+		changes[i] = AuditLogChange{
+			TargetID: c.TargetID.Hex(),
+			Key:      c.Key,
+			Old:      plainValue(c.Old),
+			New:      plainValue(c.New),
+		}
+	}
+
+	actorID := ""
+	if !l.ActorID.IsZero() {
+		actorID = l.ActorID.Hex()
+	}
+
+	return AuditLog{
+		ID:        l.ID.Hex(),
+		ActorID:   actorID,
+		Method:    l.Method,
+		Route:     l.Route,
+		Path:      l.Path,
+		Status:    l.Status,
+		ErrorCode: l.ErrorCode,
+		Targets:   targets,
+		Changes:   changes,
+		Timestamp: l.Timestamp.Format(time.RFC3339),
+	}
+}
+
+// plainValue: convert a value decoded from BSON into one which encodes naturally as JSON
+func plainValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case primitive.D:
+		m := make(map[string]interface{}, len(x))
+		for _, e := range x {
+			m[e.Key] = plainValue(e.Value)
+		}
+		return m
+	case primitive.A:
+		a := make([]interface{}, len(x))
+		for i, e := range x {
+			a[i] = plainValue(e)
+		}
+		return a
+	case primitive.DateTime:
+		return x.Time().Format(time.RFC3339)
+	}
+	return v
+}
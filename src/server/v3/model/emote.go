@@ -1,5 +1,12 @@
 package model
 
+import (
+	"time"
+
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/deletions"
+)
+
 // An Emote Object
 // @Description Represents an Emote
 type Emote struct {
@@ -7,4 +14,173 @@ type Emote struct {
 	ID string `json:"id" swaggertype:"string"`
 	// The emote's name
 	Name string `json:"name" swaggertype:"string"`
+	// The ID of the user who owns the emote
+	OwnerID string `json:"owner_id" swaggertype:"string"`
+	// The user who owns the emote
+	Owner *UserPartial `json:"owner,omitempty"`
+	// The emote's flags (1 - private, 2 - listed, 256 - zero-width)
+	Flags int32 `json:"flags" swaggertype:"integer"`
+	// The emote's search tags
+	Tags []string `json:"tags" swaggertype:"array,string"`
+	// Whether or not any version of the emote is animated
+	Animated bool `json:"animated" swaggertype:"boolean"`
+	// The time at which the emote was created
+	CreatedAt string `json:"created_at" swaggertype:"string"`
+	// The emote's versions, oldest first. Deleted versions are left out
+	Versions []EmoteVersion `json:"versions"`
+	// The ID of the emote this one diverged from, if any
+	ParentID string `json:"parent_id,omitempty" swaggertype:"string"`
+	// The IDs of the emotes this one descends from, from its parent to the original emote
+	Lineage []string `json:"lineage,omitempty" swaggertype:"array,string"`
+}
+
+// An Emote Version
+// @Description A revision of an emote's image
+type EmoteVersion struct {
+	// The version's ID
+	ID string `json:"id" swaggertype:"string"`
+	// The version's name
+	Name string `json:"name" swaggertype:"string"`
+	// The version's description
+	Description string `json:"description" swaggertype:"string"`
+	// The state of the version
+	Lifecycle string `json:"lifecycle" swaggertype:"string" enums:"PENDING,PROCESSING,DISABLED,LIVE,FAILED"`
+	// Whether or not the version is animated
+	Animated bool `json:"animated" swaggertype:"boolean"`
+	// The time at which the version was created
+	CreatedAt string `json:"created_at" swaggertype:"string"`
+	// The files of the version, by format. Empty until the version has been processed
+	Formats []EmoteFormat `json:"formats"`
+}
+
+type EmoteFormat struct {
+	// The MIME type of the files
+	Name  string      `json:"name" swaggertype:"string"`
+	Files []EmoteFile `json:"files"`
+}
+
+type EmoteFile struct {
+	Name     string `json:"name" swaggertype:"string"`
+	Width    int32  `json:"width" swaggertype:"integer"`
+	Height   int32  `json:"height" swaggertype:"integer"`
+	Animated bool   `json:"animated" swaggertype:"boolean"`
+	// The size of the file, in bytes
+	Size int64 `json:"size" swaggertype:"integer"`
+}
+
+var emoteLifecycleNames = map[structures.EmoteLifecycle]string{
+	structures.EmoteLifecycleDeleted:    "DELETED",
+	structures.EmoteLifecyclePending:    "PENDING",
+	structures.EmoteLifecycleProcessing: "PROCESSING",
+	structures.EmoteLifecycleDisabled:   "DISABLED",
+	structures.EmoteLifecycleLive:       "LIVE",
+	structures.EmoteLifecycleFailed:     "FAILED",
+}
+
+func NewEmote(e *structures.Emote) Emote {
+	tags := e.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	animated := false
+	versions := []EmoteVersion{}
+	for _, v := range e.Versions {
+		if v.State.Lifecycle == structures.EmoteLifecycleDeleted {
+			continue
+		}
+
+		ver := NewEmoteVersion(v)
+		if ver.Animated {
+			animated = true
+		}
+		versions = append(versions, ver)
+	}
+
+	result := Emote{
+		ID:        e.ID.Hex(),
+		Name:      e.Name,
+		OwnerID:   e.OwnerID.Hex(),
+		Owner:     NewUserPartial(e.Owner),
+		Flags:     int32(e.Flags),
+		Tags:      tags,
+		Animated:  animated,
+		CreatedAt: e.ID.Timestamp().Format(time.RFC3339),
+		Versions:  versions,
+	}
+	if e.ParentID != nil {
+		result.ParentID = e.ParentID.Hex()
+	}
+
+	return result
+}
+
+func NewEmoteVersion(v *structures.EmoteVersion) EmoteVersion {
+	formats := make([]EmoteFormat, len(v.Formats))
+	for i, f := range v.Formats {
+		files := make([]EmoteFile, len(f.Files))
+		for j, file := range f.Files {
+			files[j] = EmoteFile{
+				Name:     file.Name,
+				Width:    file.Width,
+				Height:   file.Height,
+				Animated: file.Animated,
+				Size:     file.Length,
+			}
+		}
+		formats[i] = EmoteFormat{
+			Name:  string(f.Name),
+			Files: files,
+		}
+	}
+
+	return EmoteVersion{
+		ID:          v.ID.Hex(),
+		Name:        v.Name,
+		Description: v.Description,
+		Lifecycle:   emoteLifecycleNames[v.State.Lifecycle],
+		Animated:    v.FrameCount > 1,
+		CreatedAt:   v.ID.Timestamp().Format(time.RFC3339),
+		Formats:     formats,
+	}
+}
+
+// An Emote Deletion
+// @Description The deletion of an emote, or one of its versions
+type EmoteDeletion struct {
+	// The deletion's ID
+	ID string `json:"id" swaggertype:"string"`
+	// The ID of the deleted emote
+	EmoteID string `json:"emote_id" swaggertype:"string"`
+	// The ID of the deleted version, if only a single version was deleted
+	VersionID string `json:"version_id,omitempty" swaggertype:"string"`
+	// The IDs of the versions which were deleted
+	Versions []string `json:"versions" swaggertype:"array,string"`
+	// Why the emote was deleted
+	Reason string `json:"reason" swaggertype:"string"`
+	// The time at which the emote was deleted
+	DeletedAt string `json:"deleted_at" swaggertype:"string"`
+	// The time until which the deletion can be undone, after which the emote's files are removed
+	RestorableUntil string `json:"restorable_until" swaggertype:"string"`
+}
+
+func NewEmoteDeletion(d *deletions.Deletion) EmoteDeletion {
+	versions := make([]string, len(d.Versions))
+	for i, v := range d.Versions {
+		versions[i] = v.ID.Hex()
+	}
+
+	result := EmoteDeletion{
+		ID:              d.ID.Hex(),
+		EmoteID:         d.EmoteID.Hex(),
+		Versions:        versions,
+		Reason:          d.Reason,
+		DeletedAt:       d.DeletedAt.Format(time.RFC3339),
+		RestorableUntil: d.PurgeAt.Format(time.RFC3339),
+	}
+	if d.VersionID != nil {
+		result.VersionID = d.VersionID.Hex()
+	}
+
+	return result
 }
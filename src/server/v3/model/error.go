@@ -0,0 +1,58 @@
+package model
+
+import (
+	"time"
+
+	"github.com/SevenTV/REST/src/panics"
+)
+
+// An Error Report
+// @Description A record of an unexpected error which occured while serving a request
+type ErrorReport struct {
+	// The error ID, as returned to the client
+	ID string `json:"id" swaggertype:"string"`
+	// The ID of the request which caused the error
+	RequestID string `json:"request_id" swaggertype:"string"`
+	// The node which served the request
+	NodeName string `json:"node_name" swaggertype:"string"`
+	// The request method
+	Method string `json:"method" swaggertype:"string"`
+	// The route which handled the request
+	Route string `json:"route,omitempty" swaggertype:"string"`
+	// The requested path
+	Path string `json:"path" swaggertype:"string"`
+	// The query string of the request
+	Query string `json:"query,omitempty" swaggertype:"string"`
+	// The ID of the user who made the request, if authenticated
+	ActorID string `json:"actor_id,omitempty" swaggertype:"string"`
+	// The address of the client
+	RemoteIP string `json:"remote_ip" swaggertype:"string"`
+	// The error message
+	Message string `json:"message" swaggertype:"string"`
+	// The stack trace at the time of the error
+	Stack string `json:"stack" swaggertype:"string"`
+	// The time at which the error occured
+	Timestamp string `json:"timestamp" swaggertype:"string"`
+}
+
+func NewErrorReport(r *panics.Report) ErrorReport {
+	actorID := ""
+	if !r.ActorID.IsZero() {
+		actorID = r.ActorID.Hex()
+	}
+
+	return ErrorReport{
+		ID:        r.ID.Hex(),
+		RequestID: r.RequestID,
+		NodeName:  r.NodeName,
+		Method:    r.Method,
+		Route:     r.Route,
+		Path:      r.Path,
+		Query:     r.Query,
+		ActorID:   actorID,
+		RemoteIP:  r.RemoteIP,
+		Message:   r.Message,
+		Stack:     r.Stack,
+		Timestamp: r.Timestamp.Format(time.RFC3339),
+	}
+}
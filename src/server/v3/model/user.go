@@ -0,0 +1,31 @@
+package model
+
+import (
+	"github.com/SevenTV/Common/structures/v3"
+)
+
+// A Partial User Object
+// @Description The public summary of a user
+type UserPartial struct {
+	// The user's ID
+	ID string `json:"id" swaggertype:"string"`
+	// The user's username
+	Username string `json:"username" swaggertype:"string"`
+	// The user's display name
+	DisplayName string `json:"display_name" swaggertype:"string"`
+	// The ID of the user's avatar
+	AvatarID string `json:"avatar_id,omitempty" swaggertype:"string"`
+}
+
+func NewUserPartial(u *structures.User) *UserPartial {
+	if u == nil {
+		return nil
+	}
+
+	return &UserPartial{
+		ID:          u.ID.Hex(),
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		AvatarID:    u.AvatarID,
+	}
+}
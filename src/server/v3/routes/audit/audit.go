@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/audit"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v3/middleware"
+	"github.com/SevenTV/REST/src/server/v3/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Route struct {
+	Ctx global.Context
+}
+
+func New(gCtx global.Context) rest.Route {
+	return &Route{gCtx}
+}
+
+func (r *Route) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/audit",
+		Method: rest.GET,
+		Middleware: []rest.Middleware{
+			middleware.Auth(r.Ctx),
+		},
+	}
+}
+
+// Audit Logs
+// @Summary Get Audit Logs
+// @Description List audit logs, most recent first. Requires the manage stack permission
+// @Tags audit
+// @Produce json
+// @Param actor query string false "filter by the ID of the user who made the request"
+// @Param target query string false "filter by the ID of an affected object"
+// @Param since query string false "only include logs at or after this time (RFC3339)"
+// @Param until query string false "only include logs before this time (RFC3339)"
+// @Param limit query integer false "the maximum amount of logs to return (default 50, max 250)"
+// @Param after query string false "the cursor of the page to get, from the next link of the previous page"
+// @Header 200 {string} Link "the next page of logs, if there is one"
+// @Header 200 {integer} X-Total-Count "the amount of logs matching the filter"
+// @Success 200 {array} model.AuditLog
+// @Router /audit [get]
+func (r *Route) Handler(ctx *rest.Ctx) rest.APIError {
+	actor, ok := ctx.GetActor()
+	if !ok {
+		return errors.ErrUnauthorized()
+	}
+	if !actor.HasPermission(structures.RolePermissionManageStack) {
+		return errors.ErrInsufficientPrivilege()
+	}
+
+	args := &listArgs{}
+	if err := ctx.Bind(args); err != nil {
+		return err
+	}
+	page, err := ctx.Paginate(bson.D{{Key: "_id", Value: -1}}, nil, 50, 250)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{}
+	if !args.Actor.IsZero() {
+		filter["actor_id"] = args.Actor
+	}
+	if !args.Target.IsZero() {
+		filter["targets.id"] = args.Target
+	}
+	if !args.Since.IsZero() || !args.Until.IsZero() {
+		tr := bson.M{}
+		if !args.Since.IsZero() {
+			tr["$gte"] = args.Since
+		}
+		if !args.Until.IsZero() {
+			tr["$lt"] = args.Until
+		}
+		filter["timestamp"] = tr
+	}
+
+	col := r.Ctx.Inst().Mongo.Collection(audit.CollectionName)
+	total, er := col.CountDocuments(ctx, filter)
+	if er != nil {
+		ctx.Log().WithError(er).Error("mongo, failed to count audit logs")
+		return errors.ErrInternalServerError()
+	}
+
+	cur, er := col.Find(ctx, page.Filter(filter), page.FindOptions())
+	if er != nil {
+		ctx.Log().WithError(er).Error("mongo, failed to query audit logs")
+		return errors.ErrInternalServerError()
+	}
+
+	logs := []*audit.Log{}
+	if er = page.Decode(ctx, cur, &logs); er != nil {
+		ctx.Log().WithError(er).Error("mongo, failed to decode audit logs")
+		return errors.ErrInternalServerError()
+	}
+	ctx.SetPageHeaders(page, total)
+
+	result := make([]model.AuditLog, len(logs))
+	for i, l := range logs {
+		result[i] = model.NewAuditLog(l)
+	}
+
+	return ctx.JSON(rest.OK, &result)
+}
+
+type listArgs struct {
+	Actor  primitive.ObjectID `query:"actor"`
+	Target primitive.ObjectID `query:"target"`
+	Since  time.Time          `query:"since"`
+	Until  time.Time          `query:"until"`
+}
@@ -0,0 +1,100 @@
+package audit_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/audit"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/server"
+	"github.com/SevenTV/REST/src/server/v3/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAuditLogs(t *testing.T) {
+	gCtx, err := fakes.NewContext(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+
+	admin := &structures.Role{ID: primitive.NewObjectID(), Name: "Admin", Allowed: structures.RolePermissionManageStack}
+	mod := &structures.Role{ID: primitive.NewObjectID(), Name: "Mod", Allowed: structures.RolePermissionEditAnyEmote | structures.RolePermissionBypassPrivacy}
+	users := map[string]*structures.User{
+		"admin": {ID: primitive.NewObjectID(), Username: "admin", RoleIDs: []primitive.ObjectID{admin.ID}},
+		"mod":   {ID: primitive.NewObjectID(), Username: "mod", RoleIDs: []primitive.ObjectID{mod.ID}},
+		"user":  {ID: primitive.NewObjectID(), Username: "user", RoleIDs: []primitive.ObjectID{}},
+	}
+	mgo.Seed(mongo.CollectionNameRoles, admin, mod)
+	for _, u := range users {
+		mgo.Seed(mongo.CollectionNameUsers, u)
+	}
+
+	target := primitive.NewObjectID()
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		l := &audit.Log{
+			ID:        primitive.NewObjectIDFromTimestamp(now.Add(time.Duration(i) * time.Second)),
+			ActorID:   users["user"].ID,
+			Method:    "PATCH",
+			Targets:   []audit.Target{},
+			Changes:   []audit.Change{},
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+		}
+		if i == 1 {
+			l.AddTarget(audit.TargetKindEmote, target)
+		}
+		mgo.Seed(audit.CollectionName, l)
+	}
+
+	h := server.NewHarness(gCtx)
+	tests := []struct {
+		name   string
+		user   string
+		query  string
+		status int
+		count  int
+	}{
+		{"anonymous", "", "", 401, 0},
+		{"without the permission", "user", "", 403, 0},
+		{"with other permissions", "mod", "", 403, 0},
+		{"manage stack", "admin", "", 200, 3},
+		{"by target", "admin", "?target=" + target.Hex(), 200, 1},
+		{"by actor", "admin", "?actor=" + users["admin"].ID.Hex(), 200, 0},
+		{"limited", "admin", "?limit=2", 200, 2},
+		{"bad filter", "admin", "?since=yesterday", 400, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.user != "" {
+				tok, err := h.Token(users[tt.user])
+				if err != nil {
+					t.Fatal(err)
+				}
+				headers["Authorization"] = "Bearer " + tok
+			}
+
+			res := h.Request("GET", "/v3/audit"+tt.query, nil, headers)
+			if res.StatusCode() != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, res.StatusCode(), res.Body())
+			}
+			if tt.status != 200 {
+				return
+			}
+
+			logs := []model.AuditLog{}
+			if err := json.Unmarshal(res.Body(), &logs); err != nil {
+				t.Fatal(err)
+			}
+			if len(logs) != tt.count {
+				t.Errorf("expected %d logs, got %d", tt.count, len(logs))
+			}
+		})
+	}
+}
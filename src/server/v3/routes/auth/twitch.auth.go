@@ -11,7 +11,6 @@ import (
 	"github.com/SevenTV/REST/src/global"
 	"github.com/SevenTV/REST/src/server/rest"
 	"github.com/google/go-querystring/query"
-	"github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
 )
 
@@ -36,7 +35,7 @@ func (r *twitch) Handler(ctx *rest.Ctx) rest.APIError {
 	// Generate a randomized value for a CSRF token
 	csrfValue, err := utils.GenerateRandomString(64)
 	if err != nil {
-		logrus.WithError(err).Error("csrf, random bytes")
+		ctx.Log().WithError(err).Error("csrf, random bytes")
 		return errors.ErrInternalServerError()
 	}
 
@@ -46,7 +45,7 @@ func (r *twitch) Handler(ctx *rest.Ctx) rest.APIError {
 		CreatedAt: time.Now(),
 	})
 	if err != nil {
-		logrus.WithError(err).Error("csrf, jwt")
+		ctx.Log().WithError(err).Error("csrf, jwt")
 		return errors.ErrInternalServerError()
 	}
 
@@ -69,7 +68,7 @@ func (r *twitch) Handler(ctx *rest.Ctx) rest.APIError {
 		State:        csrfValue,
 	})
 	if err != nil {
-		logrus.WithError(err).Error("querystring")
+		ctx.Log().WithError(err).Error("querystring")
 		return errors.ErrInternalServerError()
 	}
 
@@ -12,12 +12,13 @@ import (
 	"github.com/SevenTV/Common/mongo"
 	"github.com/SevenTV/Common/structures/v3"
 	"github.com/SevenTV/Common/utils"
+	"github.com/SevenTV/REST/src/audit"
 	"github.com/SevenTV/REST/src/externalapis"
 	"github.com/SevenTV/REST/src/global"
 	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v3/middleware"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/go-querystring/query"
-	"github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -35,10 +36,12 @@ func newTwitchCallback(gCtx global.Context) rest.Route {
 
 func (r *twitchCallback) Config() rest.RouteConfig {
 	return rest.RouteConfig{
-		URI:        "/twitch/callback",
-		Method:     rest.GET,
-		Children:   []rest.Route{},
-		Middleware: []rest.Middleware{},
+		URI:      "/twitch/callback",
+		Method:   rest.GET,
+		Children: []rest.Route{},
+		Middleware: []rest.Middleware{
+			middleware.Audit(r.Ctx),
+		},
 	}
 }
 
@@ -61,20 +64,20 @@ func (r *twitchCallback) Handler(ctx *rest.Ctx) rest.APIError {
 	csrfClaim := &auth.JWTClaimOAuth2CSRF{}
 	token, err := auth.VerifyJWT(r.Ctx.Config().Credentials.JWTSecret, csrfToken, csrfClaim)
 	if err != nil {
-		logrus.WithError(err).Error("jwt")
+		ctx.Log().WithError(err).Error("jwt")
 		ctx.SetStatusCode(rest.BadRequest)
 		return errors.ErrUnauthorized().SetDetail(fmt.Sprintf("Invalid State: %s", err.Error()))
 	}
 	{
 		b, err := json.Marshal(token.Claims)
 		if err != nil {
-			logrus.WithError(err).Error("json")
+			ctx.Log().WithError(err).Error("json")
 			ctx.SetStatusCode(rest.BadRequest)
 			return errors.ErrUnauthorized().SetDetail(fmt.Sprintf("Invalid State: %s", err.Error()))
 		}
 
 		if err = json.Unmarshal(b, csrfClaim); err != nil {
-			logrus.WithError(err).Error("json")
+			ctx.Log().WithError(err).Error("json")
 			ctx.SetStatusCode(rest.BadRequest)
 			return errors.ErrUnauthorized().SetDetail(fmt.Sprintf("Invalid State: %s", err.Error()))
 		}
@@ -112,7 +115,7 @@ func (r *twitchCallback) Handler(ctx *rest.Ctx) rest.APIError {
 		GrantType:    "authorization_code",
 	})
 	if err != nil {
-		logrus.WithError(err).Error("querystring")
+		ctx.Log().WithError(err).Error("querystring")
 		ctx.SetStatusCode(rest.InternalServerError)
 		return errors.ErrInternalServerError()
 	}
@@ -120,7 +123,7 @@ func (r *twitchCallback) Handler(ctx *rest.Ctx) rest.APIError {
 	// Prepare a HTTP request to Twitch to convert code to acccess token
 	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("https://id.twitch.tv/oauth2/token?%s", params.Encode()), nil)
 	if err != nil {
-		logrus.WithError(err).Error("twitch")
+		ctx.Log().WithError(err).Error("twitch")
 		ctx.SetStatusCode(rest.InternalServerError)
 		return errors.ErrInternalServerError().SetDetail("Internal Request to External Provider Failed")
 	}
@@ -128,14 +131,14 @@ func (r *twitchCallback) Handler(ctx *rest.Ctx) rest.APIError {
 	// Send the request
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		logrus.WithError(err).Error("twitch")
+		ctx.Log().WithError(err).Error("twitch")
 		return errors.ErrInternalServerError().SetDetail("Internal Request Rejected by External Provider")
 	}
 	defer resp.Body.Close()
 
 	grant := &OAuth2AuthorizedResponse{}
 	if err = externalapis.ReadRequestResponse(resp, grant); err != nil {
-		logrus.WithError(err).Error("ReadRequestResponse")
+		ctx.Log().WithError(err).Error("ReadRequestResponse")
 		ctx.SetStatusCode(rest.InternalServerError)
 		return errors.ErrInternalServerError().SetDetail("Failed to decode data sent by the External Provider")
 	}
@@ -143,7 +146,7 @@ func (r *twitchCallback) Handler(ctx *rest.Ctx) rest.APIError {
 	// Retrieve twitch user data
 	users, err := externalapis.Twitch.GetUsers(r.Ctx, grant.AccessToken)
 	if err != nil {
-		logrus.WithError(err).Error("Twitch, GetUsers")
+		ctx.Log().WithError(err).Error("Twitch, GetUsers")
 		ctx.SetStatusCode(rest.InternalServerError)
 		return errors.ErrInternalServerError().SetDetail("Couldn't fetch user data from the External Provider")
 	}
@@ -183,37 +186,53 @@ func (r *twitchCallback) Handler(ctx *rest.Ctx) rest.APIError {
 
 			r, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).InsertOne(ctx, ub.User)
 			if err != nil {
-				logrus.WithError(err).Error("mongo")
+				ctx.Log().WithError(err).Error("mongo")
 				ctx.SetStatusCode(rest.InternalServerError)
 				return errors.ErrInternalServerError().SetDetail("Database Write Failed (user, stat)")
 
 			}
 			userID = r.InsertedID.(primitive.ObjectID)
+
+			ctx.AuditLog().
+				AddTarget(audit.TargetKindUser, userID).
+				AddChange(userID, "username", nil, ub.User.Username).
+				AddChange(userID, "connections", nil, ucb.UserConnection.ID)
 		} else if err != nil {
-			logrus.WithError(err).Error("mongo")
+			ctx.Log().WithError(err).Error("mongo")
 			return errors.ErrInternalServerError().SetDetail("Database Write Failed (user, stat)")
-		} else if len(ub.Update) > 0 {
-			// User exists; update
-			if err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).FindOneAndUpdate(ctx, bson.M{
-				"_id":            ub.User.ID,
-				"connections.id": twUser.ID,
-			}, ub.Update, options.FindOneAndUpdate().SetReturnDocument(1)).Decode(ub.User); err != nil {
-				logrus.WithError(err).Error("mongo")
-				return errors.ErrInternalServerError().SetDetail("Database Write Failed (user, stat)")
-			}
-			userID = ub.User.ID
 		} else {
-			if err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).FindOne(ctx, bson.M{
+			// User exists: keep the username and the data of the connection up to date
+			userID = ub.User.ID
+			log := ctx.AuditLog().AddTarget(audit.TargetKindUser, userID)
+
+			if ub.User.Username != twUser.Login {
+				old := ub.User.Username
+				ub.SetUsername(twUser.Login)
+				log.AddChange(userID, "username", old, ub.User.Username)
+			}
+			if conn, ok := ub.GetConnection(structures.UserConnectionPlatformTwitch, twUser.ID); ok {
+				old, _ := conn.UserConnection.DecodeTwitch()
+				conn.SetTwitchData(twUser).
+					SetGrant(grant.AccessToken, grant.RefreshToken, grant.ExpiresIn, grant.Scope)
+				for k, v := range conn.Update["$set"].(bson.M) {
+					ub.Update.Set(k, v)
+				}
+
+				if !sameTwitchData(old, twUser) {
+					log.AddChange(userID, "connections.twitch", old, twUser)
+				}
+			}
+
+			if err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).FindOneAndUpdate(ctx, bson.M{
 				"_id":            ub.User.ID,
 				"connections.id": twUser.ID,
-			}).Decode(ub.User); err != nil {
+			}, ub.Update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(ub.User); err != nil {
 				if err == mongo.ErrNoDocuments {
 					return errors.ErrUnknownUser()
 				}
-				logrus.WithError(err).Error("mongo")
-				return errors.ErrInternalServerError().SetDetail(err.Error())
+				ctx.Log().WithError(err).Error("mongo")
+				return errors.ErrInternalServerError().SetDetail("Database Write Failed (user, stat)")
 			}
-			userID = ub.User.ID
 		}
 	}
 
@@ -230,7 +249,7 @@ func (r *twitchCallback) Handler(ctx *rest.Ctx) rest.APIError {
 		},
 	})
 	if err != nil {
-		logrus.WithError(err).Error("jwt")
+		ctx.Log().WithError(err).Error("jwt")
 		return errors.ErrInternalServerError().SetDetail(fmt.Sprintf("Token Sign Failure (%s)", err.Error()))
 	}
 
@@ -250,3 +269,17 @@ func (r *twitchCallback) Handler(ctx *rest.Ctx) rest.APIError {
 	ctx.Redirect(fmt.Sprintf("%s/oauth2?%s", r.Ctx.Config().WebsiteURL, params.Encode()), int(rest.Found))
 	return nil
 }
+
+// sameTwitchData: whether the public profile of a twitch connection is unchanged
+func sameTwitchData(a *structures.TwitchConnection, b *structures.TwitchConnection) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Login == b.Login &&
+		a.DisplayName == b.DisplayName &&
+		a.Description == b.Description &&
+		a.ProfileImageURL == b.ProfileImageURL &&
+		a.BroadcasterType == b.BroadcasterType &&
+		a.Email == b.Email
+}
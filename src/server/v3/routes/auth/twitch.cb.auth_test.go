@@ -0,0 +1,212 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/Common/auth"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/audit"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/server"
+	authroutes "github.com/SevenTV/REST/src/server/v3/routes/auth"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// twitchTransport: answers the requests made to twitch during the oauth2 flow
+type twitchTransport struct {
+	user *structures.TwitchConnection
+	// when set, the token exchange fails
+	fail bool
+}
+
+func (tr *twitchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body interface{}
+	switch req.URL.Host + req.URL.Path {
+	case "id.twitch.tv/oauth2/token":
+		if tr.fail {
+			return nil, fmt.Errorf("connection refused")
+		}
+		if req.URL.Query().Get("code") != "code" {
+			return nil, fmt.Errorf("unexpected code %q", req.URL.Query().Get("code"))
+		}
+		body = &authroutes.OAuth2AuthorizedResponse{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 3600, Scope: []string{"user:read:email"}}
+	case "api.twitch.tv/helix/users":
+		if req.Header.Get("Authorization") != "Bearer access" {
+			return nil, fmt.Errorf("unexpected authorization %q", req.Header.Get("Authorization"))
+		}
+		body = map[string]interface{}{"data": []*structures.TwitchConnection{tr.user}}
+	default:
+		return nil, fmt.Errorf("unexpected request to %s", req.URL)
+	}
+
+	b, _ := json.Marshal(body)
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(string(b))),
+		Request:    req,
+	}, nil
+}
+
+func TestTwitchCallback(t *testing.T) {
+	twUser := &structures.TwitchConnection{ID: "123", Login: "streamer", DisplayName: "Streamer", Email: "s@example.com"}
+
+	tests := []struct {
+		name string
+		// the state in the query and the csrf cookie, and when the latter was created
+		state   string
+		cookie  string
+		created time.Time
+		fail    bool
+		// the user already linked to the twitch account, if any
+		existing *structures.TwitchConnection
+		status   int
+		// the changes expected in the audit log
+		changes []string
+	}{
+		{name: "missing state", cookie: "abc", created: time.Now(), status: 400},
+		{name: "missing cookie", state: "abc", status: 400},
+		{name: "mismatching state", state: "abc", cookie: "xyz", created: time.Now(), status: 401},
+		{name: "expired state", state: "abc", cookie: "abc", created: time.Now().Add(-time.Minute * 10), status: 401},
+		{name: "twitch unreachable", state: "abc", cookie: "abc", created: time.Now(), fail: true, status: 500},
+		{name: "new user", state: "abc", cookie: "abc", created: time.Now(), status: 302, changes: []string{"username", "connections"}},
+		{
+			name: "existing user, renamed", state: "abc", cookie: "abc", created: time.Now(),
+			existing: &structures.TwitchConnection{ID: "123", Login: "oldname", DisplayName: "OldName"},
+			status:   302, changes: []string{"username", "connections.twitch"},
+		},
+		{name: "existing user, unchanged", state: "abc", cookie: "abc", created: time.Now(), existing: twUser, status: 302},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gCtx, err := fakes.NewContext(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+
+			transport := http.DefaultClient.Transport
+			http.DefaultClient.Transport = &twitchTransport{user: twUser, fail: tt.fail}
+			t.Cleanup(func() {
+				http.DefaultClient.Transport = transport
+			})
+
+			var existing *structures.User
+			if tt.existing != nil {
+				ucb := structures.NewUserConnectionBuilder(nil).
+					SetID(tt.existing.ID).
+					SetPlatform(structures.UserConnectionPlatformTwitch).
+					SetTwitchData(tt.existing)
+				existing = &structures.User{
+					ID:          primitive.NewObjectID(),
+					Username:    tt.existing.Login,
+					RoleIDs:     []primitive.ObjectID{},
+					Connections: []*structures.UserConnection{ucb.UserConnection},
+				}
+				if err := mgo.Seed(mongo.CollectionNameUsers, existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			headers := map[string]string{}
+			if tt.cookie != "" {
+				tok, err := auth.SignJWT(gCtx.Config().Credentials.JWTSecret, auth.JWTClaimOAuth2CSRF{State: tt.cookie, CreatedAt: tt.created})
+				if err != nil {
+					t.Fatal(err)
+				}
+				headers["Cookie"] = authroutes.TWITCH_CSRF_COOKIE_NAME + "=" + tok
+			}
+
+			q := url.Values{"code": {"code"}}
+			if tt.state != "" {
+				q.Set("state", tt.state)
+			}
+			res := server.NewHarness(gCtx).Request("GET", "/v3/auth/twitch/callback?"+q.Encode(), nil, headers)
+			if res.StatusCode() != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, res.StatusCode(), res.Body())
+			}
+			if tt.status != 302 {
+				if docs := mgo.Documents(mongo.CollectionNameUsers); existing == nil && len(docs) != 0 {
+					t.Errorf("expected no user to be created, got %d", len(docs))
+				}
+				return
+			}
+
+			// The user is linked to the twitch account, with the latest profile
+			docs := mgo.Documents(mongo.CollectionNameUsers)
+			if len(docs) != 1 {
+				t.Fatalf("expected a single user, got %d", len(docs))
+			}
+			u := &structures.User{}
+			if err := bson.Unmarshal(docs[0], u); err != nil {
+				t.Fatal(err)
+			}
+			if existing != nil && u.ID != existing.ID {
+				t.Errorf("expected the existing user to be kept, got %s", u.ID.Hex())
+			}
+			if u.Username != twUser.Login || len(u.Connections) != 1 {
+				t.Fatalf("expected the user to be named after the twitch account, got %+v", u)
+			}
+			data, err := u.Connections[0].DecodeTwitch()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data.Login != twUser.Login || data.DisplayName != twUser.DisplayName {
+				t.Errorf("expected the connection to hold the latest profile, got %+v", data)
+			}
+			if u.Connections[0].Grant == nil || u.Connections[0].Grant.AccessToken != "access" {
+				t.Errorf("expected the grant to be stored, got %+v", u.Connections[0].Grant)
+			}
+
+			// The client is given a token for the user
+			loc, err := url.Parse(string(res.Header.Peek("Location")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims := &auth.JWTClaimUser{}
+			if _, err := auth.VerifyJWT(gCtx.Config().Credentials.JWTSecret, strings.Split(loc.Query().Get("token"), "."), claims); err != nil {
+				t.Fatalf("expected a valid token in %s: %v", loc, err)
+			}
+			if loc.Path != "/oauth2" || claims.UserID != u.ID.Hex() {
+				t.Errorf("expected a token for %s in %s, got %s", u.ID.Hex(), loc, claims.UserID)
+			}
+
+			// Profile changes are audited
+			logs := mgo.Documents(audit.CollectionName)
+			if len(tt.changes) == 0 {
+				if len(logs) != 0 {
+					t.Errorf("expected no audit log, got %d", len(logs))
+				}
+				return
+			}
+			if len(logs) != 1 {
+				t.Fatalf("expected an audit log, got %d", len(logs))
+			}
+			l := &audit.Log{}
+			if err := bson.Unmarshal(logs[0], l); err != nil {
+				t.Fatal(err)
+			}
+			keys := []string{}
+			for _, c := range l.Changes {
+				if c.TargetID != u.ID {
+					t.Errorf("expected the change to target the user, got %s", c.TargetID.Hex())
+				}
+				keys = append(keys, c.Key)
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.changes, ",") {
+				t.Errorf("expected the changes %v, got %v", tt.changes, keys)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v3/middleware"
+)
+
+type Route struct {
+	Ctx global.Context
+}
+
+func New(gCtx global.Context) rest.Route {
+	return &Route{gCtx}
+}
+
+func (r *Route) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/cache",
+		Method: rest.DELETE,
+		Middleware: []rest.Middleware{
+			middleware.Auth(r.Ctx),
+			middleware.Audit(r.Ctx),
+		},
+	}
+}
+
+// Purge Cache
+// @Summary Purge Cached Responses
+// @Description Drop cached responses with the given tags, or all of them if no tag is specified. Requires the manage stack permission
+// @Tags cache
+// @Produce json
+// @Param tag query []string false "only purge responses with these tags (i.e emotes, emotes:<id>)"
+// @Success 200 {object} purgeResponse
+// @Router /cache [delete]
+func (r *Route) Handler(ctx *rest.Ctx) rest.APIError {
+	actor, ok := ctx.GetActor()
+	if !ok {
+		return errors.ErrUnauthorized()
+	}
+	if !actor.HasPermission(structures.RolePermissionManageStack) {
+		return errors.ErrInsufficientPrivilege()
+	}
+	if r.Ctx.Inst().Redis == nil {
+		return errors.ErrMissingInternalDependency().SetDetail("Cache Unavailable")
+	}
+
+	args := &purgeArgs{}
+	if err := ctx.Bind(args); err != nil {
+		return err
+	}
+
+	n, err := middleware.PurgeCache(ctx, r.Ctx, args.Tags...)
+	if err != nil {
+		ctx.Log().WithError(err).Error("redis, failed to purge cached responses")
+		return errors.ErrInternalServerError()
+	}
+
+	ctx.Log().WithField("tags", args.Tags).Infof("purged %d cached responses", n)
+	return ctx.JSON(rest.OK, &purgeResponse{Purged: n})
+}
+
+type purgeArgs struct {
+	Tags []string `query:"tag"`
+}
+
+type purgeResponse struct {
+	Purged int64 `json:"purged"`
+}
@@ -1,7 +1,6 @@
 package emotes
 
 import (
-	"bytes"
 	"fmt"
 	"mime"
 	"os"
@@ -16,6 +15,7 @@ import (
 	"github.com/SevenTV/Common/mongo"
 	"github.com/SevenTV/Common/structures/v3"
 	"github.com/SevenTV/Common/utils"
+	"github.com/SevenTV/REST/src/audit"
 	"github.com/SevenTV/REST/src/aws"
 	"github.com/SevenTV/REST/src/global"
 	"github.com/SevenTV/REST/src/server/rest"
@@ -23,9 +23,7 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	"github.com/seventv/ImageProcessor/src/containers"
 	"github.com/seventv/ImageProcessor/src/image"
-	"github.com/seventv/ImageProcessor/src/job"
 	"github.com/sirupsen/logrus"
-	"github.com/streadway/amqp"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -44,13 +42,16 @@ func (r *create) Config() rest.RouteConfig {
 		Method: rest.POST,
 		Middleware: []rest.Middleware{
 			middleware.Auth(r.Ctx),
+			middleware.RateLimit(r.Ctx, "emotes.create", 5, time.Minute),
+			middleware.Idempotency(r.Ctx),
+			middleware.Audit(r.Ctx),
 		},
 	}
 }
 
 // Create Emote
 // @Summary Create Emote
-// @Description Upload a new emote
+// @Description Upload a new emote. Only the private (1) and zero-width (256) flags may be set; other flags are rejected
 // @Tags emotes
 // @Accept image/webp, image/gif, image/png, image/apng, image/avif, image/jpeg, image/tiff, image/webm
 // @Param X-Emote-Data header string false "Initial emote properties"
@@ -84,30 +85,13 @@ func (r *create) Handler(ctx *rest.Ctx) rest.APIError {
 
 	// these validations are all "free" as in we can do them before we download the file they try to upload.
 	args := &createData{}
-	if err := json.Unmarshal(req.Header.Peek("X-Emote-Data"), args); err != nil {
-		return errors.ErrInvalidRequest().SetDetail(err.Error())
+	if err := ctx.BindJSONHeader("X-Emote-Data", args); err != nil {
+		return err
 	}
+	name = args.Name
+	flags = args.Flags
 
-	// Validate: Name
-	{
-		if !emoteNameRegex.MatchString(args.Name) {
-			return errors.ErrInvalidRequest().SetDetail("Bad Emote Name")
-		}
-		name = args.Name
-	}
-	// Validate: Flags
-	{
-		if args.Flags != 0 {
-			if utils.BitField.HasBits(int64(args.Flags), int64(structures.EmoteFlagsPrivate)) {
-				flags |= structures.EmoteFlagsPrivate
-			}
-			if utils.BitField.HasBits(int64(args.Flags), int64(structures.EmoteFlagsZeroWidth)) {
-				flags |= structures.EmoteFlagsZeroWidth
-			}
-		}
-	}
-
-	// Validate: Tags
+	// Tags: remove duplicates
 	{
 		uniqueTags := map[string]bool{}
 		for _, v := range args.Tags {
@@ -115,9 +99,6 @@ func (r *create) Handler(ctx *rest.Ctx) rest.APIError {
 				continue
 			}
 			uniqueTags[v] = true
-			if !emoteTagRegex.MatchString(v) {
-				return errors.ErrInvalidRequest().SetDetail(fmt.Sprintf("Bad Emote Tag '%s'", v))
-			}
 		}
 
 		tags = make([]string, len(uniqueTags))
@@ -128,10 +109,40 @@ func (r *create) Handler(ctx *rest.Ctx) rest.APIError {
 		}
 	}
 
-	body := req.Body()
+	// Reject uploads declaring a size over the limit before reading any of them
+	limit := uploadLimit(r.Ctx.Config(), actor)
+	if cl := req.Header.ContentLength(); cl > 0 && int64(cl) > limit {
+		return rest.ErrPayloadTooLarge().SetFields(errors.Fields{"limit": limit})
+	}
+
+	id := primitive.NewObjectIDFromTimestamp(time.Now())
+
+	// Stream the upload into a temp file, rather than buffering it in memory
+	tmp := r.Ctx.Config().TempFolder
+	if tmp == "" {
+		tmp = "tmp"
+	}
+	if err := os.MkdirAll(tmp, 0700); err != nil {
+		ctx.Log().WithError(err).Error("failed to create temp folder")
+		return errors.ErrInternalServerError().SetDetail("Internal Server Error")
+	}
+	up, err := receiveUpload(ctx.BodyStream(), path.Join(tmp, fmt.Sprintf("%s.upload", id.Hex())), limit)
+	if err == errUploadTooLarge {
+		return rest.ErrPayloadTooLarge().SetFields(errors.Fields{"limit": limit})
+	} else if err != nil {
+		ctx.Log().WithError(err).Error("failed to write temp file")
+		return errors.ErrInternalServerError().SetDetail("Internal Server Error")
+	}
+	defer func() {
+		_ = os.Remove(up.Path)
+	}()
+	ctx.Log().WithFields(logrus.Fields{
+		"size":   up.Size,
+		"sha256": up.SHA256,
+	}).Debug("emote upload received")
 
 	// at this point we need to verify that whatever they upload is a "valid" file accepted file.
-	imgType, err := containers.ToType(body)
+	imgType, err := containers.ToType(up.Sample())
 	if err != nil {
 		return errors.ErrInvalidRequest().SetDetail("Unknown Upload Format")
 	}
@@ -141,25 +152,14 @@ func (r *create) Handler(ctx *rest.Ctx) rest.APIError {
 	frameCount := 0
 	width := 0
 	height := 0
-	tmpPath := ""
 
-	id := primitive.NewObjectIDFromTimestamp(time.Now())
-	{
-		tmp := r.Ctx.Config().TempFolder
-		if tmp == "" {
-			tmp = "tmp"
-		}
-		if err := os.MkdirAll(tmp, 0700); err != nil {
-			logrus.WithError(err).Error("failed to create temp folder")
-			return errors.ErrInternalServerError().SetDetail("Internal Server Error")
-		}
-		tmpPath = path.Join(tmp, fmt.Sprintf("%s.%s", id.Hex(), imgType))
-		if err := os.WriteFile(tmpPath, body, 0600); err != nil {
-			logrus.WithError(err).Error("failed to write temp file")
-			return errors.ErrInternalServerError().SetDetail("Internal Server Error")
-		}
-		defer os.Remove(tmpPath)
+	// The tools below and the content type of the stored file go by the extension
+	tmpPath := path.Join(tmp, fmt.Sprintf("%s.%s", id.Hex(), imgType))
+	if err := os.Rename(up.Path, tmpPath); err != nil {
+		ctx.Log().WithError(err).Error("failed to rename temp file")
+		return errors.ErrInternalServerError().SetDetail("Internal Server Error")
 	}
+	up.Path = tmpPath
 
 	switch imgType {
 	case image.AVI, image.AVIF, image.FLV, image.MP4, image.WEBM, image.GIF, image.JPEG, image.PNG, image.TIFF:
@@ -177,31 +177,31 @@ func (r *create) Handler(ctx *rest.Ctx) rest.APIError {
 			tmpPath,
 		).Output()
 		if err != nil {
-			logrus.WithError(err).Error("failed to run ffprobe command")
+			ctx.Log().WithError(err).Error("failed to run ffprobe command")
 			return errors.ErrInternalServerError().SetDetail("Internal Server Error")
 		}
 
 		splits := strings.Split(strings.TrimSpace(utils.B2S(output)), ",")
 		if len(splits) != 3 {
-			logrus.Errorf("ffprobe command returned bad results: %s", output)
+			ctx.Log().Errorf("ffprobe command returned bad results: %s", output)
 			return errors.ErrInternalServerError().SetDetail("Internal Server Error")
 		}
 
 		width, err = strconv.Atoi(splits[0])
 		if err != nil {
-			logrus.WithError(err).Errorf("ffprobe command returned bad results: %s", output)
+			ctx.Log().WithError(err).Errorf("ffprobe command returned bad results: %s", output)
 			return errors.ErrInternalServerError().SetDetail("Internal Server Error")
 		}
 
 		height, err = strconv.Atoi(splits[1])
 		if err != nil {
-			logrus.WithError(err).Errorf("ffprobe command returned bad results: %s", output)
+			ctx.Log().WithError(err).Errorf("ffprobe command returned bad results: %s", output)
 			return errors.ErrInternalServerError().SetDetail("Internal Server Error")
 		}
 
 		frameCount, err = strconv.Atoi(splits[2])
 		if err != nil {
-			logrus.WithError(err).Errorf("ffprobe command returned bad results: %s", output)
+			ctx.Log().WithError(err).Errorf("ffprobe command returned bad results: %s", output)
 			return errors.ErrInternalServerError().SetDetail("Internal Server Error")
 		}
 	case image.WEBP:
@@ -212,32 +212,32 @@ func (r *create) Handler(ctx *rest.Ctx) rest.APIError {
 			tmpPath,
 		).Output()
 		if err != nil {
-			logrus.WithError(err).Error("failed to run webpmux command")
+			ctx.Log().WithError(err).Error("failed to run webpmux command")
 			return errors.ErrInternalServerError().SetDetail("Internal Server Error")
 		}
 
 		matches := webpMuxRegex.FindAllStringSubmatch(utils.B2S(output), 1)
 		if len(matches) == 0 {
-			logrus.Errorf("webpmux command returned bad results: %s", output)
+			ctx.Log().Errorf("webpmux command returned bad results: %s", output)
 			return errors.ErrInternalServerError().SetDetail("Internal Server Error")
 		}
 
 		width, err = strconv.Atoi(matches[0][1])
 		if err != nil {
-			logrus.WithError(err).Errorf("ffprobe command returned bad results: %s", output)
+			ctx.Log().WithError(err).Errorf("ffprobe command returned bad results: %s", output)
 			return errors.ErrInternalServerError().SetDetail("Internal Server Error")
 		}
 
 		height, err = strconv.Atoi(matches[0][2])
 		if err != nil {
-			logrus.WithError(err).Errorf("ffprobe command returned bad results: %s", output)
+			ctx.Log().WithError(err).Errorf("ffprobe command returned bad results: %s", output)
 			return errors.ErrInternalServerError().SetDetail("Internal Server Error")
 		}
 
 		if matches[0][3] != "" {
 			frameCount, err = strconv.Atoi(matches[0][3])
 			if err != nil {
-				logrus.WithError(err).Errorf("ffprobe command returned bad results: %s", output)
+				ctx.Log().WithError(err).Errorf("ffprobe command returned bad results: %s", output)
 				return errors.ErrInternalServerError().SetDetail("Internal Server Error")
 			}
 		} else {
@@ -289,22 +289,13 @@ func (r *create) Handler(ctx *rest.Ctx) rest.APIError {
 		eb.Emote = parentEmote
 
 		// Check permissions
-		if actor.ID != parentEmote.OwnerID && !actor.HasPermission(structures.RolePermissionEditAnyEmote) {
-			ok := false
-			for _, ed := range parentEmote.Owner.Editors {
-				if ed.ID == actor.ID && ed.HasPermission(structures.UserEditorPermissionManageOwnedEmotes) {
-					ok = true // actor is an editor of emote owner with correct permissions
-					break
-				}
-			}
-			if !ok {
-				return errors.ErrInsufficientPrivilege()
-			}
+		if !canManageEmote(actor, parentEmote) {
+			return errors.ErrInsufficientPrivilege()
 		}
 
 		// Add as version?
 		if !args.Version.Diverged {
-			eb.AddVersion(&structures.EmoteVersion{
+			version := &structures.EmoteVersion{
 				ID:          id,
 				Name:        args.Version.Name,
 				Description: args.Version.Description,
@@ -313,11 +304,16 @@ func (r *create) Handler(ctx *rest.Ctx) rest.APIError {
 				State: structures.EmoteState{
 					Lifecycle: structures.EmoteLifecyclePending,
 				},
-			})
+			}
+			eb.AddVersion(version)
 			if _, err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).UpdateByID(ctx, parentEmote.ID, eb.Update); err != nil {
-				logrus.WithError(err).WithField("PARENT_EMOTE_ID", parentEmote.ID.Hex()).Error("mongo, failed to add version of emote in DB")
+				ctx.Log().WithError(err).WithField("PARENT_EMOTE_ID", parentEmote.ID.Hex()).Error("mongo, failed to add version of emote in DB")
 				return errors.ErrInternalServerError().SetFields(errors.Fields{"MONGO_ERROR": err.Error()})
 			}
+
+			ctx.AuditLog().
+				AddTarget(audit.TargetKindEmote, parentEmote.ID).
+				AddChange(parentEmote.ID, "versions", nil, version)
 		} else {
 			// Diverged version;
 			// will create a full document with a parent ID
@@ -337,48 +333,44 @@ func (r *create) Handler(ctx *rest.Ctx) rest.APIError {
 		}
 	}
 	if args.Version == nil || args.Version.Diverged {
-		if _, err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).InsertOne(ctx, eb.Emote); err != nil {
-			logrus.WithError(err).Error("mongo, failed to create pending emote in DB")
+		// The popularity is set, for the emote to be included when paging through emotes sorted by it
+		doc := struct {
+			structures.Emote `bson:",inline"`
+			Popularity       int32 `bson:"popularity"`
+		}{Emote: *eb.Emote}
+		if _, err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).InsertOne(ctx, doc); err != nil {
+			ctx.Log().WithError(err).Error("mongo, failed to create pending emote in DB")
 			return errors.ErrInternalServerError().SetDetail("Internal Server Error")
 		}
+
+		ctx.AuditLog().
+			AddTarget(audit.TargetKindEmote, eb.Emote.ID).
+			AddChange(eb.Emote.ID, "emote", nil, eb.Emote)
 	}
 
 	// at this point we are confident that the image is valid and that we can send it over to the EmoteProcessor and it will succeed.
-	fileKey := fmt.Sprintf("%s.%s", id.Hex(), imgType)
-	internalFilekey := fmt.Sprintf("internal/emote/%s", fileKey)
+	internalFilekey := internalFileKey(id, string(imgType))
+	file, err := os.Open(tmpPath)
+	if err != nil {
+		ctx.Log().WithError(err).Error("failed to open temp file")
+		return errors.ErrInternalServerError().SetDetail("Internal Server Error")
+	}
+	defer file.Close()
 	if err := r.Ctx.Inst().AwsS3.UploadFile(
 		ctx,
 		r.Ctx.Config().Aws.Bucket,
 		internalFilekey,
-		bytes.NewBuffer(body),
+		file,
 		utils.StringPointer(mime.TypeByExtension(path.Ext(tmpPath))),
 		aws.AclPrivate,
 		aws.DefaultCacheControl,
 	); err != nil {
-		logrus.WithError(err).Errorf("failed to upload image to aws")
+		ctx.Log().WithError(err).Errorf("failed to upload image to aws")
 		return errors.ErrInternalServerError().SetDetail("Internal Server Error")
 	}
 
-	providerDetails, _ := json.Marshal(job.RawProviderDetailsAws{
-		Bucket: r.Ctx.Config().Aws.Bucket,
-		Key:    internalFilekey,
-	})
-
-	consumerDetails, _ := json.Marshal(job.ResultConsumerDetailsAws{
-		Bucket:    r.Ctx.Config().Aws.Bucket,
-		KeyFolder: fmt.Sprintf("emote/%s", id.Hex()),
-	})
-
-	msg, _ := json.Marshal(&job.Job{
-		ID:                    id.Hex(),
-		RawProvider:           job.AwsProvider,
-		RawProviderDetails:    providerDetails,
-		ResultConsumer:        job.AwsConsumer,
-		ResultConsumerDetails: consumerDetails,
-	})
-
-	if err := r.Ctx.Inst().Rmq.Publish(r.Ctx.Config().Rmq.JobQueueName, "application/json", amqp.Persistent, msg); err != nil {
-		logrus.WithError(err).Errorf("failed to add job to rmq")
+	if err := PublishJob(ctx, r.Ctx, id, internalFilekey, ctx.RequestID()); err != nil {
+		ctx.Log().WithError(err).Errorf("failed to add job to rmq")
 		return errors.ErrInternalServerError().SetDetail("Internal Server Error")
 	}
 
@@ -386,11 +378,11 @@ func (r *create) Handler(ctx *rest.Ctx) rest.APIError {
 }
 
 type createData struct {
-	Name    string               `json:"name"`
-	Tags    [MAX_TAGS]string     `json:"tags"`
-	Flags   structures.EmoteFlag `json:"flags"`
+	Name    string               `json:"name" validate:"required,match=emote_name"`
+	Tags    [MAX_TAGS]string     `json:"tags" validate:"match=emote_tag"`
+	Flags   structures.EmoteFlag `json:"flags" validate:"mask=emote_flags"`
 	Version *struct {
-		ParentID    string `json:"parent_id"`
+		ParentID    string `json:"parent_id" validate:"required,objectid"`
 		Diverged    bool   `json:"diverged"`
 		Name        string `json:"name"`
 		Description string `json:"description"`
@@ -412,4 +404,10 @@ var (
 	webpMuxRegex   = regexp.MustCompile(`Canvas size: (\d+) x (\d+)(?:\n?.*\n){0,2}(?:Number of frames: (\d+))?`) // capture group 1: width, 2: height, 3: frame count or empty which means 1
 )
 
+func init() {
+	rest.RegisterPattern("emote_name", emoteNameRegex)
+	rest.RegisterPattern("emote_tag", emoteTagRegex)
+	rest.RegisterMask("emote_flags", int64(editableEmoteFlags))
+}
+
 var json = jsoniter.ConfigCompatibleWithStandardLibrary
@@ -0,0 +1,186 @@
+package emotes_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server"
+	"github.com/SevenTV/REST/src/server/rest"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// emoteUsers: seed a user allowed to create emotes and one who is not
+func emoteUsers(t *testing.T, gCtx global.Context) map[string]*structures.User {
+	mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+
+	role := &structures.Role{ID: primitive.NewObjectID(), Name: "Default", Allowed: structures.RolePermissionCreateEmote}
+	users := map[string]*structures.User{
+		"creator": {ID: primitive.NewObjectID(), Username: "creator", RoleIDs: []primitive.ObjectID{role.ID}},
+		"user":    {ID: primitive.NewObjectID(), Username: "user", RoleIDs: []primitive.ObjectID{}},
+	}
+	if err := mgo.Seed(mongo.CollectionNameRoles, role); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range users {
+		if err := mgo.Seed(mongo.CollectionNameUsers, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return users
+}
+
+func authHeader(t *testing.T, h *server.Harness, u *structures.User) string {
+	tok, err := h.Token(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + tok
+}
+
+// fakeFFProbe: put an ffprobe on the PATH which reports the given "width,height,frames"
+func fakeFFProbe(t *testing.T, output string) {
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho '" + output + "'\n"
+	if err := os.WriteFile(path.Join(dir, "ffprobe"), []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func testPNG(t *testing.T) []byte {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCreateEmote(t *testing.T) {
+	img := testPNG(t)
+
+	tests := []struct {
+		name    string
+		user    string
+		data    string
+		body    []byte
+		ffprobe string
+		// the upload limit of the creator's role, if any
+		roleLimit int64
+		status    int
+		// the error detail expected, if any
+		detail string
+	}{
+		{name: "anonymous", data: `{"name":"peepoHappy"}`, body: img, status: 401},
+		{name: "without the permission", user: "user", data: `{"name":"peepoHappy"}`, body: img, status: 403},
+		{name: "missing data", user: "creator", body: img, status: 400},
+		{name: "bad name", user: "creator", data: `{"name":"a b"}`, body: img, status: 400},
+		{name: "bad tag", user: "creator", data: `{"name":"peepoHappy","tags":["NO"]}`, body: img, status: 400},
+		{name: "flags outside the mask", user: "creator", data: `{"name":"peepoHappy","flags":2}`, body: img, status: 400},
+		{name: "too large", user: "creator", data: `{"name":"peepoHappy"}`, body: make([]byte, 2621441), status: 413},
+		{name: "over the role's lower limit", user: "creator", data: `{"name":"peepoHappy"}`, body: img, roleLimit: 10, status: 413},
+		{name: "unknown format", user: "creator", data: `{"name":"peepoHappy"}`, body: []byte("not an image"), status: 400, detail: "Unknown Upload Format"},
+		{name: "too wide", user: "creator", data: `{"name":"peepoHappy"}`, body: img, ffprobe: "2000,32,1", status: 400, detail: "Bad Input Width"},
+		{name: "too many frames", user: "creator", data: `{"name":"peepoHappy"}`, body: img, ffprobe: "32,32,1000", status: 400, detail: "Too Many Frames"},
+		{name: "created", user: "creator", data: `{"name":"peepoHappy","tags":["cute","cute"],"flags":256}`, body: img, ffprobe: "32,32,1", status: 201},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := fakes.NewConfig()
+			config.TempFolder = t.TempDir()
+			gCtx, err := fakes.NewContext(context.Background(), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			users := emoteUsers(t, gCtx)
+			if tt.roleLimit > 0 {
+				config.Limits.Uploads.Roles = map[string]int64{users["creator"].RoleIDs[0].Hex(): tt.roleLimit}
+			}
+			if tt.ffprobe != "" {
+				fakeFFProbe(t, tt.ffprobe)
+			}
+
+			h := server.NewHarness(gCtx)
+			headers := map[string]string{"Content-Type": "image/png"}
+			if tt.user != "" {
+				headers["Authorization"] = authHeader(t, h, users[tt.user])
+			}
+			if tt.data != "" {
+				headers["X-Emote-Data"] = tt.data
+			}
+
+			res := h.Request("POST", "/v3/emotes", tt.body, headers)
+			if res.StatusCode() != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, res.StatusCode(), res.Body())
+			}
+			if tt.detail != "" && !strings.Contains(string(res.Body()), tt.detail) {
+				t.Errorf("expected %q, got %s", tt.detail, res.Body())
+			}
+
+			emotes := gCtx.Inst().Mongo.(*fakes.Mongo).Documents(mongo.CollectionNameEmotes)
+			jobs := gCtx.Inst().Rmq.(*fakes.Rmq).Published(config.Rmq.JobQueueName)
+			if tt.status != 201 {
+				if len(emotes) != 0 || len(jobs) != 0 {
+					t.Errorf("expected nothing to be created, got %d emotes and %d jobs", len(emotes), len(jobs))
+				}
+				return
+			}
+
+			result := map[string]string{}
+			if err := json.Unmarshal(res.Body(), &result); err != nil {
+				t.Fatal(err)
+			}
+			id, err := primitive.ObjectIDFromHex(result["id"])
+			if err != nil {
+				t.Fatalf("expected the id of the version, got %s", res.Body())
+			}
+
+			// The emote is pending, with its version
+			if len(emotes) != 1 {
+				t.Fatalf("expected an emote to be created, got %d", len(emotes))
+			}
+			e := &structures.Emote{}
+			if err := bson.Unmarshal(emotes[0], e); err != nil {
+				t.Fatal(err)
+			}
+			if e.Name != "peepoHappy" || e.OwnerID != users["creator"].ID || e.Flags != structures.EmoteFlagsZeroWidth {
+				t.Errorf("unexpected emote %+v", e)
+			}
+			if p, ok := emotes[0].Lookup("popularity").Int32OK(); !ok || p != 0 {
+				t.Errorf("expected the emote to be created with no popularity, got %v", emotes[0].Lookup("popularity"))
+			}
+			if len(e.Tags) != 1 || e.Tags[0] != "cute" {
+				t.Errorf("expected duplicate tags to be removed, got %v", e.Tags)
+			}
+			if len(e.Versions) != 1 || e.Versions[0].ID != id || e.Versions[0].State.Lifecycle != structures.EmoteLifecyclePending || e.Versions[0].FrameCount != 1 {
+				t.Errorf("expected a pending version %s, got %+v", id.Hex(), e.Versions)
+			}
+
+			// The upload is stored, and a job published for it
+			obj, ok := gCtx.Inst().AwsS3.(*fakes.S3).Object(config.Aws.Bucket, "internal/emote/"+id.Hex()+".png")
+			if !ok || !bytes.Equal(obj.Data, tt.body) || obj.ContentType != "image/png" || obj.ACL != "private" {
+				t.Errorf("expected the upload to be stored privately, got %+v", obj.ContentType)
+			}
+			if len(jobs) != 1 || jobs[0].Headers[rest.RequestIDHeader] == "" || !strings.Contains(string(jobs[0].Body), id.Hex()) {
+				t.Errorf("expected a job for the version, got %+v", jobs)
+			}
+
+			// The temp files are cleaned up
+			if files, _ := os.ReadDir(config.TempFolder); len(files) != 0 {
+				t.Errorf("expected no temp files left, got %d", len(files))
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package emotes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/audit"
+	"github.com/SevenTV/REST/src/deletions"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v3/middleware"
+	"github.com/SevenTV/REST/src/server/v3/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type del struct {
+	Ctx global.Context
+	// Whether the route deletes a single version, rather than the whole emote
+	version bool
+}
+
+func newDelete(gCtx global.Context) rest.Route {
+	return &del{Ctx: gCtx}
+}
+
+func newDeleteVersion(gCtx global.Context) rest.Route {
+	return &del{Ctx: gCtx, version: true}
+}
+
+func (r *del) Config() rest.RouteConfig {
+	uri := "/{emote}"
+	if r.version {
+		uri = "/{emote}/versions/{version}"
+	}
+
+	return rest.RouteConfig{
+		URI:    uri,
+		Method: rest.DELETE,
+		Middleware: []rest.Middleware{
+			middleware.Auth(r.Ctx),
+			middleware.RateLimit(r.Ctx, "emotes.delete", 30, time.Minute),
+			middleware.Idempotency(r.Ctx),
+			middleware.Audit(r.Ctx),
+		},
+	}
+}
+
+// Delete Emote
+// @Summary Delete Emote
+// @Description Delete an emote, or a single version of it. The files of the emote are removed once the retention window has passed, until which the deletion can be undone
+// @Tags emotes
+// @Accept json
+// @Param emote path string true "the ID of the emote"
+// @Param version path string false "the ID of the version, to only delete that version"
+// @Param data body deleteData true "why the emote is deleted"
+// @Produce json
+// @Success 200 {object} model.EmoteDeletion
+// @Router /emotes/{emote} [delete]
+// @Router /emotes/{emote}/versions/{version} [delete]
+func (r *del) Handler(ctx *rest.Ctx) rest.APIError {
+	actor, ok := ctx.GetActor()
+	if !ok {
+		return errors.ErrUnauthorized()
+	}
+
+	id, err := ctx.ObjectIDParam("emote")
+	if err != nil {
+		return err
+	}
+	var versionID *primitive.ObjectID
+	if r.version {
+		vid, err := ctx.ObjectIDParam("version")
+		if err != nil {
+			return err
+		}
+		versionID = &vid
+	}
+
+	args := &deleteData{}
+	if err = ctx.BindBody(args); err != nil {
+		return err
+	}
+
+	emote, err := findManagedEmote(ctx, r.Ctx, actor, id)
+	if err != nil {
+		return err
+	}
+
+	// Mark the versions as deleted, keeping aside the state they were in
+	eb := structures.NewEmoteBuilder(emote)
+	now := time.Now()
+	deletion := &deletions.Deletion{
+		ID:        primitive.NewObjectIDFromTimestamp(now),
+		EmoteID:   emote.ID,
+		VersionID: versionID,
+		Versions:  []deletions.Version{},
+		ActorID:   actor.ID,
+		Reason:    args.Reason,
+		DeletedAt: now,
+		PurgeAt:   now.Add(deletionRetention(r.Ctx)),
+	}
+	for i, ver := range emote.Versions {
+		if ver.State.Lifecycle == structures.EmoteLifecycleDeleted || (versionID != nil && ver.ID != *versionID) {
+			continue
+		}
+
+		deletion.Versions = append(deletion.Versions, deletions.Version{
+			ID:        ver.ID,
+			Lifecycle: ver.State.Lifecycle,
+		})
+		ver.State.Lifecycle = structures.EmoteLifecycleDeleted
+		eb.Update.Set(fmt.Sprintf("versions.%d.state.lifecycle", i), structures.EmoteLifecycleDeleted)
+	}
+	if len(deletion.Versions) == 0 {
+		// The emote or version was already deleted
+		return errors.ErrUnknownEmote()
+	}
+
+	// The deletion is recorded first, so that the versions can always be restored
+	col := r.Ctx.Inst().Mongo.Collection(deletions.CollectionName)
+	if _, er := col.InsertOne(ctx, deletion); er != nil {
+		ctx.Log().WithError(er).Error("mongo, failed to record emote deletion")
+		return errors.ErrInternalServerError()
+	}
+	if _, er := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).UpdateByID(ctx, emote.ID, eb.Update); er != nil {
+		ctx.Log().WithError(er).Error("mongo, failed to delete emote")
+		if _, er = col.DeleteOne(ctx, bson.M{"_id": deletion.ID}); er != nil {
+			ctx.Log().WithError(er).Error("mongo, failed to remove the record of a failed emote deletion")
+		}
+		return errors.ErrInternalServerError()
+	}
+
+	ctx.AuditLog().
+		AddTarget(audit.TargetKindEmote, emote.ID).
+		AddChange(emote.ID, "deletion", nil, deletion)
+
+	// Let the caches and subscribers know of the change
+	if er := r.Ctx.Inst().Redis.RawClient().Publish(ctx, fmt.Sprintf("7tv-events:sub:emotes:%s", emote.ID.Hex()), "1").Err(); er != nil {
+		ctx.Log().WithError(er).Error("redis, failed to publish emote change")
+	}
+
+	result := model.NewEmoteDeletion(deletion)
+	return ctx.JSON(rest.OK, &result)
+}
+
+type deleteData struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// deletionRetention: how long the files of deleted emotes are kept
+func deletionRetention(gCtx global.Context) time.Duration {
+	if s := gCtx.Config().Emotes.DeletionRetention; s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return deletions.DefaultRetention
+}
@@ -0,0 +1,182 @@
+package emotes_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/deletions"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/server"
+	"github.com/SevenTV/REST/src/server/v3/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// twoVersions: an emote of the owner with a live and a pending version
+func twoVersions(owner *structures.User, flags structures.EmoteFlag) *structures.Emote {
+	e := ownedEmote(owner, "original", flags)
+	e.Versions = append(e.Versions, &structures.EmoteVersion{
+		ID:    primitive.NewObjectID(),
+		State: structures.EmoteState{Lifecycle: structures.EmoteLifecyclePending},
+	})
+	return e
+}
+
+func TestDeleteEmote(t *testing.T) {
+	tests := []struct {
+		name  string
+		user  string
+		flags structures.EmoteFlag
+		// the version to delete, by index, rather than the whole emote. -1 for an unknown version
+		version *int
+		// the versions already deleted, by index
+		deleted []int
+		body    string
+		status  int
+		// the versions expected to be deleted, by index
+		want []int
+	}{
+		{name: "anonymous", body: `{"reason":"spam"}`, status: 401},
+		{name: "another user", user: "stranger", flags: structures.EmoteFlagsListed, body: `{"reason":"spam"}`, status: 403},
+		{name: "an editor without the permission", user: "viewer", flags: structures.EmoteFlagsListed, body: `{"reason":"spam"}`, status: 403},
+		{name: "private to another user", user: "stranger", flags: structures.EmoteFlagsPrivate, body: `{"reason":"spam"}`, status: 404},
+		{name: "no reason", user: "owner", body: `{}`, status: 400},
+		{name: "reason too long", user: "owner", body: `{"reason":"` + strings.Repeat("a", 501) + `"}`, status: 400},
+		{name: "deleted by the owner", user: "owner", flags: structures.EmoteFlagsListed, body: `{"reason":"spam"}`, status: 200, want: []int{0, 1}},
+		{name: "deleted by an editor", user: "editor", body: `{"reason":"spam"}`, status: 200, want: []int{0, 1}},
+		{name: "deleted by a moderator", user: "mod", flags: structures.EmoteFlagsPrivate, body: `{"reason":"spam"}`, status: 200, want: []int{0, 1}},
+		{name: "the rest of a partly deleted emote", user: "owner", deleted: []int{1}, body: `{"reason":"spam"}`, status: 200, want: []int{0}},
+		{name: "already deleted", user: "owner", deleted: []int{0, 1}, body: `{"reason":"spam"}`, status: 404},
+		{name: "a version", user: "owner", version: intPtr(1), body: `{"reason":"broken"}`, status: 200, want: []int{1}},
+		{name: "a deleted version", user: "owner", version: intPtr(1), deleted: []int{1}, body: `{"reason":"broken"}`, status: 404},
+		{name: "an unknown version", user: "owner", version: intPtr(-1), body: `{"reason":"broken"}`, status: 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := fakes.NewConfig()
+			config.Emotes.DeletionRetention = 3600
+			gCtx, err := fakes.NewContext(context.Background(), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+			users := manageUsers(t, gCtx)
+
+			emote := twoVersions(users["owner"], tt.flags)
+			lifecycles := []structures.EmoteLifecycle{emote.Versions[0].State.Lifecycle, emote.Versions[1].State.Lifecycle}
+			for _, i := range tt.deleted {
+				emote.Versions[i].State.Lifecycle = structures.EmoteLifecycleDeleted
+			}
+			if err := mgo.Seed(mongo.CollectionNameEmotes, emote); err != nil {
+				t.Fatal(err)
+			}
+
+			h := server.NewHarness(gCtx)
+			headers := map[string]string{}
+			if tt.user != "" {
+				headers["Authorization"] = authHeader(t, h, users[tt.user])
+			}
+
+			uri := "/v3/emotes/" + emote.ID.Hex()
+			if tt.version != nil {
+				vid := primitive.NewObjectID()
+				if *tt.version >= 0 {
+					vid = emote.Versions[*tt.version].ID
+				}
+				uri += "/versions/" + vid.Hex()
+			}
+			res := h.Request("DELETE", uri, []byte(tt.body), headers)
+			if res.StatusCode() != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, res.StatusCode(), res.Body())
+			}
+
+			stored := &structures.Emote{}
+			if err := mgo.Collection(mongo.CollectionNameEmotes).FindOne(gCtx, bson.M{"_id": emote.ID}).Decode(stored); err != nil {
+				t.Fatal(err)
+			}
+			records := mgo.Documents(deletions.CollectionName)
+			if tt.status != 200 {
+				if len(records) != 0 {
+					t.Errorf("expected no deletion to be recorded, got %d", len(records))
+				}
+				for i, v := range stored.Versions {
+					if v.State.Lifecycle != emote.Versions[i].State.Lifecycle {
+						t.Errorf("expected version %d to be left as it was", i)
+					}
+				}
+				return
+			}
+
+			// The versions are marked as deleted, with the state they were in recorded
+			for i, v := range stored.Versions {
+				want := emote.Versions[i].State.Lifecycle
+				if containsInt(tt.want, i) {
+					want = structures.EmoteLifecycleDeleted
+				}
+				if v.State.Lifecycle != want {
+					t.Errorf("expected version %d to be in state %d, got %d", i, want, v.State.Lifecycle)
+				}
+			}
+			if len(records) != 1 {
+				t.Fatalf("expected the deletion to be recorded, got %d", len(records))
+			}
+			d := &deletions.Deletion{}
+			if err := bson.Unmarshal(records[0], d); err != nil {
+				t.Fatal(err)
+			}
+			if d.EmoteID != emote.ID || d.ActorID != users[tt.user].ID || len(d.Versions) != len(tt.want) || d.Reason == "" {
+				t.Fatalf("unexpected deletion %+v", d)
+			}
+			for j, i := range tt.want {
+				if d.Versions[j].ID != emote.Versions[i].ID || d.Versions[j].Lifecycle != lifecycles[i] {
+					t.Errorf("expected version %d to be recorded in state %d, got %+v", i, lifecycles[i], d.Versions[j])
+				}
+			}
+			if (tt.version != nil) != (d.VersionID != nil) {
+				t.Errorf("expected the deleted version to be recorded, got %v", d.VersionID)
+			}
+			if retention := d.PurgeAt.Sub(d.DeletedAt); retention != time.Hour {
+				t.Errorf("expected the configured retention, got %s", retention)
+			}
+
+			result := &model.EmoteDeletion{}
+			if err := json.Unmarshal(res.Body(), result); err != nil {
+				t.Fatal(err)
+			}
+			if result.ID != d.ID.Hex() || result.EmoteID != emote.ID.Hex() || len(result.Versions) != len(tt.want) || result.RestorableUntil == "" {
+				t.Errorf("unexpected result %+v", result)
+			}
+
+			// Once every version is deleted, the emote can no longer be found
+			res = h.Request("GET", "/v3/emotes/"+emote.ID.Hex(), nil, headers)
+			gone := len(tt.want)+len(tt.deleted) == 2
+			if (res.StatusCode() == 404) != gone {
+				t.Errorf("expected the emote to be gone=%t, got status %d", gone, res.StatusCode())
+			}
+			res = h.Request("GET", "/v3/emotes?lifecycle=PENDING&count=true", nil, headers)
+			searchable := !containsInt(tt.want, 1) && !containsInt(tt.deleted, 1)
+			if found := string(res.Header.Peek("X-Total-Count")) == "1"; found != searchable {
+				t.Errorf("expected the pending version to be searchable=%t", searchable)
+			}
+		})
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func containsInt(s []int, v int) bool {
+	for _, i := range s {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package emotes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/audit"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v3/middleware"
+	"github.com/SevenTV/REST/src/server/v3/model"
+)
+
+// The flags which may be set when creating an emote, and toggled by its owner afterwards
+const editableEmoteFlags = structures.EmoteFlagsPrivate | structures.EmoteFlagsZeroWidth
+
+type edit struct {
+	Ctx global.Context
+}
+
+func newEdit(gCtx global.Context) rest.Route {
+	return &edit{gCtx}
+}
+
+func (r *edit) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/{emote}",
+		Method: rest.PATCH,
+		Middleware: []rest.Middleware{
+			middleware.Auth(r.Ctx),
+			middleware.RateLimit(r.Ctx, "emotes.edit", 30, time.Minute),
+			middleware.Idempotency(r.Ctx),
+			middleware.Audit(r.Ctx),
+		},
+	}
+}
+
+// Edit Emote
+// @Summary Edit Emote
+// @Description Change the name, tags or flags of an emote. Omitted fields are left as they are
+// @Tags emotes
+// @Accept json
+// @Param emote path string true "the ID of the emote"
+// @Param data body editData true "the changes to make"
+// @Produce json
+// @Success 200 {object} model.Emote
+// @Router /emotes/{emote} [patch]
+func (r *edit) Handler(ctx *rest.Ctx) rest.APIError {
+	actor, ok := ctx.GetActor()
+	if !ok {
+		return errors.ErrUnauthorized()
+	}
+
+	id, err := ctx.ObjectIDParam("emote")
+	if err != nil {
+		return err
+	}
+
+	args := &editData{}
+	if err = ctx.BindBody(args); err != nil {
+		return err
+	}
+	if args.Tags != nil && countTags(*args.Tags) > MAX_TAGS {
+		return errors.ErrInvalidRequest().SetDetail("Bad Fields (tags)").SetFields(errors.Fields{
+			"tags": fmt.Sprintf("must be at most %d items", MAX_TAGS),
+		})
+	}
+
+	emote, err := findManagedEmote(ctx, r.Ctx, actor, id)
+	if err != nil {
+		return err
+	}
+	if len(model.NewEmote(emote).Versions) == 0 {
+		// Every version was deleted
+		return errors.ErrUnknownEmote()
+	}
+
+	eb := structures.NewEmoteBuilder(emote)
+	log := ctx.AuditLog().AddTarget(audit.TargetKindEmote, emote.ID)
+
+	if args.Name != nil && *args.Name != emote.Name {
+		old := emote.Name
+		eb.SetName(*args.Name)
+		log.AddChange(emote.ID, "name", old, emote.Name)
+	}
+
+	if args.Tags != nil && !sameTags(emote.Tags, *args.Tags) {
+		old := emote.Tags
+		eb.SetTags(*args.Tags, true)
+		log.AddChange(emote.ID, "tags", old, emote.Tags)
+	}
+
+	if args.Flags != nil {
+		old := emote.Flags
+		flags := old&^editableEmoteFlags | *args.Flags
+		if flags != old {
+			eb.SetFlags(flags)
+			log.AddChange(emote.ID, "flags", old, flags)
+		}
+	}
+
+	if len(eb.Update) > 0 {
+		if _, er := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).UpdateByID(ctx, emote.ID, eb.Update); er != nil {
+			ctx.Log().WithError(er).Error("mongo, failed to update emote")
+			return errors.ErrInternalServerError()
+		}
+
+		// Let the caches and subscribers know of the change
+		if er := r.Ctx.Inst().Redis.RawClient().Publish(ctx, fmt.Sprintf("7tv-events:sub:emotes:%s", emote.ID.Hex()), "1").Err(); er != nil {
+			ctx.Log().WithError(er).Error("redis, failed to publish emote change")
+		}
+	}
+
+	result := model.NewEmote(emote)
+	return ctx.JSON(rest.OK, &result)
+}
+
+type editData struct {
+	Name  *string               `json:"name" validate:"min=2,match=emote_name"`
+	Tags  *[]string             `json:"tags" validate:"match=emote_tag"` // at most MAX_TAGS
+	Flags *structures.EmoteFlag `json:"flags" validate:"mask=emote_flags"`
+}
+
+// countTags: the amount of tags in a list, ignoring empty ones
+func countTags(tags []string) int {
+	n := 0
+	for _, t := range tags {
+		if t != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// sameTags: whether two lists hold the same tags, in any order and ignoring duplicates
+func sameTags(a []string, b []string) bool {
+	set := func(tags []string) map[string]bool {
+		m := make(map[string]bool, len(tags))
+		for _, t := range tags {
+			if t != "" {
+				m[t] = true
+			}
+		}
+		return m
+	}
+
+	sa, sb := set(a), set(b)
+	if len(sa) != len(sb) {
+		return false
+	}
+	for t := range sb {
+		if !sa[t] {
+			return false
+		}
+	}
+	return true
+}
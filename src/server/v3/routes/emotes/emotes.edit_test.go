@@ -0,0 +1,162 @@
+package emotes_test
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/audit"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/server"
+	"github.com/SevenTV/REST/src/server/v3/model"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestEditEmote(t *testing.T) {
+	tests := []struct {
+		name string
+		user string
+		// the flags of the emote, and whether its versions are deleted
+		flags   structures.EmoteFlag
+		deleted bool
+		body    string
+		status  int
+		// the expected emote once edited, and the changes expected to be logged
+		wantName  string
+		wantTags  string
+		wantFlags structures.EmoteFlag
+		changes   string
+	}{
+		{name: "anonymous", body: `{"name":"renamed"}`, status: 401},
+		{name: "another user", user: "stranger", flags: structures.EmoteFlagsListed, body: `{"name":"renamed"}`, status: 403},
+		{name: "an editor without the permission", user: "viewer", flags: structures.EmoteFlagsListed, body: `{"name":"renamed"}`, status: 403},
+		{name: "private to another user", user: "stranger", flags: structures.EmoteFlagsPrivate, body: `{"name":"renamed"}`, status: 404},
+		{name: "every version deleted", user: "owner", deleted: true, body: `{"name":"renamed"}`, status: 404},
+		{name: "bad name", user: "owner", body: `{"name":"a b"}`, status: 400},
+		{name: "bad tag", user: "owner", body: `{"tags":["NO"]}`, status: 400},
+		{name: "flags outside the mask", user: "owner", body: `{"flags":2}`, status: 400},
+		{name: "too many tags", user: "owner", body: `{"tags":["aaa","bbb","ccc","ddd","eee","fff","ggg"]}`, status: 400},
+		{
+			name: "as many tags as allowed", user: "owner", body: `{"tags":["aaa","bbb","ccc","ddd","eee","fff",""]}`, status: 200,
+			wantName: "original", wantTags: "aaa,bbb,ccc,ddd,eee,fff", changes: "tags",
+		},
+		{
+			name: "renamed by the owner", user: "owner", flags: structures.EmoteFlagsListed, body: `{"name":"renamed"}`, status: 200,
+			wantName: "renamed", wantTags: "old", wantFlags: structures.EmoteFlagsListed, changes: "name",
+		},
+		{
+			name: "tagged by an editor", user: "editor", body: `{"tags":["new","cute","new"]}`, status: 200,
+			wantName: "original", wantTags: "cute,new", changes: "tags",
+		},
+		{
+			name: "made private by a moderator", user: "mod", flags: structures.EmoteFlagsListed, body: `{"flags":1}`, status: 200,
+			wantName: "original", wantTags: "old", wantFlags: structures.EmoteFlagsListed | structures.EmoteFlagsPrivate, changes: "flags",
+		},
+		{
+			name: "flags cleared", user: "owner", flags: structures.EmoteFlagsListed | structures.EmoteFlagsPrivate | structures.EmoteFlagsZeroWidth, body: `{"flags":0}`, status: 200,
+			wantName: "original", wantTags: "old", wantFlags: structures.EmoteFlagsListed, changes: "flags",
+		},
+		{
+			name: "everything", user: "owner", body: `{"name":"renamed","tags":["new"],"flags":256}`, status: 200,
+			wantName: "renamed", wantTags: "new", wantFlags: structures.EmoteFlagsZeroWidth, changes: "flags,name,tags",
+		},
+		{
+			name: "nothing changed", user: "owner", body: `{"name":"original","tags":["old"],"flags":0}`, status: 200,
+			wantName: "original", wantTags: "old",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gCtx, err := fakes.NewContext(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+			users := manageUsers(t, gCtx)
+
+			emote := ownedEmote(users["owner"], "original", tt.flags)
+			emote.Tags = []string{"old"}
+			if tt.deleted {
+				emote.Versions[0].State.Lifecycle = structures.EmoteLifecycleDeleted
+			}
+			if err := mgo.Seed(mongo.CollectionNameEmotes, emote); err != nil {
+				t.Fatal(err)
+			}
+
+			sub := gCtx.Inst().Redis.RawClient().Subscribe(gCtx, "7tv-events:sub:emotes:"+emote.ID.Hex())
+			defer sub.Close()
+			if _, err := sub.Receive(gCtx); err != nil {
+				t.Fatal(err)
+			}
+
+			h := server.NewHarness(gCtx)
+			headers := map[string]string{}
+			if tt.user != "" {
+				headers["Authorization"] = authHeader(t, h, users[tt.user])
+			}
+
+			res := h.Request("PATCH", "/v3/emotes/"+emote.ID.Hex(), []byte(tt.body), headers)
+			if res.StatusCode() != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, res.StatusCode(), res.Body())
+			}
+
+			stored := &structures.Emote{}
+			if err := mgo.Collection(mongo.CollectionNameEmotes).FindOne(gCtx, bson.M{"_id": emote.ID}).Decode(stored); err != nil {
+				t.Fatal(err)
+			}
+			published := false
+			select {
+			case <-sub.Channel():
+				published = true
+			case <-time.After(time.Millisecond * 200):
+			}
+
+			if tt.status != 200 {
+				if stored.Name != "original" || stored.Flags != tt.flags || published {
+					t.Errorf("expected the emote to be left as it was, got %+v", stored)
+				}
+				return
+			}
+
+			result := &model.Emote{}
+			if err := json.Unmarshal(res.Body(), result); err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(result.Tags)
+			sort.Strings(stored.Tags)
+			if result.Name != tt.wantName || strings.Join(result.Tags, ",") != tt.wantTags || result.Flags != int32(tt.wantFlags) {
+				t.Errorf("expected %s %s %d, got %+v", tt.wantName, tt.wantTags, tt.wantFlags, result)
+			}
+			if stored.Name != tt.wantName || strings.Join(stored.Tags, ",") != tt.wantTags || stored.Flags != tt.wantFlags {
+				t.Errorf("expected the changes to be stored, got %+v", stored)
+			}
+			if published != (tt.changes != "") {
+				t.Errorf("expected the change to be published=%t", tt.changes != "")
+			}
+
+			// The changes are logged
+			logs := mgo.Documents(audit.CollectionName)
+			if len(logs) != 1 {
+				t.Fatalf("expected the edit to be logged, got %d logs", len(logs))
+			}
+			log := &audit.Log{}
+			if err := bson.Unmarshal(logs[0], log); err != nil {
+				t.Fatal(err)
+			}
+			keys := []string{}
+			for _, c := range log.Changes {
+				keys = append(keys, c.Key)
+			}
+			sort.Strings(keys)
+			if strings.Join(keys, ",") != tt.changes || log.ActorID != users[tt.user].ID {
+				t.Errorf("expected changes to %q by %s, got %v by %s", tt.changes, tt.user, keys, log.ActorID.Hex())
+			}
+		})
+	}
+}
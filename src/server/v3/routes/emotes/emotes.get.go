@@ -0,0 +1,121 @@
+package emotes
+
+import (
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v3/middleware"
+	"github.com/SevenTV/REST/src/server/v3/model"
+	"github.com/SevenTV/REST/src/visibility"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The maximum amount of ancestors followed to build the lineage of an emote
+const MAX_LINEAGE_DEPTH = 25
+
+type get struct {
+	Ctx global.Context
+}
+
+func newGet(gCtx global.Context) rest.Route {
+	return &get{gCtx}
+}
+
+func (r *get) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/{emote}",
+		Method: rest.GET,
+		Middleware: []rest.Middleware{
+			middleware.OptionalAuth(r.Ctx),
+			middleware.Cache(r.Ctx, time.Minute),
+		},
+	}
+}
+
+// Get Emote
+// @Summary Get Emote
+// @Description Get a single emote, with its owner, versions and lineage
+// @Tags emotes
+// @Produce json
+// @Param emote path string true "the ID of the emote"
+// @Success 200 {object} model.Emote
+// @Router /emotes/{emote} [get]
+func (r *get) Handler(ctx *rest.Ctx) rest.APIError {
+	id, err := ctx.ObjectIDParam("emote")
+	if err != nil {
+		return err
+	}
+	ctx.AddCacheTags("emotes:" + id.Hex())
+
+	actor, _ := ctx.GetActor()
+	if actor != nil {
+		// The emote may be one only the actor can see
+		ctx.Response.Header.Set("Cache-Control", "private")
+	}
+
+	visible, er := visibility.Filter(ctx, r.Ctx, actor, false)
+	if er != nil {
+		ctx.Log().WithError(er).Error("mongo, failed to find the emotes visible to the actor")
+		return errors.ErrInternalServerError()
+	}
+
+	emote := &structures.Emote{}
+	if er = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).FindOne(ctx, bson.M{
+		"$and": bson.A{bson.M{"_id": id}, visible},
+	}).Decode(emote); er != nil {
+		if er == mongo.ErrNoDocuments {
+			return errors.ErrUnknownEmote()
+		}
+		ctx.Log().WithError(er).Error("mongo, failed to find emote")
+		return errors.ErrInternalServerError()
+	}
+
+	result := model.NewEmote(emote)
+	if len(result.Versions) == 0 {
+		// Every version was deleted
+		return errors.ErrUnknownEmote()
+	}
+
+	// Owner
+	owner := &structures.User{}
+	if er = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).FindOne(ctx, bson.M{
+		"_id": emote.OwnerID,
+	}, options.FindOne().SetProjection(bson.M{
+		"username":     1,
+		"display_name": 1,
+		"avatar_id":    1,
+	})).Decode(owner); er == nil {
+		result.Owner = model.NewUserPartial(owner)
+	} else if er != mongo.ErrNoDocuments {
+		ctx.Log().WithError(er).Error("mongo, failed to find the owner of emote")
+		return errors.ErrInternalServerError()
+	}
+
+	// Lineage
+	parentID := emote.ParentID
+	seen := map[primitive.ObjectID]bool{emote.ID: true}
+	for parentID != nil && !seen[*parentID] && len(result.Lineage) < MAX_LINEAGE_DEPTH {
+		seen[*parentID] = true
+		result.Lineage = append(result.Lineage, parentID.Hex())
+
+		parent := &structures.Emote{}
+		if er = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).FindOne(ctx, bson.M{
+			"_id": parentID,
+		}, options.FindOne().SetProjection(bson.M{"parent_id": 1})).Decode(parent); er != nil {
+			if er == mongo.ErrNoDocuments {
+				break
+			}
+			ctx.Log().WithError(er).Error("mongo, failed to find the parent of emote")
+			return errors.ErrInternalServerError()
+		}
+		parentID = parent.ParentID
+	}
+
+	return ctx.JSON(rest.OK, &result)
+}
@@ -0,0 +1,185 @@
+package emotes_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server"
+	"github.com/SevenTV/REST/src/server/v3/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// manageUsers: seed an owner, an editor who may manage their emotes, one who may not,
+// a user unrelated to them and one who may edit any emote
+func manageUsers(t *testing.T, gCtx global.Context) map[string]*structures.User {
+	mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+
+	role := &structures.Role{ID: primitive.NewObjectID(), Name: "Moderator", Allowed: structures.RolePermissionEditAnyEmote}
+	users := map[string]*structures.User{
+		"owner":    {ID: primitive.NewObjectID(), Username: "owner", DisplayName: "Owner", RoleIDs: []primitive.ObjectID{}},
+		"editor":   {ID: primitive.NewObjectID(), Username: "editor", RoleIDs: []primitive.ObjectID{}},
+		"viewer":   {ID: primitive.NewObjectID(), Username: "viewer", RoleIDs: []primitive.ObjectID{}},
+		"stranger": {ID: primitive.NewObjectID(), Username: "stranger", RoleIDs: []primitive.ObjectID{}},
+		"mod":      {ID: primitive.NewObjectID(), Username: "mod", RoleIDs: []primitive.ObjectID{role.ID}},
+	}
+	users["owner"].Editors = []*structures.UserEditor{
+		{ID: users["editor"].ID, Permissions: structures.UserEditorPermissionManageOwnedEmotes},
+		{ID: users["viewer"].ID, Permissions: structures.UserEditorPermissionManageEmoteSets},
+	}
+	if err := mgo.Seed(mongo.CollectionNameRoles, role); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range users {
+		if err := mgo.Seed(mongo.CollectionNameUsers, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return users
+}
+
+// ownedEmote: an emote of the owner, with a live version
+func ownedEmote(owner *structures.User, name string, flags structures.EmoteFlag) *structures.Emote {
+	return &structures.Emote{
+		ID:      primitive.NewObjectID(),
+		OwnerID: owner.ID,
+		Name:    name,
+		Flags:   flags,
+		Tags:    []string{},
+		Versions: []*structures.EmoteVersion{{
+			ID:         primitive.NewObjectID(),
+			Name:       name,
+			FrameCount: 1,
+			State:      structures.EmoteState{Lifecycle: structures.EmoteLifecycleLive},
+		}},
+	}
+}
+
+func TestGetEmote(t *testing.T) {
+	gCtx, err := fakes.NewContext(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := manageUsers(t, gCtx)
+	owner := users["owner"]
+
+	emotes := map[string]*structures.Emote{
+		"listed":   ownedEmote(owner, "listed", structures.EmoteFlagsListed),
+		"unlisted": ownedEmote(owner, "unlisted", 0),
+		"private":  ownedEmote(owner, "private", structures.EmoteFlagsPrivate|structures.EmoteFlagsListed),
+		"deleted":  ownedEmote(owner, "deleted", structures.EmoteFlagsListed),
+		"original": ownedEmote(owner, "original", structures.EmoteFlagsListed),
+		"parent":   ownedEmote(owner, "parent", structures.EmoteFlagsListed),
+		"child":    ownedEmote(owner, "child", structures.EmoteFlagsListed),
+		"loop":     ownedEmote(owner, "loop", structures.EmoteFlagsListed),
+	}
+	emotes["listed"].Tags = []string{"cute"}
+	emotes["listed"].Versions = append(emotes["listed"].Versions, &structures.EmoteVersion{
+		ID:         primitive.NewObjectID(),
+		Name:       "animated",
+		FrameCount: 8,
+		State:      structures.EmoteState{Lifecycle: structures.EmoteLifecycleLive},
+		Formats: []structures.EmoteFormat{{
+			Name:  structures.EmoteFormatNameWEBP,
+			Files: []structures.EmoteFile{{Name: "1x.webp", Width: 32, Height: 32, Animated: true, Length: 1024}},
+		}},
+	}, &structures.EmoteVersion{
+		ID:    primitive.NewObjectID(),
+		State: structures.EmoteState{Lifecycle: structures.EmoteLifecycleDeleted},
+	})
+	emotes["deleted"].Versions[0].State.Lifecycle = structures.EmoteLifecycleDeleted
+	emotes["parent"].ParentID = &emotes["original"].ID
+	emotes["child"].ParentID = &emotes["parent"].ID
+	emotes["loop"].ParentID = &emotes["loop"].ID
+	for _, e := range emotes {
+		if err := gCtx.Inst().Mongo.(*fakes.Mongo).Seed(mongo.CollectionNameEmotes, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		user  string
+		emote string
+		// the id requested instead of that of the emote, if set
+		id     string
+		status int
+		// checks on the emote returned
+		check func(t *testing.T, e *model.Emote)
+	}{
+		{name: "listed", emote: "listed", status: 200, check: func(t *testing.T, e *model.Emote) {
+			if e.ID != emotes["listed"].ID.Hex() || e.Name != "listed" || e.Flags != int32(structures.EmoteFlagsListed) || strings.Join(e.Tags, ",") != "cute" {
+				t.Errorf("unexpected emote %+v", e)
+			}
+			if e.Owner == nil || e.Owner.ID != owner.ID.Hex() || e.Owner.Username != "owner" || e.Owner.DisplayName != "Owner" {
+				t.Errorf("expected the owner, got %+v", e.Owner)
+			}
+			if len(e.Versions) != 2 || e.Versions[0].Lifecycle != "LIVE" || !e.Animated {
+				t.Fatalf("expected the versions which are not deleted, got %+v", e.Versions)
+			}
+			if f := e.Versions[1].Formats; len(f) != 1 || f[0].Name != "image/webp" || len(f[0].Files) != 1 || f[0].Files[0].Size != 1024 {
+				t.Errorf("expected the files of the version, got %+v", f)
+			}
+		}},
+		{name: "unlisted", emote: "unlisted", status: 200},
+		{name: "private to the public", emote: "private", status: 404},
+		{name: "private to another user", user: "stranger", emote: "private", status: 404},
+		{name: "private to an editor without the permission", user: "viewer", emote: "private", status: 404},
+		{name: "private to the owner", user: "owner", emote: "private", status: 200},
+		{name: "private to an editor", user: "editor", emote: "private", status: 200},
+		{name: "private to a moderator", user: "mod", emote: "private", status: 200},
+		{name: "every version deleted", emote: "deleted", status: 404},
+		{name: "unknown", id: primitive.NewObjectID().Hex(), status: 404},
+		{name: "bad id", id: "nope", status: 400},
+		{name: "lineage", emote: "child", status: 200, check: func(t *testing.T, e *model.Emote) {
+			want := emotes["parent"].ID.Hex() + "," + emotes["original"].ID.Hex()
+			if e.ParentID != emotes["parent"].ID.Hex() || strings.Join(e.Lineage, ",") != want {
+				t.Errorf("expected the lineage %s, got %v", want, e.Lineage)
+			}
+		}},
+		{name: "lineage loop", emote: "loop", status: 200, check: func(t *testing.T, e *model.Emote) {
+			if len(e.Lineage) != 0 {
+				t.Errorf("expected the emote not to be its own ancestor, got %v", e.Lineage)
+			}
+		}},
+	}
+
+	h := server.NewHarness(gCtx)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := tt.id
+			if id == "" {
+				id = emotes[tt.emote].ID.Hex()
+			}
+			headers := map[string]string{}
+			if tt.user != "" {
+				headers["Authorization"] = authHeader(t, h, users[tt.user])
+			}
+
+			res := h.Request("GET", "/v3/emotes/"+id, nil, headers)
+			if res.StatusCode() != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, res.StatusCode(), res.Body())
+			}
+			if tt.status == 404 && !strings.Contains(string(res.Body()), "Unknown Emote") {
+				t.Errorf("expected the emote to be unknown, got %s", res.Body())
+			}
+			if tt.user != "" && string(res.Header.Peek("Cache-Control")) != "private" {
+				t.Error("expected the response not to be shared")
+			}
+			if tt.check == nil {
+				return
+			}
+
+			e := &model.Emote{}
+			if err := json.Unmarshal(res.Body(), e); err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, e)
+		})
+	}
+}
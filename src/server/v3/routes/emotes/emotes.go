@@ -1,9 +1,22 @@
 package emotes
 
 import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/utils"
 	"github.com/SevenTV/REST/src/global"
 	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v3/middleware"
 	"github.com/SevenTV/REST/src/server/v3/model"
+	"github.com/SevenTV/REST/src/visibility"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Route struct {
@@ -11,7 +24,6 @@ type Route struct {
 }
 
 func New(gCtx global.Context) rest.Route {
-	listen(gCtx)
 	return &Route{gCtx}
 }
 
@@ -21,20 +33,177 @@ func (r *Route) Config() rest.RouteConfig {
 		Method: rest.GET,
 		Children: []rest.Route{
 			newCreate(r.Ctx),
+			newGet(r.Ctx),
+			newEdit(r.Ctx),
+			newDelete(r.Ctx),
+			newDeleteVersion(r.Ctx),
+			newRestore(r.Ctx),
+			newRestoreVersion(r.Ctx),
+		},
+		Middleware: []rest.Middleware{
+			middleware.OptionalAuth(r.Ctx),
+			middleware.Cache(r.Ctx, time.Minute, "emotes"),
 		},
-		Middleware: []rest.Middleware{},
 	}
 }
 
 // Emote Search
 // @Summary Search Emotes
-// @Description Search for emotes
+// @Description Search for emotes by the start of their name and by tags. Private and unlisted emotes are only included for those who may see them
 // @Tags emotes
 // @Produce json
 // @Param query query string false "search by emote name / tags"
+// @Param animated query boolean false "only include animated (true) or static (false) emotes"
+// @Param zero_width query boolean false "only include zero-width (true) or regular (false) emotes"
+// @Param lifecycle query string false "only include emotes with a version in this state (default LIVE)" Enums(LIVE, PENDING, PROCESSING, FAILED, DISABLED)
+// @Param owner query string false "filter by the ID of the emote's owner"
+// @Param since query string false "only include emotes created at or after this time (RFC3339)"
+// @Param until query string false "only include emotes created before this time (RFC3339)"
+// @Param sort query string false "the order of the results (default relevance when searching, popularity otherwise)" Enums(relevance, popularity, date)
+// @Param limit query integer false "the maximum amount of emotes to return (default 50, max 150)"
+// @Param after query string false "the cursor of the page to get, from the next link of the previous page"
+// @Param count query boolean false "whether to count the emotes matching the search, in the X-Total-Count header"
+// @Header 200 {string} Link "the next page of emotes, if there is one"
+// @Header 200 {integer} X-Total-Count "the amount of emotes matching the search, if requested"
 // @Success 200 {array} model.Emote
 // @Router /emotes [get]
 func (r *Route) Handler(ctx *rest.Ctx) rest.APIError {
-	res := []model.Emote{{}}
-	return ctx.JSON(rest.OK, &res)
+	args := &searchArgs{
+		Lifecycle: "LIVE",
+		Sort:      "popularity",
+	}
+	if err := ctx.Bind(args); err != nil {
+		return err
+	}
+	if args.Query != "" && !ctx.QueryArgs().Has("sort") {
+		args.Sort = "relevance"
+	}
+
+	actor, _ := ctx.GetActor()
+	if actor != nil {
+		// Results vary by actor, so they must not be shared
+		ctx.Response.Header.Set("Cache-Control", "private")
+	}
+
+	// Filter
+	visible, er := visibility.Filter(ctx, r.Ctx, actor, true)
+	if er != nil {
+		ctx.Log().WithError(er).Error("mongo, failed to find the emotes visible to the actor")
+		return errors.ErrInternalServerError()
+	}
+	// Conditions on versions must all be met by the same version
+	version := bson.M{"state.lifecycle": searchLifecycles[args.Lifecycle]}
+	if args.Animated != nil {
+		if *args.Animated {
+			version["frame_count"] = bson.M{"$gt": 1}
+		} else {
+			version["frame_count"] = bson.M{"$lte": 1}
+		}
+	}
+	and := bson.A{visible, bson.M{"versions": bson.M{"$elemMatch": version}}}
+
+	query := strings.ToLower(strings.TrimSpace(args.Query))
+	if query != "" {
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"name": bson.M{"$regex": "^" + regexp.QuoteMeta(query), "$options": "i"}},
+			bson.M{"tags": query},
+		}})
+	}
+	if args.ZeroWidth != nil {
+		op := utils.Ternary(*args.ZeroWidth, "$bitsAllSet", "$bitsAllClear").(string)
+		and = append(and, bson.M{"flags": bson.M{op: structures.EmoteFlagsZeroWidth}})
+	}
+	if !args.Owner.IsZero() {
+		and = append(and, bson.M{"owner_id": args.Owner})
+	}
+	if !args.Since.IsZero() || !args.Until.IsZero() {
+		tr := bson.M{}
+		if !args.Since.IsZero() {
+			tr["$gte"] = primitive.NewObjectIDFromTimestamp(args.Since)
+		}
+		if !args.Until.IsZero() {
+			tr["$lt"] = primitive.NewObjectIDFromTimestamp(args.Until)
+		}
+		and = append(and, bson.M{"_id": tr})
+	}
+	filter := bson.M{"$and": and}
+
+	// Sort
+	sort := bson.D{{Key: "_id", Value: -1}}
+	switch args.Sort {
+	case "popularity":
+		sort = bson.D{{Key: "popularity", Value: -1}, {Key: "_id", Value: -1}}
+	case "relevance":
+		sort = bson.D{{Key: "relevance", Value: -1}, {Key: "popularity", Value: -1}, {Key: "_id", Value: -1}}
+	}
+	page, err := ctx.Paginate(sort, map[string]bsontype.Type{
+		"popularity": bsontype.Int32,
+		"relevance":  bsontype.Int32,
+	}, 50, 150)
+	if err != nil {
+		return err
+	}
+
+	// Popularity is kept on the emotes by popularity.Watch
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: filter}}}
+	if args.Sort == "relevance" {
+		// Relevance: an exact name match ranks above a matching tag, which ranks above a name starting with the query
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{"relevance": bson.M{"$add": bson.A{
+			bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{bson.M{"$toLower": "$name"}, query}}, 2, 0}},
+			bson.M{"$cond": bson.A{bson.M{"$in": bson.A{query, bson.M{"$ifNull": bson.A{"$tags", bson.A{}}}}}, 1, 0}},
+		}}}}})
+	}
+	pipeline = append(pipeline, page.Stages()...)
+
+	col := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes)
+	// Counting every match is costly, so it is only done when asked for
+	total := int64(-1)
+	if args.Count {
+		if total, er = col.CountDocuments(ctx, filter); er != nil {
+			ctx.Log().WithError(er).Error("mongo, failed to count emotes")
+			return errors.ErrInternalServerError()
+		}
+	}
+
+	cur, er := col.Aggregate(ctx, pipeline)
+	if er != nil {
+		ctx.Log().WithError(er).Error("mongo, failed to search emotes")
+		return errors.ErrInternalServerError()
+	}
+
+	emotes := []*structures.Emote{}
+	if er = page.Decode(ctx, cur, &emotes); er != nil {
+		ctx.Log().WithError(er).Error("mongo, failed to decode emotes")
+		return errors.ErrInternalServerError()
+	}
+	ctx.SetPageHeaders(page, total)
+
+	// Changes to the listed emotes drop the cached page, while new matches show up once it expires
+	result := make([]model.Emote, len(emotes))
+	for i, e := range emotes {
+		result[i] = model.NewEmote(e)
+		ctx.AddCacheTags("emotes:" + e.ID.Hex())
+	}
+
+	return ctx.JSON(rest.OK, &result)
+}
+
+type searchArgs struct {
+	Query     string             `query:"query" validate:"max=100"`
+	Animated  *bool              `query:"animated"`
+	ZeroWidth *bool              `query:"zero_width"`
+	Lifecycle string             `query:"lifecycle" validate:"oneof=LIVE|PENDING|PROCESSING|FAILED|DISABLED"`
+	Owner     primitive.ObjectID `query:"owner"`
+	Since     time.Time          `query:"since"`
+	Until     time.Time          `query:"until"`
+	Sort      string             `query:"sort" validate:"oneof=relevance|popularity|date"`
+	Count     bool               `query:"count"`
+}
+
+var searchLifecycles = map[string]structures.EmoteLifecycle{
+	"LIVE":       structures.EmoteLifecycleLive,
+	"PENDING":    structures.EmoteLifecyclePending,
+	"PROCESSING": structures.EmoteLifecycleProcessing,
+	"FAILED":     structures.EmoteLifecycleFailed,
+	"DISABLED":   structures.EmoteLifecycleDisabled,
 }
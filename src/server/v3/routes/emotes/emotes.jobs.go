@@ -0,0 +1,73 @@
+package emotes
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/seventv/ImageProcessor/src/job"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// internalFileKey: the key of an uploaded emote file, kept privately for the processor to download
+func internalFileKey(id primitive.ObjectID, ext string) string {
+	return fmt.Sprintf("internal/emote/%s.%s", id.Hex(), ext)
+}
+
+// FindInternalFile: get the key of the file uploaded for an emote version, so that it can be processed again
+func FindInternalFile(ctx context.Context, gCtx global.Context, id primitive.ObjectID) (string, error) {
+	keys, err := gCtx.Inst().AwsS3.ListObjects(ctx, gCtx.Config().Aws.Bucket, strings.TrimSuffix(internalFileKey(id, ""), "."))
+	if err != nil {
+		return "", err
+	}
+
+	for _, k := range keys {
+		// Only a single file is uploaded per version, but make sure the prefix did not match a longer name
+		if strings.TrimSuffix(path.Base(k), path.Ext(k)) == id.Hex() {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("no file uploaded for emote version %s", id.Hex())
+}
+
+// PublishJob: send an uploaded emote file to the processor. The ID of the request is passed along,
+// so that the events of the job can be correlated with it
+func PublishJob(ctx context.Context, gCtx global.Context, id primitive.ObjectID, key string, requestID string) error {
+	providerDetails, _ := json.Marshal(job.RawProviderDetailsAws{
+		Bucket: gCtx.Config().Aws.Bucket,
+		Key:    key,
+	})
+
+	consumerDetails, _ := json.Marshal(job.ResultConsumerDetailsAws{
+		Bucket:    gCtx.Config().Aws.Bucket,
+		KeyFolder: fmt.Sprintf("emote/%s", id.Hex()),
+	})
+
+	msg, _ := json.Marshal(&job.Job{
+		ID:                    id.Hex(),
+		RawProvider:           job.AwsProvider,
+		RawProviderDetails:    providerDetails,
+		ResultConsumer:        job.AwsConsumer,
+		ResultConsumerDetails: consumerDetails,
+	})
+
+	// The processor does not echo message headers back in its events,
+	// so the request ID is also kept aside for the processing listener to pick up
+	if gCtx.Inst().Redis != nil {
+		gCtx.Inst().Redis.RawClient().Set(ctx, requestIDKey(id), requestID, JOB_REQUEST_ID_TTL)
+	}
+
+	headers := amqp.Table{rest.RequestIDHeader: requestID}
+	if err := gCtx.Inst().Rmq.Publish(gCtx.Config().Rmq.JobQueueName, "application/json", amqp.Persistent, headers, msg); err != nil {
+		return err
+	}
+	if gCtx.Inst().Prometheus != nil {
+		gCtx.Inst().Prometheus.EmoteJobPublished()
+	}
+
+	return nil
+}
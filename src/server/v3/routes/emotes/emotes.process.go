@@ -10,21 +10,32 @@ import (
 	"github.com/SevenTV/Common/structures/v3"
 	"github.com/SevenTV/Common/utils"
 	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
 	"github.com/sirupsen/logrus"
 	"github.com/streadway/amqp"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func listen(gCtx global.Context) {
-	epl := &EmoteProcessingListener{gCtx}
-	go epl.Listen()
+// How long the request ID of a job is kept for its events to be correlated
+const JOB_REQUEST_ID_TTL = time.Hour * 24
+
+// NewEmoteProcessingListener: create a listener for the events of emote processing jobs. It must be started with Listen
+func NewEmoteProcessingListener(gCtx global.Context) *EmoteProcessingListener {
+	return &EmoteProcessingListener{
+		Ctx:  gCtx,
+		stop: make(chan struct{}),
+	}
 }
 
 type EmoteProcessingListener struct {
 	Ctx global.Context
+
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
+// Listen: consume the update and result queues until the listener is stopped, the queues are closed or the global context is canceled
 func (epl *EmoteProcessingListener) Listen() {
 	rmq := epl.Ctx.Inst().Rmq
 	if rmq == nil { // RMQ not set up; ignore
@@ -48,20 +59,28 @@ func (epl *EmoteProcessingListener) Listen() {
 	go func() {
 		defer wg.Done()
 
-		var msg amqp.Delivery
-		for {
+		for epl.running() {
 			select {
-			case msg = <-ch1:
+			case msg, ok := <-ch1:
+				if !ok {
+					return
+				}
+
 				evt := &EmoteJobEvent{}
-				if err = json.Unmarshal(msg.Body, evt); err != nil {
+				if err := json.Unmarshal(msg.Body, evt); err != nil {
 					logrus.WithError(err).Error("EmoteProcessingListener, failed to decode emote processing event")
 					return
 				}
+				if evt.RequestID == "" {
+					evt.RequestID = epl.requestID(msg, evt.JobID)
+				}
 
-				if err = epl.HandleUpdateEvent(evt); err != nil {
-					logrus.WithError(err).Error("EmoteProcessingListener, failed to handle event")
+				if err := epl.HandleUpdateEvent(evt); err != nil {
+					logrus.WithError(err).WithField("request_id", evt.RequestID).Error("EmoteProcessingListener, failed to handle event")
 				}
 				_ = msg.Ack(false)
+			case <-epl.stop:
+				return
 			case <-epl.Ctx.Done():
 				return
 			}
@@ -71,20 +90,28 @@ func (epl *EmoteProcessingListener) Listen() {
 	go func() {
 		defer wg.Done()
 
-		var msg amqp.Delivery
-		for {
+		for epl.running() {
 			select {
-			case msg = <-ch2:
+			case msg, ok := <-ch2:
+				if !ok {
+					return
+				}
+
 				evt := &EmoteResultEvent{}
-				if err = json.Unmarshal(msg.Body, evt); err != nil {
+				if err := json.Unmarshal(msg.Body, evt); err != nil {
 					logrus.WithError(err).Error("EmoteProcessingListener, failed to decode emote result event")
 					return
 				}
+				if evt.RequestID == "" {
+					evt.RequestID = epl.requestID(msg, evt.JobID)
+				}
 
-				if err = epl.HandleResultEvent(evt); err != nil {
-					logrus.WithError(err).Error("EmoteProcessingListener, failed to handle event")
+				if err := epl.HandleResultEvent(evt); err != nil {
+					logrus.WithError(err).WithField("request_id", evt.RequestID).Error("EmoteProcessingListener, failed to handle event")
 				}
 				_ = msg.Ack(false)
+			case <-epl.stop:
+				return
 			case <-epl.Ctx.Done():
 				return
 			}
@@ -95,6 +122,41 @@ func (epl *EmoteProcessingListener) Listen() {
 	logrus.Info("stopped emote processing listener")
 }
 
+// Stop: stop consuming once the messages currently being handled are done with. Listen returns when it has stopped
+func (epl *EmoteProcessingListener) Stop() {
+	epl.stopOnce.Do(func() {
+		close(epl.stop)
+	})
+}
+
+// running: whether or not the listener should take another message
+func (epl *EmoteProcessingListener) running() bool {
+	select {
+	case <-epl.stop:
+		return false
+	default:
+		return true
+	}
+}
+
+// requestID: find the ID of the request which created a job, so that its events can be correlated with it.
+// Used for events which do not carry it themselves
+func (epl *EmoteProcessingListener) requestID(msg amqp.Delivery, jobID primitive.ObjectID) string {
+	if v, ok := msg.Headers[rest.RequestIDHeader].(string); ok && v != "" {
+		return v
+	}
+	if epl.Ctx.Inst().Redis == nil {
+		return ""
+	}
+
+	v, _ := epl.Ctx.Inst().Redis.RawClient().Get(epl.Ctx, requestIDKey(jobID)).Result()
+	return v
+}
+
+func requestIDKey(jobID primitive.ObjectID) string {
+	return fmt.Sprintf("emote-processing:%s:request-id", jobID.Hex())
+}
+
 func (epl *EmoteProcessingListener) HandleUpdateEvent(evt *EmoteJobEvent) error {
 	// Fetch the emote
 	eb := structures.NewEmoteBuilder(&structures.Emote{})
@@ -105,14 +167,17 @@ func (epl *EmoteProcessingListener) HandleUpdateEvent(evt *EmoteJobEvent) error
 	}
 
 	// Store the state in redis
-	epl.Ctx.Inst().Redis.RawClient().Set(epl.Ctx, fmt.Sprintf("emote-processing:%s:status", evt.JobID), evt.Type, time.Minute)
+	epl.Ctx.Inst().Redis.RawClient().Set(epl.Ctx, fmt.Sprintf("emote-processing:%s:status", evt.JobID.Hex()), string(evt.Type), time.Minute)
 
-	logf := logrus.WithFields(logrus.Fields{"emote_id": evt.JobID})
+	logf := logrus.WithFields(logrus.Fields{"emote_id": evt.JobID, "request_id": evt.RequestID})
 	switch evt.Type {
 	case EmoteJobEventTypeStarted:
+		if epl.Ctx.Inst().Prometheus != nil {
+			epl.Ctx.Inst().Prometheus.EmoteJobStarted()
+		}
 		ver, i := eb.GetVersion(evt.JobID)
 		if ver != nil {
-			eb.Update.Set(fmt.Sprintf("versions.%d.state.lifecycle", i), structures.EmoteLifecycleProcessing)
+			epl.setLifecycle(eb, ver, i, structures.EmoteLifecycleProcessing)
 		}
 		logf.Info("Emote Processing Started")
 	case EmoteJobEventTypeCompleted:
@@ -122,14 +187,14 @@ func (epl *EmoteProcessingListener) HandleUpdateEvent(evt *EmoteJobEvent) error
 			logf.Error("couldn't find version of the emote for this job")
 			break
 		}
-		eb.Update.Set(fmt.Sprintf("versions.%d.state.lifecycle", i), structures.EmoteLifecycleLive)
+		epl.setLifecycle(eb, ver, i, structures.EmoteLifecycleLive)
 	default:
 		logf.Infof("Emote Processing Status: %s", evt.Type)
 	}
 
 	// Update the emote in DB if status was updated
 	if len(eb.Update) > 0 {
-		if _, err := epl.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).UpdateOne(epl.Ctx, bson.M{"versions.id": eb.Emote.ID}, eb.Update); err != nil {
+		if _, err := epl.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).UpdateOne(epl.Ctx, bson.M{"versions.id": evt.JobID}, eb.Update); err != nil {
 			return err
 		}
 	}
@@ -146,6 +211,16 @@ func (epl *EmoteProcessingListener) HandleResultEvent(evt *EmoteResultEvent) err
 	}).Decode(eb.Emote); err != nil {
 		return err
 	}
+	if epl.Ctx.Inst().Prometheus != nil {
+		epl.Ctx.Inst().Prometheus.EmoteJobFinished(evt.Success)
+	}
+
+	logf := logrus.WithFields(logrus.Fields{"emote_id": evt.JobID, "request_id": evt.RequestID})
+	if evt.Success {
+		logf.Info("Emote Processing Succeeded")
+	} else {
+		logf.WithField("error", evt.Error).Warn("Emote Processing Failed")
+	}
 
 	// Map formats
 	formats := make(map[structures.EmoteFormatName]*structures.EmoteFormat)
@@ -189,9 +264,8 @@ func (epl *EmoteProcessingListener) HandleResultEvent(evt *EmoteResultEvent) err
 
 	lc := utils.Ternary(evt.Success, structures.EmoteLifecycleLive, structures.EmoteLifecycleFailed).(structures.EmoteLifecycle)
 	ver, verIndex := eb.GetVersion(evt.JobID)
-	ver.State.Lifecycle = lc
+	epl.setLifecycle(eb, ver, verIndex, lc)
 	ver.Formats = formatList
-	eb.Update.Set(fmt.Sprintf("versions.%d.state.lifecycle", verIndex), lc)
 	eb.Update.Set(fmt.Sprintf("versions.%d.formats", verIndex), formatList)
 
 	// Update database
@@ -199,14 +273,29 @@ func (epl *EmoteProcessingListener) HandleResultEvent(evt *EmoteResultEvent) err
 		"versions.id": evt.JobID,
 	}, eb.Update)
 
-	epl.Ctx.Inst().Redis.RawClient().Publish(epl.Ctx, fmt.Sprintf("7tv-events:sub:emotes:%s", evt.JobID.Hex()), "1")
+	epl.Ctx.Inst().Redis.RawClient().Publish(epl.Ctx, fmt.Sprintf("7tv-events:sub:emotes:%s", eb.Emote.ID.Hex()), "1")
 	return err
 }
 
+// setLifecycle: change the state of a version. Deleted versions stay deleted, and are instead set to the state when restored
+func (epl *EmoteProcessingListener) setLifecycle(eb *structures.EmoteBuilder, ver *structures.EmoteVersion, i int, lc structures.EmoteLifecycle) {
+	if ver.State.Lifecycle == structures.EmoteLifecycleDeleted {
+		if err := restoredLifecycle(epl.Ctx, epl.Ctx, ver.ID, lc); err != nil {
+			logrus.WithError(err).WithField("emote_id", ver.ID).Error("mongo, failed to update the deletion of emote")
+		}
+		return
+	}
+
+	ver.State.Lifecycle = lc
+	eb.Update.Set(fmt.Sprintf("versions.%d.state.lifecycle", i), lc)
+}
+
 type EmoteJobEvent struct {
 	JobID     primitive.ObjectID
 	Type      EmoteJobEventType
 	Timestamp time.Time
+	// The ID of the request which created the job
+	RequestID string `json:"request_id,omitempty"`
 }
 
 type EmoteJobEventType string
@@ -229,6 +318,8 @@ type EmoteResultEvent struct {
 	Success bool               `json:"success"`
 	Files   []EmoteResultFile  `json:"files"`
 	Error   string             `json:"error"`
+	// The ID of the request which created the job
+	RequestID string `json:"request_id,omitempty"`
 }
 
 type EmoteResultFile struct {
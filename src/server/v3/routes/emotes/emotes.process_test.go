@@ -0,0 +1,263 @@
+package emotes_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/deletions"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v3/routes/emotes"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// processingEmote: seed an emote with a version being processed, and a deleted one with its deletion
+func processingEmote(t *testing.T, gCtx global.Context) (*structures.Emote, *deletions.Deletion) {
+	mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+
+	e := &structures.Emote{ID: primitive.NewObjectID(), OwnerID: primitive.NewObjectID(), Name: "peepoHappy", Tags: []string{}}
+	for _, lc := range []structures.EmoteLifecycle{structures.EmoteLifecyclePending, structures.EmoteLifecycleDeleted} {
+		e.Versions = append(e.Versions, &structures.EmoteVersion{ID: primitive.NewObjectID(), State: structures.EmoteState{Lifecycle: lc}})
+	}
+	d := &deletions.Deletion{
+		ID:       primitive.NewObjectID(),
+		EmoteID:  e.ID,
+		Versions: []deletions.Version{{ID: e.Versions[1].ID, Lifecycle: structures.EmoteLifecyclePending}},
+	}
+	if err := mgo.Seed(mongo.CollectionNameEmotes, e); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgo.Seed(deletions.CollectionName, d); err != nil {
+		t.Fatal(err)
+	}
+	return e, d
+}
+
+// processingState: the stored lifecycle of each version, and the lifecycle the deleted one is restored to
+func processingState(t *testing.T, gCtx global.Context) (*structures.Emote, structures.EmoteLifecycle) {
+	mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+
+	e := &structures.Emote{}
+	if err := bson.Unmarshal(mgo.Documents(mongo.CollectionNameEmotes)[0], e); err != nil {
+		t.Fatal(err)
+	}
+	d := &deletions.Deletion{}
+	if err := bson.Unmarshal(mgo.Documents(deletions.CollectionName)[0], d); err != nil {
+		t.Fatal(err)
+	}
+	return e, d.Versions[0].Lifecycle
+}
+
+func TestHandleUpdateEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		evt     emotes.EmoteJobEventType
+		deleted bool
+		unknown bool
+		// the lifecycle of the version afterwards, or of its deletion if deleted
+		want structures.EmoteLifecycle
+	}{
+		{name: "started", evt: emotes.EmoteJobEventTypeStarted, want: structures.EmoteLifecycleProcessing},
+		{name: "progress", evt: emotes.EmoteJobEventTypeStageOne, want: structures.EmoteLifecyclePending},
+		{name: "completed", evt: emotes.EmoteJobEventTypeCompleted, want: structures.EmoteLifecycleLive},
+		{name: "deleted version stays deleted", evt: emotes.EmoteJobEventTypeCompleted, deleted: true, want: structures.EmoteLifecycleLive},
+		{name: "unknown job", evt: emotes.EmoteJobEventTypeStarted, unknown: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gCtx, err := fakes.NewContext(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e, _ := processingEmote(t, gCtx)
+
+			ver := e.Versions[0]
+			if tt.deleted {
+				ver = e.Versions[1]
+			}
+			jobID := ver.ID
+			if tt.unknown {
+				jobID = primitive.NewObjectID()
+			}
+
+			err = emotes.NewEmoteProcessingListener(gCtx).HandleUpdateEvent(&emotes.EmoteJobEvent{JobID: jobID, Type: tt.evt})
+			if tt.unknown {
+				if err != mongo.ErrNoDocuments {
+					t.Errorf("expected no emote to be found, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, restored := processingState(t, gCtx)
+			if tt.deleted {
+				if got.Versions[1].State.Lifecycle != structures.EmoteLifecycleDeleted || restored != tt.want {
+					t.Errorf("expected the version to stay deleted and be restored as %d, got %d and %d", tt.want, got.Versions[1].State.Lifecycle, restored)
+				}
+			} else if got.Versions[0].State.Lifecycle != tt.want {
+				t.Errorf("expected lifecycle %d, got %d", tt.want, got.Versions[0].State.Lifecycle)
+			}
+
+			status, _ := gCtx.Inst().Redis.RawClient().Get(gCtx, "emote-processing:"+jobID.Hex()+":status").Result()
+			if status != string(tt.evt) {
+				t.Errorf("expected the status %s to be stored, got %q", tt.evt, status)
+			}
+		})
+	}
+}
+
+func TestHandleResultEvent(t *testing.T) {
+	files := []emotes.EmoteResultFile{
+		{Name: "2x.webp", ContentType: "image/webp", Width: 64, Height: 64},
+		{Name: "1x.webp", ContentType: "image/webp", Width: 32, Height: 32},
+		{Name: "1x.avif", ContentType: "image/avif", Width: 32, Height: 32},
+	}
+
+	tests := []struct {
+		name    string
+		success bool
+		deleted bool
+		unknown bool
+		want    structures.EmoteLifecycle
+		formats int
+	}{
+		{name: "succeeded", success: true, want: structures.EmoteLifecycleLive, formats: 2},
+		{name: "failed", success: false, want: structures.EmoteLifecycleFailed, formats: 2},
+		{name: "deleted version stays deleted", success: true, deleted: true, want: structures.EmoteLifecycleLive, formats: 2},
+		{name: "unknown job", success: true, unknown: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gCtx, err := fakes.NewContext(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e, _ := processingEmote(t, gCtx)
+
+			i := 0
+			if tt.deleted {
+				i = 1
+			}
+			jobID := e.Versions[i].ID
+			if tt.unknown {
+				jobID = primitive.NewObjectID()
+			}
+
+			err = emotes.NewEmoteProcessingListener(gCtx).HandleResultEvent(&emotes.EmoteResultEvent{JobID: jobID, Success: tt.success, Files: files})
+			if tt.unknown {
+				if err != mongo.ErrNoDocuments {
+					t.Errorf("expected no emote to be found, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, restored := processingState(t, gCtx)
+			ver := got.Versions[i]
+			if tt.deleted {
+				if ver.State.Lifecycle != structures.EmoteLifecycleDeleted || restored != tt.want {
+					t.Errorf("expected the version to stay deleted and be restored as %d, got %d and %d", tt.want, ver.State.Lifecycle, restored)
+				}
+			} else if ver.State.Lifecycle != tt.want {
+				t.Errorf("expected lifecycle %d, got %d", tt.want, ver.State.Lifecycle)
+			}
+
+			if len(ver.Formats) != tt.formats {
+				t.Fatalf("expected %d formats, got %+v", tt.formats, ver.Formats)
+			}
+			for _, f := range ver.Formats {
+				for j := 1; j < len(f.Files); j++ {
+					if f.Files[j-1].Width > f.Files[j].Width {
+						t.Errorf("expected the files of %s to be sorted by width, got %+v", f.Name, f.Files)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEmoteProcessingListener(t *testing.T) {
+	gCtx, err := fakes.NewContext(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rmq := gCtx.Inst().Rmq.(*fakes.Rmq)
+	e, _ := processingEmote(t, gCtx)
+
+	epl := emotes.NewEmoteProcessingListener(gCtx)
+	done := make(chan struct{})
+	go func() {
+		epl.Listen()
+		close(done)
+	}()
+
+	// The request ID in the body of an event takes precedence over the one in its headers
+	logs := logtest.NewGlobal()
+	defer logs.Reset()
+	update, _ := json.Marshal(&emotes.EmoteJobEvent{JobID: e.Versions[0].ID, Type: emotes.EmoteJobEventTypeStarted, RequestID: "body"})
+	result, _ := json.Marshal(&emotes.EmoteResultEvent{JobID: e.Versions[0].ID, Success: true})
+	if err = rmq.Deliver(gCtx.Config().Rmq.UpdateQueueName, amqp.Table{rest.RequestIDHeader: "req"}, update); err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for the update to be handled before the result, as the processor would send them
+	waitAcks := func(n int) {
+		deadline := time.Now().Add(time.Second * 5)
+		for len(rmq.Acks()) < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d messages to be acknowledged, got %d", n, len(rmq.Acks()))
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+	}
+	waitAcks(1)
+	if got, _ := processingState(t, gCtx); got.Versions[0].State.Lifecycle != structures.EmoteLifecycleProcessing {
+		t.Errorf("expected the version to be processing, got %d", got.Versions[0].State.Lifecycle)
+	}
+	started := false
+	for _, entry := range logs.AllEntries() {
+		if entry.Message == "Emote Processing Started" {
+			started = true
+			if entry.Data["request_id"] != "body" {
+				t.Errorf("expected the request ID of the event body, got %v", entry.Data["request_id"])
+			}
+		}
+	}
+	if !started {
+		t.Error("expected the update to be logged")
+	}
+
+	if err = rmq.Deliver(gCtx.Config().Rmq.ResultQueueName, nil, result); err != nil {
+		t.Fatal(err)
+	}
+	waitAcks(2)
+	if got, _ := processingState(t, gCtx); got.Versions[0].State.Lifecycle != structures.EmoteLifecycleLive {
+		t.Errorf("expected the version to be live, got %d", got.Versions[0].State.Lifecycle)
+	}
+	for _, a := range rmq.Acks() {
+		if !a.Ack {
+			t.Errorf("expected the message to be acknowledged, got %+v", a)
+		}
+	}
+
+	epl.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("the listener did not stop")
+	}
+}
@@ -0,0 +1,109 @@
+package emotes
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/REST/src/deletions"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// How often deletions are checked for having passed their retention window
+const PURGE_INTERVAL = time.Minute * 10
+
+// How long a node may take to remove the files of a deletion, before another node may take it over
+const PURGE_LEASE = time.Minute * 10
+
+// WatchDeletions: remove the files of deleted emotes once their retention window has passed, until the context is done
+func WatchDeletions(gCtx global.Context) {
+	tick := time.NewTicker(PURGE_INTERVAL)
+	defer tick.Stop()
+
+	for {
+		n, err := PurgeDeletions(gCtx, gCtx)
+		if err != nil {
+			logrus.WithError(err).Error("failed to purge deleted emotes")
+		}
+		if n > 0 {
+			logrus.WithField("count", n).Info("purged deleted emotes")
+		}
+
+		select {
+		case <-tick.C:
+		case <-gCtx.Done():
+			return
+		}
+	}
+}
+
+// PurgeDeletions: remove the files of every deletion whose retention window has passed, after which they can no longer be undone.
+// Returns how many deletions were purged
+//
+// Each deletion is leased by the node purging it, so that nodes may run this at the same time
+func PurgeDeletions(ctx context.Context, gCtx global.Context) (int, error) {
+	col := gCtx.Inst().Mongo.Collection(deletions.CollectionName)
+
+	n := 0
+	for {
+		now := time.Now()
+		d := &deletions.Deletion{}
+		if err := col.FindOneAndUpdate(ctx, bson.M{
+			"purge_at":  bson.M{"$lte": now},
+			"purged_at": bson.M{"$exists": false},
+			"$or": bson.A{
+				bson.M{"lease_until": bson.M{"$exists": false}},
+				bson.M{"lease_until": bson.M{"$lt": now}},
+			},
+		}, bson.M{
+			"$set": bson.M{"lease_until": now.Add(PURGE_LEASE)},
+		}, options.FindOneAndUpdate().SetSort(bson.M{"purge_at": 1})).Decode(d); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return n, nil
+			}
+			return n, err
+		}
+
+		// The lease is left to expire if this fails, for the deletion to be tried again later
+		if err := purgeFiles(ctx, gCtx, d); err != nil {
+			return n, err
+		}
+
+		if _, err := col.UpdateByID(ctx, d.ID, bson.M{
+			"$set":   bson.M{"purged_at": time.Now()},
+			"$unset": bson.M{"lease_until": 1},
+		}); err != nil {
+			return n, err
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"deletion_id": d.ID,
+			"emote_id":    d.EmoteID,
+		}).Debug("purged deleted emote")
+		n++
+	}
+}
+
+// purgeFiles: remove the uploaded and processed files of the versions of a deletion
+func purgeFiles(ctx context.Context, gCtx global.Context, d *deletions.Deletion) error {
+	bucket := gCtx.Config().Aws.Bucket
+
+	keys := []string{}
+	for _, v := range d.Versions {
+		for _, prefix := range deletions.FilePrefixes(v.ID) {
+			k, err := gCtx.Inst().AwsS3.ListObjects(ctx, bucket, prefix)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, k...)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return gCtx.Inst().AwsS3.DeleteObjects(ctx, bucket, keys)
+}
@@ -0,0 +1,122 @@
+package emotes_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/REST/src/deletions"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/server/v3/routes/emotes"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPurgeDeletions(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+
+	tests := []struct {
+		name string
+		// the deletion's retention window ends at, and whether it was purged or is leased by another node until
+		purgeAt time.Time
+		purged  bool
+		lease   *time.Time
+		// whether the store fails
+		fail bool
+		// whether the files are expected to be removed
+		want bool
+	}{
+		{name: "due", purgeAt: past, want: true},
+		{name: "not due", purgeAt: future},
+		{name: "already purged", purgeAt: past, purged: true},
+		{name: "leased by another node", purgeAt: past, lease: &future},
+		{name: "expired lease", purgeAt: past, lease: &past, want: true},
+		{name: "store unavailable", purgeAt: past, fail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gCtx, err := fakes.NewContext(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+			s3 := gCtx.Inst().AwsS3.(*fakes.S3)
+			bucket := gCtx.Config().Aws.Bucket
+
+			// The files of the deleted version, and those of another version which are kept
+			deleted, kept := primitive.NewObjectID(), primitive.NewObjectID()
+			files := func(id primitive.ObjectID) []string {
+				return []string{
+					fmt.Sprintf("internal/emote/%s.png", id.Hex()),
+					fmt.Sprintf("emote/%s/1x.webp", id.Hex()),
+					fmt.Sprintf("emote/%s/4x.avif", id.Hex()),
+				}
+			}
+			for _, id := range []primitive.ObjectID{deleted, kept} {
+				for _, k := range files(id) {
+					if err := s3.UploadFile(gCtx, bucket, k, bytes.NewReader([]byte("x")), nil, nil, nil); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			d := &deletions.Deletion{
+				ID:         primitive.NewObjectID(),
+				EmoteID:    primitive.NewObjectID(),
+				Versions:   []deletions.Version{{ID: deleted}},
+				DeletedAt:  now.Add(-time.Hour),
+				PurgeAt:    tt.purgeAt,
+				LeaseUntil: tt.lease,
+			}
+			if tt.purged {
+				d.PurgedAt = &past
+			}
+			if err := mgo.Seed(deletions.CollectionName, d); err != nil {
+				t.Fatal(err)
+			}
+			if tt.fail {
+				s3.Fail(fmt.Errorf("unreachable"))
+			}
+
+			n, err := emotes.PurgeDeletions(gCtx, gCtx)
+			if tt.fail != (err != nil) {
+				t.Fatalf("expected err=%t, got %v", tt.fail, err)
+			}
+			s3.Fail(nil)
+			if (n == 1) != tt.want {
+				t.Errorf("expected the deletion to be purged=%t, got %d purged", tt.want, n)
+			}
+
+			for _, k := range files(deleted) {
+				if _, ok := s3.Object(bucket, k); ok == tt.want {
+					t.Errorf("expected %s to be removed=%t", k, tt.want)
+				}
+			}
+			for _, k := range files(kept) {
+				if _, ok := s3.Object(bucket, k); !ok {
+					t.Errorf("expected %s to be kept", k)
+				}
+			}
+
+			stored := &deletions.Deletion{}
+			if err := mgo.Collection(deletions.CollectionName).FindOne(gCtx, bson.M{"_id": d.ID}).Decode(stored); err != nil {
+				t.Fatal(err)
+			}
+			if tt.want && (stored.PurgedAt == nil || stored.LeaseUntil != nil) {
+				t.Errorf("expected the deletion to be marked as purged, got %+v", stored)
+			}
+			if tt.fail && (stored.PurgedAt != nil || stored.LeaseUntil == nil || !stored.LeaseUntil.After(now)) {
+				t.Errorf("expected the deletion to be leased until it is tried again, got %+v", stored)
+			}
+
+			// Nothing is left to purge
+			if n, err = emotes.PurgeDeletions(gCtx, gCtx); err != nil || n != 0 {
+				t.Errorf("expected nothing left to purge, got %d %v", n, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package emotes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/audit"
+	"github.com/SevenTV/REST/src/deletions"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v3/middleware"
+	"github.com/SevenTV/REST/src/server/v3/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type restore struct {
+	Ctx global.Context
+	// Whether the route restores a single version, rather than the whole emote
+	version bool
+}
+
+func newRestore(gCtx global.Context) rest.Route {
+	return &restore{Ctx: gCtx}
+}
+
+func newRestoreVersion(gCtx global.Context) rest.Route {
+	return &restore{Ctx: gCtx, version: true}
+}
+
+func (r *restore) Config() rest.RouteConfig {
+	uri := "/{emote}/restore"
+	if r.version {
+		uri = "/{emote}/versions/{version}/restore"
+	}
+
+	return rest.RouteConfig{
+		URI:    uri,
+		Method: rest.POST,
+		Middleware: []rest.Middleware{
+			middleware.Auth(r.Ctx),
+			middleware.RateLimit(r.Ctx, "emotes.restore", 30, time.Minute),
+			middleware.Idempotency(r.Ctx),
+			middleware.Audit(r.Ctx),
+		},
+	}
+}
+
+// Restore Emote
+// @Summary Restore Emote
+// @Description Undo the latest deletion of an emote, or of a single version of it. This is only possible until the deleted files are removed
+// @Tags emotes
+// @Param emote path string true "the ID of the emote"
+// @Param version path string false "the ID of the version, to undo the deletion of that version"
+// @Produce json
+// @Success 200 {object} model.Emote
+// @Router /emotes/{emote}/restore [post]
+// @Router /emotes/{emote}/versions/{version}/restore [post]
+func (r *restore) Handler(ctx *rest.Ctx) rest.APIError {
+	actor, ok := ctx.GetActor()
+	if !ok {
+		return errors.ErrUnauthorized()
+	}
+
+	id, err := ctx.ObjectIDParam("emote")
+	if err != nil {
+		return err
+	}
+	filter := bson.M{
+		"emote_id":   id,
+		"version_id": bson.M{"$exists": false},
+		"purged_at":  bson.M{"$exists": false},
+		"purge_at":   bson.M{"$gt": time.Now()},
+	}
+	if r.version {
+		vid, err := ctx.ObjectIDParam("version")
+		if err != nil {
+			return err
+		}
+		filter["version_id"] = vid
+	}
+
+	emote, err := findManagedEmote(ctx, r.Ctx, actor, id)
+	if err != nil {
+		return err
+	}
+
+	// Taking the deletion out makes sure it is only undone once, and that its files are no longer removed
+	col := r.Ctx.Inst().Mongo.Collection(deletions.CollectionName)
+	deletion := &deletions.Deletion{}
+	if er := col.FindOneAndDelete(ctx, filter, options.FindOneAndDelete().SetSort(bson.M{"deleted_at": -1})).Decode(deletion); er != nil {
+		if er == mongo.ErrNoDocuments {
+			return errors.ErrUnknownEmote().SetDetail("No Restorable Deletion")
+		}
+		ctx.Log().WithError(er).Error("mongo, failed to find emote deletion")
+		return errors.ErrInternalServerError()
+	}
+
+	// Set the versions back to the state they were in
+	eb := structures.NewEmoteBuilder(emote)
+	for _, v := range deletion.Versions {
+		ver, i := eb.GetVersion(v.ID)
+		if ver == nil || ver.State.Lifecycle != structures.EmoteLifecycleDeleted {
+			continue
+		}
+
+		ver.State.Lifecycle = v.Lifecycle
+		eb.Update.Set(fmt.Sprintf("versions.%d.state.lifecycle", i), v.Lifecycle)
+	}
+	if len(eb.Update) > 0 {
+		if _, er := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).UpdateByID(ctx, emote.ID, eb.Update); er != nil {
+			ctx.Log().WithError(er).Error("mongo, failed to restore emote")
+			if _, er = col.InsertOne(ctx, deletion); er != nil {
+				ctx.Log().WithError(er).WithField("deletion", deletion).Error("mongo, failed to put back the deletion of an emote which could not be restored")
+			}
+			return errors.ErrInternalServerError()
+		}
+	}
+
+	ctx.AuditLog().
+		AddTarget(audit.TargetKindEmote, emote.ID).
+		AddChange(emote.ID, "deletion", deletion, nil)
+
+	// Let the caches and subscribers know of the change
+	if er := r.Ctx.Inst().Redis.RawClient().Publish(ctx, fmt.Sprintf("7tv-events:sub:emotes:%s", emote.ID.Hex()), "1").Err(); er != nil {
+		ctx.Log().WithError(er).Error("redis, failed to publish emote change")
+	}
+
+	result := model.NewEmote(emote)
+	return ctx.JSON(rest.OK, &result)
+}
+
+// restoredLifecycle: keep the state a deleted version would be restored to up to date,
+// for the processing events which arrive after the version was deleted
+func restoredLifecycle(ctx context.Context, gCtx global.Context, versionID primitive.ObjectID, lc structures.EmoteLifecycle) error {
+	col := gCtx.Inst().Mongo.Collection(deletions.CollectionName)
+	cur, err := col.Find(ctx, bson.M{
+		"versions.id": versionID,
+		"purged_at":   bson.M{"$exists": false},
+	})
+	if err != nil {
+		return err
+	}
+
+	deleted := []*deletions.Deletion{}
+	if err = cur.All(ctx, &deleted); err != nil {
+		return err
+	}
+
+	for _, d := range deleted {
+		for i, v := range d.Versions {
+			if v.ID != versionID {
+				continue
+			}
+
+			if _, err = col.UpdateByID(ctx, d.ID, bson.M{"$set": bson.M{
+				fmt.Sprintf("versions.%d.lifecycle", i): lc,
+			}}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package emotes_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/configure"
+	"github.com/SevenTV/REST/src/deletions"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/server"
+	"github.com/SevenTV/REST/src/server/v3/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRestoreEmote(t *testing.T) {
+	tests := []struct {
+		name string
+		user string
+		// whether a single version was deleted rather than the emote, and whether that is what is restored
+		versionDeleted  bool
+		versionRestored bool
+		// whether the deletion has passed its retention window, or been purged
+		expired bool
+		purged  bool
+		status  int
+	}{
+		{name: "anonymous", status: 401},
+		{name: "another user", user: "stranger", status: 403},
+		{name: "an editor without the permission", user: "viewer", status: 403},
+		{name: "restored by the owner", user: "owner", status: 200},
+		{name: "restored by an editor", user: "editor", status: 200},
+		{name: "restored by a moderator", user: "mod", status: 200},
+		{name: "past the retention window", user: "owner", expired: true, status: 404},
+		{name: "purged", user: "owner", purged: true, status: 404},
+		{name: "a version", user: "owner", versionDeleted: true, versionRestored: true, status: 200},
+		{name: "the emote when a version was deleted", user: "owner", versionDeleted: true, status: 404},
+		{name: "a version when the emote was deleted", user: "owner", versionRestored: true, status: 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gCtx, err := fakes.NewContext(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+			users := manageUsers(t, gCtx)
+
+			// The emote is listed, and one of its versions is live so that it can be seen once deleted
+			emote := twoVersions(users["owner"], structures.EmoteFlagsListed)
+			emote.Versions = append(emote.Versions, &structures.EmoteVersion{
+				ID:    primitive.NewObjectID(),
+				State: structures.EmoteState{Lifecycle: structures.EmoteLifecycleLive},
+			})
+			now := time.Now()
+			d := &deletions.Deletion{
+				ID:        primitive.NewObjectID(),
+				EmoteID:   emote.ID,
+				ActorID:   users["owner"].ID,
+				Reason:    "spam",
+				DeletedAt: now,
+				PurgeAt:   now.Add(time.Hour),
+				Versions:  []deletions.Version{},
+			}
+			deleted := []int{0, 1}
+			if tt.versionDeleted {
+				deleted = []int{1}
+				d.VersionID = &emote.Versions[1].ID
+			}
+			for _, i := range deleted {
+				d.Versions = append(d.Versions, deletions.Version{ID: emote.Versions[i].ID, Lifecycle: emote.Versions[i].State.Lifecycle})
+				emote.Versions[i].State.Lifecycle = structures.EmoteLifecycleDeleted
+			}
+			if tt.expired {
+				d.PurgeAt = now.Add(-time.Minute)
+			}
+			if tt.purged {
+				d.PurgeAt = now.Add(-time.Minute)
+				d.PurgedAt = &now
+			}
+			if err := mgo.Seed(mongo.CollectionNameEmotes, emote); err != nil {
+				t.Fatal(err)
+			}
+			if err := mgo.Seed(deletions.CollectionName, d); err != nil {
+				t.Fatal(err)
+			}
+
+			h := server.NewHarness(gCtx)
+			headers := map[string]string{}
+			if tt.user != "" {
+				headers["Authorization"] = authHeader(t, h, users[tt.user])
+			}
+
+			uri := "/v3/emotes/" + emote.ID.Hex() + "/restore"
+			if tt.versionRestored {
+				uri = "/v3/emotes/" + emote.ID.Hex() + "/versions/" + emote.Versions[1].ID.Hex() + "/restore"
+			}
+			res := h.Request("POST", uri, nil, headers)
+			if res.StatusCode() != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, res.StatusCode(), res.Body())
+			}
+			if tt.status == 404 && !strings.Contains(string(res.Body()), "No Restorable Deletion") {
+				t.Errorf("expected no deletion to be restorable, got %s", res.Body())
+			}
+
+			stored := &structures.Emote{}
+			if err := mgo.Collection(mongo.CollectionNameEmotes).FindOne(gCtx, bson.M{"_id": emote.ID}).Decode(stored); err != nil {
+				t.Fatal(err)
+			}
+			records := mgo.Documents(deletions.CollectionName)
+			if tt.status != 200 {
+				if len(records) != 1 {
+					t.Errorf("expected the deletion to be kept, got %d", len(records))
+				}
+				for _, i := range deleted {
+					if stored.Versions[i].State.Lifecycle != structures.EmoteLifecycleDeleted {
+						t.Errorf("expected version %d to stay deleted", i)
+					}
+				}
+				return
+			}
+
+			// The versions are back in the state they were in, and the deletion can no longer be undone again
+			for _, v := range d.Versions {
+				for _, sv := range stored.Versions {
+					if sv.ID == v.ID && sv.State.Lifecycle != v.Lifecycle {
+						t.Errorf("expected version %s to be restored to state %d, got %d", v.ID.Hex(), v.Lifecycle, sv.State.Lifecycle)
+					}
+				}
+			}
+			if len(records) != 0 {
+				t.Errorf("expected the deletion to be taken out, got %d", len(records))
+			}
+
+			result := &model.Emote{}
+			if err := json.Unmarshal(res.Body(), result); err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Versions) != 3 {
+				t.Errorf("expected every version to be returned, got %+v", result.Versions)
+			}
+
+			res = h.Request("POST", uri, nil, headers)
+			if res.StatusCode() != 404 {
+				t.Errorf("expected the deletion to be undone once, got status %d", res.StatusCode())
+			}
+		})
+	}
+}
+
+func TestRestoreRateLimit(t *testing.T) {
+	config := fakes.NewConfig()
+	config.Limits.Buckets = map[string]configure.LimitBucket{
+		"emotes.delete":  {Limit: 1},
+		"emotes.restore": {Limit: 2},
+	}
+	gCtx, err := fakes.NewContext(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := manageUsers(t, gCtx)
+	emote := twoVersions(users["owner"], structures.EmoteFlagsListed)
+	if err := gCtx.Inst().Mongo.(*fakes.Mongo).Seed(mongo.CollectionNameEmotes, emote); err != nil {
+		t.Fatal(err)
+	}
+
+	h := server.NewHarness(gCtx)
+	headers := map[string]string{"Authorization": authHeader(t, h, users["owner"])}
+	uri := "/v3/emotes/" + emote.ID.Hex()
+
+	// Restores have their own budget, apart from that of deletions
+	steps := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{"DELETE", "", 200},
+		{"POST", "/restore", 200},
+		{"DELETE", "", 429},
+		{"POST", "/restore", 404},
+		{"POST", "/restore", 429},
+	}
+	for i, s := range steps {
+		var body []byte
+		if s.method == "DELETE" {
+			body = []byte(`{"reason":"spam"}`)
+		}
+		res := h.Request(s.method, uri+s.path, body, headers)
+		if res.StatusCode() != s.status {
+			t.Fatalf("step %d: expected status %d, got %d: %s", i, s.status, res.StatusCode(), res.Body())
+		}
+		if s.status != 429 && s.method == "POST" && string(res.Header.Peek("X-RateLimit-Limit")) != "2" {
+			t.Errorf("step %d: expected the restore budget, got %s", i, res.Header.Peek("X-RateLimit-Limit"))
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package emotes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/configure"
+)
+
+// The amount of bytes kept from each end of an upload, enough for the container checks to identify its format
+const UPLOAD_SAMPLE_SIZE = 64
+
+var errUploadTooLarge = errors.New("upload exceeds the size limit")
+
+// upload: an emote file streamed from the request body into a temporary file
+type upload struct {
+	Path   string
+	Size   int64
+	SHA256 string
+
+	head []byte
+	tail []byte
+}
+
+// Write: record the head and tail of the file as it is written
+func (u *upload) Write(p []byte) (int, error) {
+	n := len(p)
+	if r := UPLOAD_SAMPLE_SIZE - len(u.head); r > 0 {
+		if r > len(p) {
+			r = len(p)
+		}
+		u.head = append(u.head, p[:r]...)
+		p = p[r:]
+	}
+
+	u.tail = append(u.tail, p...)
+	if len(u.tail) > UPLOAD_SAMPLE_SIZE {
+		u.tail = u.tail[len(u.tail)-UPLOAD_SAMPLE_SIZE:]
+	}
+	return n, nil
+}
+
+// Sample: the head and tail of the file. The container checks only look at either end,
+// so this identifies the format the same way the full file would
+func (u *upload) Sample() []byte {
+	return append(append([]byte{}, u.head...), u.tail...)
+}
+
+// receiveUpload: stream a request body into a file, hashing it on the way.
+// errUploadTooLarge is returned, and the file removed, once more than limit bytes are read
+func receiveUpload(body io.Reader, path string, limit int64) (*upload, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &upload{Path: path}
+	h := sha256.New()
+	u.Size, err = io.Copy(io.MultiWriter(f, h, u), io.LimitReader(body, limit+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && u.Size > limit {
+		err = errUploadTooLarge
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+
+	u.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return u, nil
+}
+
+// uploadLimit: the maximum size of an upload by an actor.
+// Limits configured for the actor's roles replace the default, and the highest of them applies
+func uploadLimit(config *configure.Config, actor *structures.User) int64 {
+	limit := int64(-1)
+	for _, role := range actor.Roles {
+		if l, ok := config.Limits.Uploads.Roles[role.ID.Hex()]; ok && l > limit {
+			limit = l
+		}
+	}
+	if limit < 0 {
+		return defaultUploadLimit(config)
+	}
+	return limit
+}
+
+// MaxUploadSize: the largest upload anyone may make, which request bodies are capped to
+func MaxUploadSize(config *configure.Config) int64 {
+	limit := defaultUploadLimit(config)
+	for _, l := range config.Limits.Uploads.Roles {
+		if l > limit {
+			limit = l
+		}
+	}
+	return limit
+}
+
+func defaultUploadLimit(config *configure.Config) int64 {
+	if config.Limits.Uploads.MaxSize > 0 {
+		return config.Limits.Uploads.MaxSize
+	}
+	return MAX_UPLOAD_SIZE
+}
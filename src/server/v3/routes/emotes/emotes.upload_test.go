@@ -0,0 +1,125 @@
+package emotes
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/configure"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUploadLimit(t *testing.T) {
+	small := &structures.Role{ID: primitive.NewObjectID()}
+	large := &structures.Role{ID: primitive.NewObjectID()}
+	plain := &structures.Role{ID: primitive.NewObjectID()}
+	roles := map[string]int64{small.ID.Hex(): 1000, large.ID.Hex(): 5000000}
+
+	tests := []struct {
+		name    string
+		maxSize int64
+		roles   []*structures.Role
+		want    int64
+	}{
+		{"default", 0, nil, MAX_UPLOAD_SIZE},
+		{"configured default", 3000000, nil, 3000000},
+		{"role without a limit", 0, []*structures.Role{plain}, MAX_UPLOAD_SIZE},
+		{"role raising the limit", 0, []*structures.Role{plain, large}, 5000000},
+		{"role lowering the limit", 0, []*structures.Role{plain, small}, 1000},
+		{"highest role limit", 0, []*structures.Role{small, large}, 5000000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &configure.Config{}
+			config.Limits.Uploads = configure.UploadLimits{MaxSize: tt.maxSize, Roles: roles}
+
+			if got := uploadLimit(config, &structures.User{Roles: tt.roles}); got != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMaxUploadSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxSize int64
+		roles   map[string]int64
+		want    int64
+	}{
+		{"default", 0, nil, MAX_UPLOAD_SIZE},
+		{"configured default", 1000, nil, 1000},
+		{"lower role limits", 0, map[string]int64{"a": 1000}, MAX_UPLOAD_SIZE},
+		{"higher role limit", 0, map[string]int64{"a": 1000, "b": 9000000}, 9000000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &configure.Config{}
+			config.Limits.Uploads = configure.UploadLimits{MaxSize: tt.maxSize, Roles: tt.roles}
+
+			if got := MaxUploadSize(config); got != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReceiveUpload(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	tests := []struct {
+		name  string
+		size  int
+		limit int64
+		err   error
+	}{
+		{"under the limit", 100, 1000, nil},
+		{"at the limit", 1000, 1000, nil},
+		{"over the limit", 1000, 999, errUploadTooLarge},
+		{"smaller than the sample", 10, 1000, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := path.Join(t.TempDir(), "upload")
+			up, err := receiveUpload(bytes.NewReader(data[:tt.size]), p, tt.limit)
+			if err != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if err != nil {
+				if _, err := os.Stat(p); !os.IsNotExist(err) {
+					t.Error("expected the file to be removed")
+				}
+				return
+			}
+
+			b, err := os.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := sha256.Sum256(data[:tt.size])
+			if !bytes.Equal(b, data[:tt.size]) || up.Size != int64(tt.size) || up.SHA256 != hex.EncodeToString(h[:]) {
+				t.Errorf("unexpected upload of %d bytes, hashed %s", up.Size, up.SHA256)
+			}
+
+			// The sample holds both ends of the file
+			sample := up.Sample()
+			n := UPLOAD_SAMPLE_SIZE
+			if tt.size < n {
+				n = tt.size
+			}
+			if !bytes.HasPrefix(sample, data[:n]) || !bytes.HasSuffix(sample, data[tt.size-n:tt.size]) {
+				t.Errorf("expected the sample to hold the head and tail of the file")
+			}
+		})
+	}
+}
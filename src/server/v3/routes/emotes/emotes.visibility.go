@@ -0,0 +1,71 @@
+package emotes
+
+import (
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/visibility"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// findManagedEmote: get an emote the actor may make changes to, with its owner and their editors.
+// Emotes hidden from the actor are reported as unknown, rather than forbidden
+func findManagedEmote(ctx *rest.Ctx, gCtx global.Context, actor *structures.User, id primitive.ObjectID) (*structures.Emote, rest.APIError) {
+	visible, err := visibility.Filter(ctx, gCtx, actor, false)
+	if err != nil {
+		ctx.Log().WithError(err).Error("mongo, failed to find the emotes visible to the actor")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	emote := &structures.Emote{}
+	if err = gCtx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).FindOne(ctx, bson.M{
+		"$and": bson.A{bson.M{"_id": id}, visible},
+	}).Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrUnknownEmote()
+		}
+		ctx.Log().WithError(err).Error("mongo, failed to find emote")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	owner := &structures.User{}
+	if err = gCtx.Inst().Mongo.Collection(mongo.CollectionNameUsers).FindOne(ctx, bson.M{
+		"_id": emote.OwnerID,
+	}, options.FindOne().SetProjection(bson.M{
+		"username":     1,
+		"display_name": 1,
+		"avatar_id":    1,
+		"editors":      1,
+	})).Decode(owner); err == nil {
+		emote.Owner = owner
+	} else if err != mongo.ErrNoDocuments {
+		ctx.Log().WithError(err).Error("mongo, failed to find the owner of emote")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	if !canManageEmote(actor, emote) {
+		return nil, errors.ErrInsufficientPrivilege()
+	}
+	return emote, nil
+}
+
+// canManageEmote: whether the actor may make changes to an emote. The emote's owner must be populated with its editors
+func canManageEmote(actor *structures.User, emote *structures.Emote) bool {
+	if actor.ID == emote.OwnerID || actor.HasPermission(structures.RolePermissionEditAnyEmote) {
+		return true
+	}
+	if emote.Owner == nil {
+		return false
+	}
+
+	for _, ed := range emote.Owner.Editors {
+		if ed.ID == actor.ID && ed.HasPermission(structures.UserEditorPermissionManageOwnedEmotes) {
+			return true // actor is an editor of emote owner with correct permissions
+		}
+	}
+	return false
+}
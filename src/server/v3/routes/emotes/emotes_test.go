@@ -0,0 +1,164 @@
+package emotes_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// searchEmote: an emote as stored, with its popularity
+type searchEmote struct {
+	structures.Emote `bson:",inline"`
+	Popularity       int32 `bson:"popularity"`
+}
+
+// seedSearch: seed listed emotes, and a private one of the owner
+func seedSearch(t *testing.T, gCtx global.Context) map[string]*structures.User {
+	mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+
+	role := &structures.Role{ID: primitive.NewObjectID(), Name: "Privacy", Allowed: structures.RolePermissionBypassPrivacy}
+	users := map[string]*structures.User{
+		"owner":   {ID: primitive.NewObjectID(), Username: "owner", RoleIDs: []primitive.ObjectID{}},
+		"user":    {ID: primitive.NewObjectID(), Username: "user", RoleIDs: []primitive.ObjectID{}},
+		"privacy": {ID: primitive.NewObjectID(), Username: "privacy", RoleIDs: []primitive.ObjectID{role.ID}},
+	}
+	if err := mgo.Seed(mongo.CollectionNameRoles, role); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range users {
+		if err := mgo.Seed(mongo.CollectionNameUsers, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	emote := func(name string, popularity int32, flags structures.EmoteFlag, tags ...string) *searchEmote {
+		return &searchEmote{Emote: structures.Emote{
+			ID:      primitive.NewObjectID(),
+			OwnerID: users["owner"].ID,
+			Name:    name,
+			Flags:   flags,
+			Tags:    tags,
+			Versions: []*structures.EmoteVersion{{
+				ID:         primitive.NewObjectID(),
+				FrameCount: 1,
+				State:      structures.EmoteState{Lifecycle: structures.EmoteLifecycleLive},
+			}},
+		}, Popularity: popularity}
+	}
+	if err := mgo.Seed(mongo.CollectionNameEmotes,
+		emote("pepeLaugh", 10, structures.EmoteFlagsListed),
+		emote("Pepega", 5, structures.EmoteFlagsListed),
+		emote("xpep", 20, structures.EmoteFlagsListed),
+		emote("frog", 1, structures.EmoteFlagsListed, "pep"),
+		emote("pep", 0, structures.EmoteFlagsListed),
+		emote("pepePrivate", 30, structures.EmoteFlagsListed|structures.EmoteFlagsPrivate),
+	); err != nil {
+		t.Fatal(err)
+	}
+	return users
+}
+
+func TestSearchEmotes(t *testing.T) {
+	tests := []struct {
+		name  string
+		user  string
+		query string
+		// the names of the emotes expected, in order, and the X-Total-Count expected, if any
+		want  string
+		total string
+	}{
+		{name: "by popularity", want: "xpep,pepeLaugh,Pepega,frog,pep"},
+		{name: "name prefix and tag", query: "query=pep", want: "pep,frog,pepeLaugh,Pepega"},
+		{name: "prefix is case insensitive", query: "query=PEPE", want: "pepeLaugh,Pepega"},
+		{name: "query is not a pattern", query: "query=pep.", want: ""},
+		{name: "by date", query: "sort=date", want: "pep,frog,xpep,Pepega,pepeLaugh"},
+		{name: "counted when asked", query: "query=pep&count=true", want: "pep,frog,pepeLaugh,Pepega", total: "4"},
+		{name: "private to others", user: "user", want: "xpep,pepeLaugh,Pepega,frog,pep"},
+		{name: "private to the owner", user: "owner", want: "pepePrivate,xpep,pepeLaugh,Pepega,frog,pep"},
+		{name: "privacy bypassed", user: "privacy", query: "count=true", want: "pepePrivate,xpep,pepeLaugh,Pepega,frog,pep", total: "6"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gCtx, err := fakes.NewContext(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			users := seedSearch(t, gCtx)
+
+			h := server.NewHarness(gCtx)
+			headers := map[string]string{}
+			if tt.user != "" {
+				headers["Authorization"] = authHeader(t, h, users[tt.user])
+			}
+
+			res := h.Request("GET", "/v3/emotes?"+tt.query, nil, headers)
+			if res.StatusCode() != 200 {
+				t.Fatalf("expected status 200, got %d: %s", res.StatusCode(), res.Body())
+			}
+			if got := searchNames(t, res.Body()); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+			if got := string(res.Header.Peek("X-Total-Count")); got != tt.total {
+				t.Errorf("expected a total count of %q, got %q", tt.total, got)
+			}
+		})
+	}
+}
+
+func TestSearchEmotesPaging(t *testing.T) {
+	gCtx, err := fakes.NewContext(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seedSearch(t, gCtx)
+	h := server.NewHarness(gCtx)
+
+	// Follow the next links until the last page
+	names := []string{}
+	uri := "/v3/emotes?limit=2"
+	for pages := 0; uri != ""; pages++ {
+		if pages == 5 {
+			t.Fatal("expected the pages to end")
+		}
+		res := h.Request("GET", uri, nil, nil)
+		if res.StatusCode() != 200 {
+			t.Fatalf("expected status 200, got %d: %s", res.StatusCode(), res.Body())
+		}
+		if page := searchNames(t, res.Body()); page != "" {
+			names = append(names, page)
+		}
+
+		uri = ""
+		if link := string(res.Header.Peek("Link")); link != "" {
+			uri = link[strings.Index(link, "<")+1 : strings.Index(link, ">")]
+			uri = "/v3" + uri[strings.Index(uri, "/emotes"):]
+		}
+	}
+
+	if got := strings.Join(names, ","); got != "xpep,pepeLaugh,Pepega,frog,pep" {
+		t.Errorf("expected every emote once, by popularity, got %s", got)
+	}
+}
+
+func searchNames(t *testing.T, body []byte) string {
+	result := []struct {
+		Name string `json:"name"`
+	}{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("expected emotes, got %s", body)
+	}
+	names := make([]string, len(result))
+	for i, e := range result {
+		names[i] = e.Name
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,66 @@
+package reports
+
+import (
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/panics"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/SevenTV/REST/src/server/v3/middleware"
+	"github.com/SevenTV/REST/src/server/v3/model"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type Route struct {
+	Ctx global.Context
+}
+
+func New(gCtx global.Context) rest.Route {
+	return &Route{gCtx}
+}
+
+func (r *Route) Config() rest.RouteConfig {
+	return rest.RouteConfig{
+		URI:    "/errors/{id}",
+		Method: rest.GET,
+		Middleware: []rest.Middleware{
+			middleware.Auth(r.Ctx),
+		},
+	}
+}
+
+// Get Error Report
+// @Summary Get Error Report
+// @Description Look up the report of an unexpected error by the error ID returned to the client. Requires the manage stack permission
+// @Tags errors
+// @Produce json
+// @Param id path string true "the error ID"
+// @Success 200 {object} model.ErrorReport
+// @Router /errors/{id} [get]
+func (r *Route) Handler(ctx *rest.Ctx) rest.APIError {
+	actor, ok := ctx.GetActor()
+	if !ok {
+		return errors.ErrUnauthorized()
+	}
+	if !actor.HasPermission(structures.RolePermissionManageStack) {
+		return errors.ErrInsufficientPrivilege()
+	}
+
+	id, err := ctx.ObjectIDParam("id")
+	if err != nil {
+		return err
+	}
+
+	report := &panics.Report{}
+	if err := r.Ctx.Inst().Mongo.Collection(panics.CollectionName).FindOne(ctx, bson.M{"_id": id}).Decode(report); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return rest.ErrUnknownErrorReport()
+		}
+
+		ctx.Log().WithError(err).Error("mongo, failed to query error report")
+		return errors.ErrInternalServerError()
+	}
+
+	return ctx.JSON(rest.OK, model.NewErrorReport(report))
+}
@@ -1,17 +1,18 @@
 package routes
 
 import (
-	"context"
-	"sync"
 	"time"
 
-	"github.com/SevenTV/Common/utils"
 	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/maintenance"
 	"github.com/SevenTV/REST/src/server/rest"
 	"github.com/SevenTV/REST/src/server/v3/middleware"
+	"github.com/SevenTV/REST/src/server/v3/routes/audit"
 	"github.com/SevenTV/REST/src/server/v3/routes/auth"
+	"github.com/SevenTV/REST/src/server/v3/routes/cache"
 	"github.com/SevenTV/REST/src/server/v3/routes/docs"
 	"github.com/SevenTV/REST/src/server/v3/routes/emotes"
+	"github.com/SevenTV/REST/src/server/v3/routes/reports"
 )
 
 type Route struct {
@@ -30,79 +31,29 @@ func (r *Route) Config() rest.RouteConfig {
 			docs.New(r.Ctx),
 			auth.New(r.Ctx),
 			emotes.New(r.Ctx),
+			audit.New(r.Ctx),
+			cache.New(r.Ctx),
+			reports.New(r.Ctx),
 		},
 		Middleware: []rest.Middleware{
 			middleware.SetCacheControl(r.Ctx, 30, nil),
-			middleware.Audit(r.Ctx),
 		},
 	}
 }
 
+// Handler: the landing page of the API. Dependencies are reported by the /health endpoints
 func (r *Route) Handler(ctx *rest.Ctx) rest.APIError {
 	uptime := r.Ctx.Value("uptime").(time.Time)
 
-	// Default service statuses
-	services := responseServices{
-		RabbitMQ: utils.Ternary(r.Ctx.Inst().Rmq != nil, responseServiceStatusOK, responseServiceStatusUnavailable).(responseServiceStatus),
-		S3:       utils.Ternary(r.Ctx.Inst().AwsS3 != nil, responseServiceStatusOK, responseServiceStatusUnavailable).(responseServiceStatus),
-		MongoDB:  responseServiceStatusUnavailable,
-		Redis:    responseServiceStatusUnavailable,
-	}
-
-	// Define a context that will last one second
-	lctx, cancel := context.WithTimeout(ctx, time.Second*1)
-	wg := sync.WaitGroup{}
-	if r.Ctx.Inst().Mongo != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-
-			if err := r.Ctx.Inst().Mongo.Ping(lctx); err == nil {
-				services.MongoDB = responseServiceStatusOK
-			} else if lctx.Err() != nil {
-				services.MongoDB = responseServiceStatusTimeout
-			}
-		}()
-	}
-	if r.Ctx.Inst().Redis != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-
-			if err := r.Ctx.Inst().Redis.Ping(lctx); err == nil {
-				services.Redis = responseServiceStatusOK
-			} else if lctx.Err() != nil {
-				services.Redis = responseServiceStatusTimeout
-			}
-		}()
-	}
-	wg.Wait()
-	cancel()
-
 	return ctx.JSON(rest.OK, &Response{
-		Online:   true,
-		Uptime:   uptime.Format(time.RFC3339),
-		Services: services,
+		Online: true,
+		Uptime: uptime.Format(time.RFC3339),
+		Mode:   maintenance.Current(r.Ctx).Mode,
 	})
 }
 
 type Response struct {
-	Online   bool             `json:"online"`
-	Uptime   string           `json:"uptime"`
-	Services responseServices `json:"services"`
-}
-
-type responseServices struct {
-	MongoDB  responseServiceStatus `json:"database"`
-	Redis    responseServiceStatus `json:"memcache"`
-	RabbitMQ responseServiceStatus `json:"tasks"`
-	S3       responseServiceStatus `json:"objectstore"`
+	Online bool             `json:"online"`
+	Uptime string           `json:"uptime"`
+	Mode   maintenance.Mode `json:"mode"`
 }
-
-type responseServiceStatus string
-
-const (
-	responseServiceStatusOK          responseServiceStatus = "OK"
-	responseServiceStatusUnavailable responseServiceStatus = "UNAVAILABLE"
-	responseServiceStatusTimeout     responseServiceStatus = "TIMED_OUT"
-)
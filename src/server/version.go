@@ -1,20 +1,31 @@
 package server
 
 import (
-	"encoding/json"
-	"runtime/debug"
+	"fmt"
+	"regexp"
 
 	"github.com/SevenTV/Common/errors"
 	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/probes"
 	"github.com/SevenTV/REST/src/server/rest"
+	v2 "github.com/SevenTV/REST/src/server/v2"
 	v3 "github.com/SevenTV/REST/src/server/v3"
-	"github.com/fasthttp/router"
 	"github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
 )
 
+func (s *HttpServer) V2(gCtx global.Context) {
+	s.traverseRoutes(v2.API(gCtx, s.router), "")
+}
+
 func (s *HttpServer) V3(gCtx global.Context) {
-	s.traverseRoutes(v3.API(gCtx, s.router), s.router)
+	s.traverseRoutes(v3.API(gCtx, s.router), "")
+}
+
+func (s *HttpServer) Probes(gCtx global.Context) {
+	for _, r := range probes.Routes(gCtx) {
+		s.traverseRoutes(r, "")
+	}
 }
 
 func (s *HttpServer) SetupHandlers() {
@@ -28,40 +39,44 @@ func (s *HttpServer) SetupHandlers() {
 
 	// Handle P A N I C
 	s.router.PanicHandler = func(ctx *fasthttp.RequestCtx, i interface{}) {
-		err := "Uh oh. Something went horribly wrong"
-		switch x := i.(type) {
-		case error:
-			err += ": " + x.Error()
-			logrus.WithError(x).Errorf("panic occured: %+v\n%s", i, debug.Stack())
-		case string:
-			err += ": " + x
-		}
-		s.getErrorHandler(
-			rest.InternalServerError,
-			errors.ErrInternalServerError().SetFields(errors.Fields{
-				"panic": err,
-			}),
-		)(ctx)
+		writeError(ctx, rest.InternalServerError, s.recoverPanic(ctx, i))
 	}
 }
 
-func (s *HttpServer) traverseRoutes(r rest.Route, parentGroup Router) {
+func (s *HttpServer) traverseRoutes(r rest.Route, prefix string, params ...string) {
 	c := r.Config()
 
 	// Compose the full request URI (prefixing with parent, if any)
-	routable := parentGroup
-	group := routable.Group(c.URI)
+	uri := prefix + c.URI
+	if uri == "" || uri[0] != '/' {
+		panic(fmt.Sprintf("route %q must begin with '/'", uri))
+	}
+
+	// Path parameters are shared with child routes, so their names must be unique along the way
+	for _, p := range pathParamRegex.FindAllStringSubmatch(c.URI, -1) {
+		for _, existing := range params {
+			if existing == p[1] {
+				panic(fmt.Sprintf("route %q declares the path parameter %q more than once", uri, p[1]))
+			}
+		}
+		params = append(params[:len(params):len(params)], p[1])
+	}
+
 	l := logrus.WithFields(logrus.Fields{
-		"group":  group,
+		"uri":    uri,
 		"method": c.Method,
 	})
 
 	// Handle requests
-	group.Handle(string(c.Method), "", func(ctx *fasthttp.RequestCtx) {
+	s.router.Handle(string(c.Method), uri, func(ctx *fasthttp.RequestCtx) {
 		rctx := &rest.Ctx{
-			Lifecycle:  rest.NewLifecycle(ctx),
 			RequestCtx: ctx,
 		}
+		if s.gCtx.Inst().Prometheus != nil {
+			rctx.OnComplete(func(ctx *rest.Ctx, res rest.RequestResult) {
+				s.gCtx.Inst().Prometheus.ObserveRequest(string(c.Method), ctx.Route(), int(res.Status), res.Duration)
+			})
+		}
 
 		handlers := make([]rest.Middleware, len(c.Middleware)+1)
 		for i, mw := range c.Middleware {
@@ -69,70 +84,50 @@ func (s *HttpServer) traverseRoutes(r rest.Route, parentGroup Router) {
 		}
 		handlers[len(handlers)-1] = r.Handler
 
+		var err rest.APIError
+		defer func() {
+			// Panics in middleware or the handler still complete the request, with an opaque error
+			if v := recover(); v != nil {
+				err = s.recoverPanic(ctx, v)
+				writeError(ctx, rest.InternalServerError, err)
+				rctx.Complete(err)
+			}
+		}()
+
 		for i, h := range handlers {
 			if i == len(handlers)-1 {
-				// emit "started" lifecycle event after middlewares
-				rctx.Lifecycle.Write(rest.LifecyclePhaseStarted, nil)
+				// run "start" hooks after middlewares
+				rctx.Start()
 			}
-			if err := h(rctx); err != nil {
+			if err = h(rctx); err != nil {
 				// If the request handler returned an error
 				// we will format it into standard API error response
-				if ctx.Response.StatusCode() < 400 {
-					rctx.SetStatusCode(rest.HttpStatusCode(err.ExpectedHTTPStatus()))
+				status := rctx.StatusCode()
+				if status < 400 {
+					status = rest.HttpStatusCode(err.ExpectedHTTPStatus())
 				}
-				resp := &rest.APIErrorResponse{
-					Status:     rctx.StatusCode().String(),
-					StatusCode: rctx.StatusCode(),
-					Error:      err.Message(),
-					ErrorCode:  err.Code(),
-					Details:    err.GetFields(),
-				}
-
-				b, _ := json.Marshal(resp)
-				rctx.SetContentType("application/json")
-				rctx.SetBody(b)
-				return
+				writeError(ctx, status, err)
+				break
+			}
+			if rctx.Halted() {
+				break
 			}
 		}
-		// emit "completed" lifecycle event once all handlers have completed
-		rctx.Lifecycle.Write(rest.LifecyclePhaseCompleted, nil)
-		go rctx.Lifecycle.Destroy()
+		// run "complete" hooks once the response is final
+		rctx.Complete(err)
 	})
 	l.Debug("Route registered")
 
 	// activate child routes
 	for _, child := range c.Children {
-		s.traverseRoutes(child, group)
+		s.traverseRoutes(child, uri, params...)
 	}
 }
 
 func (s *HttpServer) getErrorHandler(status rest.HttpStatusCode, err rest.APIError) func(ctx *fasthttp.RequestCtx) {
 	return func(ctx *fasthttp.RequestCtx) {
-		b, _ := json.Marshal(&rest.APIErrorResponse{
-			Status:     status.String(),
-			StatusCode: status,
-			Error:      err.Message(),
-			ErrorCode:  err.Code(),
-			Details:    err.GetFields(),
-		})
-		ctx.SetContentType("application/json")
-		ctx.SetBody(b)
+		writeError(ctx, status, err)
 	}
 }
 
-type Router interface {
-	ANY(path string, handler fasthttp.RequestHandler)
-	CONNECT(path string, handler fasthttp.RequestHandler)
-	DELETE(path string, handler fasthttp.RequestHandler)
-	GET(path string, handler fasthttp.RequestHandler)
-	Group(path string) *router.Group
-	HEAD(path string, handler fasthttp.RequestHandler)
-	Handle(method, path string, handler fasthttp.RequestHandler)
-	OPTIONS(path string, handler fasthttp.RequestHandler)
-	PATCH(path string, handler fasthttp.RequestHandler)
-	POST(path string, handler fasthttp.RequestHandler)
-	PUT(path string, handler fasthttp.RequestHandler)
-	ServeFiles(path string, rootPath string)
-	ServeFilesCustom(path string, fs *fasthttp.FS)
-	TRACE(path string, handler fasthttp.RequestHandler)
-}
+var pathParamRegex = regexp.MustCompile(`{([^}:]+)(?::[^}]*)?}`)
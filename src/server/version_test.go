@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/REST/src/fakes"
+	"github.com/SevenTV/REST/src/global"
+	"github.com/SevenTV/REST/src/server/rest"
+	"github.com/fasthttp/router"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testRoute: a route whose handler is a function
+type testRoute struct {
+	config  rest.RouteConfig
+	handler func(ctx *rest.Ctx) rest.APIError
+}
+
+func (r *testRoute) Config() rest.RouteConfig {
+	return r.config
+}
+
+func (r *testRoute) Handler(ctx *rest.Ctx) rest.APIError {
+	return r.handler(ctx)
+}
+
+// newTestHarness: a harness serving only the given routes
+func newTestHarness(gCtx global.Context, routes ...rest.Route) *Harness {
+	srv := &HttpServer{gCtx: gCtx, router: router.New()}
+	srv.router.SaveMatchedRoutePath = true
+	srv.SetupHandlers()
+	for _, r := range routes {
+		srv.traverseRoutes(r, "")
+	}
+
+	return &Harness{srv: srv}
+}
+
+func TestObjectIDParam(t *testing.T) {
+	gCtx, err := fakes.NewContext(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func(ctx *rest.Ctx) rest.APIError {
+		res := map[string]string{}
+		for _, name := range []string{"user", "emote"} {
+			if ctx.Param(name) == "" {
+				continue
+			}
+			id, err := ctx.ObjectIDParam(name)
+			if err != nil {
+				return err
+			}
+			res[name] = id.Hex()
+		}
+		return ctx.JSON(rest.OK, res)
+	}
+	h := newTestHarness(gCtx, &testRoute{
+		config: rest.RouteConfig{
+			URI:    "/users/{user}",
+			Method: rest.GET,
+			Children: []rest.Route{&testRoute{
+				config:  rest.RouteConfig{URI: "/emotes/{emote}", Method: rest.GET},
+				handler: ids,
+			}},
+		},
+		handler: ids,
+	})
+
+	user, emote := primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()
+	tests := []struct {
+		name   string
+		uri    string
+		status int
+		// the ids passed to the handler, or the fields of the error
+		want map[string]string
+	}{
+		{"valid", "/users/" + user, 200, map[string]string{"user": user}},
+		{"valid in a child route", "/users/" + user + "/emotes/" + emote, 200, map[string]string{"user": user, "emote": emote}},
+		{"malformed", "/users/xyz", 400, map[string]string{"param": "user", "value": "xyz"}},
+		{"too short", "/users/" + user[:23], 400, map[string]string{"param": "user", "value": user[:23]}},
+		{"malformed in a child route", "/users/" + user + "/emotes/" + emote + "0", 400, map[string]string{"param": "emote", "value": emote + "0"}},
+		{"parent param is checked first", "/users/nope/emotes/nope", 400, map[string]string{"param": "user", "value": "nope"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := h.Request("GET", tt.uri, nil, nil)
+			if res.StatusCode() != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, res.StatusCode(), res.Body())
+			}
+
+			got := map[string]string{}
+			if tt.status == 200 {
+				if err := json.Unmarshal(res.Body(), &got); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				body := &rest.APIErrorResponse{}
+				if err := json.Unmarshal(res.Body(), body); err != nil {
+					t.Fatal(err)
+				}
+				if body.ErrorCode != errors.ErrBadObjectID().Code() {
+					t.Errorf("expected a bad object id error, got %d", body.ErrorCode)
+				}
+				for k, v := range body.Details {
+					got[k], _ = v.(string)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s: expected %q, got %q", k, v, got[k])
+				}
+			}
+		})
+	}
+}
+
+func TestDuplicatePathParam(t *testing.T) {
+	gCtx, err := fakes.NewContext(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a route declaring a parent's path parameter to panic")
+		}
+	}()
+	newTestHarness(gCtx, &testRoute{
+		config: rest.RouteConfig{
+			URI:    "/emotes/{emote}",
+			Method: rest.GET,
+			Children: []rest.Route{&testRoute{
+				config: rest.RouteConfig{URI: "/versions/{emote}", Method: rest.GET},
+			}},
+		},
+	})
+}
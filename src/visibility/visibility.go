@@ -0,0 +1,63 @@
+package visibility
+
+import (
+	"context"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/global"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Filter: the condition selecting the emotes the actor may see. actor may be nil
+//
+// Private emotes, and unlisted emotes when listing, are hidden except from their owner,
+// the owner's editors who may manage their emotes, and users who may edit any emote or bypass privacy
+func Filter(ctx context.Context, gCtx global.Context, actor *structures.User, listing bool) (bson.M, error) {
+	flags := bson.M{"$bitsAllClear": structures.EmoteFlagsPrivate}
+	if listing {
+		flags["$bitsAllSet"] = structures.EmoteFlagsListed
+	}
+	public := bson.M{"flags": flags}
+	if actor == nil {
+		return public, nil
+	}
+	if actor.HasPermission(structures.RolePermissionEditAnyEmote) || actor.HasPermission(structures.RolePermissionBypassPrivacy) {
+		return bson.M{}, nil
+	}
+
+	owners, err := EditableOwners(ctx, gCtx, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	return bson.M{"$or": bson.A{public, bson.M{"owner_id": bson.M{"$in": owners}}}}, nil
+}
+
+// EditableOwners: the users whose emotes the actor may manage, themselves included
+func EditableOwners(ctx context.Context, gCtx global.Context, actor *structures.User) ([]primitive.ObjectID, error) {
+	cur, err := gCtx.Inst().Mongo.Collection(mongo.CollectionNameUsers).Find(ctx, bson.M{
+		"editors.id": actor.ID,
+	}, options.Find().SetProjection(bson.M{"_id": 1, "editors": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	users := []*structures.User{}
+	if err = cur.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	owners := []primitive.ObjectID{actor.ID}
+	for _, u := range users {
+		for _, ed := range u.Editors {
+			if ed.ID == actor.ID && ed.HasPermission(structures.UserEditorPermissionManageOwnedEmotes) {
+				owners = append(owners, u.ID)
+				break
+			}
+		}
+	}
+	return owners, nil
+}
@@ -0,0 +1,90 @@
+package visibility
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/REST/src/fakes"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFilter(t *testing.T) {
+	gCtx, err := fakes.NewContext(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgo := gCtx.Inst().Mongo.(*fakes.Mongo)
+
+	role := func(p structures.RolePermission) []*structures.Role {
+		return []*structures.Role{{ID: primitive.NewObjectID(), Allowed: p}}
+	}
+	users := map[string]*structures.User{
+		"owner":    {ID: primitive.NewObjectID()},
+		"editor":   {ID: primitive.NewObjectID()},
+		"viewer":   {ID: primitive.NewObjectID()},
+		"stranger": {ID: primitive.NewObjectID()},
+		"mod":      {ID: primitive.NewObjectID(), Roles: role(structures.RolePermissionEditAnyEmote)},
+		"privacy":  {ID: primitive.NewObjectID(), Roles: role(structures.RolePermissionBypassPrivacy)},
+	}
+	_ = mgo.Seed(mongo.CollectionNameUsers, &structures.User{
+		ID:      users["owner"].ID,
+		RoleIDs: []primitive.ObjectID{},
+		Editors: []*structures.UserEditor{
+			{ID: users["editor"].ID, Permissions: structures.UserEditorPermissionManageOwnedEmotes},
+			{ID: users["viewer"].ID, Permissions: structures.UserEditorPermissionManageEmoteSets},
+		},
+	})
+	_ = mgo.Seed(mongo.CollectionNameEmotes,
+		bson.M{"_id": primitive.NewObjectID(), "name": "listed", "owner_id": users["owner"].ID, "flags": structures.EmoteFlagsListed},
+		bson.M{"_id": primitive.NewObjectID(), "name": "unlisted", "owner_id": users["owner"].ID, "flags": 0},
+		bson.M{"_id": primitive.NewObjectID(), "name": "private", "owner_id": users["owner"].ID, "flags": structures.EmoteFlagsPrivate | structures.EmoteFlagsListed},
+	)
+
+	tests := []struct {
+		actor   string
+		listing bool
+		// the emotes expected to be visible, by name
+		want string
+	}{
+		{"", true, "listed"},
+		{"", false, "listed,unlisted"},
+		{"stranger", true, "listed"},
+		{"stranger", false, "listed,unlisted"},
+		{"viewer", true, "listed"},
+		{"owner", true, "listed,private,unlisted"},
+		{"editor", true, "listed,private,unlisted"},
+		{"mod", true, "listed,private,unlisted"},
+		{"privacy", true, "listed,private,unlisted"},
+		{"privacy", false, "listed,private,unlisted"},
+	}
+
+	for _, tt := range tests {
+		filter, err := Filter(gCtx, gCtx, users[tt.actor], tt.listing)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cur, err := mgo.Collection(mongo.CollectionNameEmotes).Find(gCtx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		emotes := []*structures.Emote{}
+		if err = cur.All(gCtx, &emotes); err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, len(emotes))
+		for i, e := range emotes {
+			names[i] = e.Name
+		}
+		sort.Strings(names)
+
+		if got := strings.Join(names, ","); got != tt.want {
+			t.Errorf("%q listing=%t: expected %s, got %s", tt.actor, tt.listing, tt.want, got)
+		}
+	}
+}